@@ -3,8 +3,9 @@ module Chess2020
 go 1.15
 
 require (
+	github.com/gorilla/websocket v1.5.3
 	github.com/spf13/cast v1.3.1 // indirect
 	github.com/spf13/viper v1.7.1 // indirect
-	github.com/yaricom/goNEAT v0.0.0-20190822164653-2553ade85ca4 // indirect
+	github.com/yaricom/goNEAT v0.0.0-20190822164653-2553ade85ca4
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
@@ -0,0 +1,129 @@
+package chess
+
+import "fmt"
+
+// DefaultMaxMoves is the number of moves each player may make in a Game before it is drawn, unless its Adjudication
+// says otherwise
+const DefaultMaxMoves = 200
+
+// Evaluator is implemented by Players that report how they evaluate the game, e.g. engines, so that games can be
+// adjudicated from their scores
+type Evaluator interface {
+	// Score returns the score the player gave its last move, in centipawns from its own point of view, or false if it
+	// didn't give one. Mate scores are beyond any other score.
+	Score() (int, bool)
+}
+
+// Tablebase knows the outcome of some positions under perfect play, e.g. those with few pieces
+type Tablebase interface {
+	// Outcome returns the outcome of b under perfect play, or false if it doesn't know it
+	Outcome(b *Board) (Outcome, bool)
+}
+
+// Adjudication ends a Game before checkmate or a draw by the rules, once its outcome is clear enough, to save time in
+// automated matches. Each rule is disabled by the zero value of its fields.
+type Adjudication struct {
+	// MaxMoves draws the game once each player has made this many moves
+	MaxMoves int
+
+	// ResignScore and ResignMoves make a player win once both players have scored their last ResignMoves moves at
+	// least ResignScore centipawns in its favor
+	ResignScore int
+	ResignMoves int
+
+	// DrawMoveNumber, DrawScore and DrawMoves draw the game once each player has made DrawMoveNumber moves, and both
+	// players have scored their last DrawMoves moves within DrawScore centipawns of 0
+	DrawMoveNumber int
+	DrawScore      int
+	DrawMoves      int
+
+	// Tablebase, if set, ends the game with the outcome of its position under perfect play, if it knows it
+	Tablebase Tablebase
+}
+
+// DefaultAdjudication returns the Adjudication of a new Game, which only caps its length to DefaultMaxMoves
+func DefaultAdjudication() Adjudication {
+	return Adjudication{MaxMoves: DefaultMaxMoves}
+}
+
+// recordScore records the score player c gave the move it just played, if it reported one
+func (g *Game) recordScore(c Color, p Player) {
+	e, ok := p.(Evaluator)
+	if !ok {
+		return
+	}
+
+	score, ok := e.Score()
+	if !ok {
+		// only consecutive scores count
+		g.scores[c] = nil
+		return
+	}
+
+	// scores are kept from white's point of view
+	if c == Black {
+		score = -score
+	}
+	g.scores[c] = append(g.scores[c], score)
+}
+
+// lastScores returns true iff both players have scored their last n moves, and all these scores satisfy f
+func (g *Game) lastScores(n int, f func(score int) bool) bool {
+	for _, scores := range g.scores {
+		if len(scores) < n {
+			return false
+		}
+
+		for _, score := range scores[len(scores)-n:] {
+			if !f(score) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// adjudicate returns the result of the game if its adjudication ends it
+func (g *Game) adjudicate() *GameResult {
+	a := g.Adjudication
+
+	if a.Tablebase != nil {
+		if o, ok := a.Tablebase.Outcome(g.board); ok {
+			switch o {
+			case WhiteWon:
+				return g.result(o, "White won by tablebase adjudication")
+			case BlackWon:
+				return g.result(o, "Black won by tablebase adjudication")
+			default:
+				return g.result(o, "draw by tablebase adjudication")
+			}
+		}
+	}
+
+	if a.ResignMoves > 0 {
+		switch {
+		case g.lastScores(a.ResignMoves, func(score int) bool { return score >= a.ResignScore }):
+			return g.result(WhiteWon, fmt.Sprintf("White won by adjudication: scored %v+ for %v moves",
+				a.ResignScore, a.ResignMoves))
+		case g.lastScores(a.ResignMoves, func(score int) bool { return score <= -a.ResignScore }):
+			return g.result(BlackWon, fmt.Sprintf("Black won by adjudication: scored %v+ for %v moves",
+				a.ResignScore, a.ResignMoves))
+		}
+	}
+
+	// number of moves made by both players
+	moves := len(g.moves) / 2
+
+	if a.DrawMoves > 0 && moves >= a.DrawMoveNumber &&
+		g.lastScores(a.DrawMoves, func(score int) bool { return -a.DrawScore <= score && score <= a.DrawScore }) {
+		return g.result(Draw, fmt.Sprintf("draw by adjudication: scored within %v of 0 for %v moves",
+			a.DrawScore, a.DrawMoves))
+	}
+
+	if a.MaxMoves > 0 && moves >= a.MaxMoves {
+		return g.result(Draw, fmt.Sprintf("draw by adjudication: %v moves played", a.MaxMoves))
+	}
+
+	return nil
+}
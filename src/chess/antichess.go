@@ -0,0 +1,83 @@
+package chess
+
+import "fmt"
+
+// Antichess is the variant where a player wins by losing all pieces, or by having no legal move. Captures are
+// compulsory, the king is a common piece, which may be captured and which pawns may promote to, and there is no
+// castling.
+var Antichess Variant = antichess{}
+
+type antichess struct{}
+
+func (antichess) Name() string {
+	return "Antichess"
+}
+
+func (v antichess) NewBoard() *Board {
+	b := NewBoard()
+	b.CanWhiteCastleKingside, b.CanWhiteCastleQueenside = false, false
+	b.CanBlackCastleKingside, b.CanBlackCastleQueenside = false, false
+	b.Variant = v
+	return b
+}
+
+func (antichess) CheckMove(b *Board, m *Move) error {
+	if m.IsDrop() {
+		return nil
+	}
+
+	if b.castling(b.PieceAt(m.From), b.PieceAt(m.To), bitmap(m.From), bitmap(m.To)) != noCastling {
+		return fmt.Errorf("there is no castling in Antichess")
+	}
+
+	if !b.IsCapture(m) && b.canCapture() {
+		return fmt.Errorf("captures are compulsory in Antichess")
+	}
+
+	return nil
+}
+
+func (antichess) Moved(b *Board, m *Move) {}
+
+func (antichess) Outcome(b *Board) (Outcome, string, bool) {
+	pieces := b.Pieces[:BlackKing]
+	if b.Turn == Black {
+		pieces = b.Pieces[BlackKing:]
+	}
+
+	for _, p := range pieces {
+		if p != 0 {
+			if b.hasLegalMove() {
+				return 0, "", false
+			}
+
+			return wins(b.Turn), fmt.Sprintf("%v won by having no legal move", b.Turn), true
+		}
+	}
+
+	return wins(b.Turn), fmt.Sprintf("%v won by losing all pieces", b.Turn), true
+}
+
+func (antichess) commonKing() {}
+
+// canCapture returns true iff the player to move attacks a piece of the other player, or may capture en passant
+func (b *Board) canCapture() bool {
+	pieceTypes, others := WhitePieceTypes, BlackPieceTypes
+	if b.Turn == Black {
+		pieceTypes, others = BlackPieceTypes, WhitePieceTypes
+	}
+
+	var targets bitmap
+	for _, p := range others {
+		targets |= b.Pieces[p]
+	}
+
+	for _, p := range pieceTypes {
+		attacked := b.attackedSquares(p)
+		if attacked&targets != 0 || (p == WhitePawn || p == BlackPawn) && attacked&b.EnPassent != 0 {
+			return true
+		}
+	}
+
+	return false
+}
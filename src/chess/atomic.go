@@ -0,0 +1,85 @@
+package chess
+
+import "fmt"
+
+// Atomic is the variant where captures explode: the capturing piece, and all pieces but pawns around the square of the
+// capture, are removed along with the captured piece. Kings can't capture, and a player wins by exploding the king of
+// the other player, which a player may do even while in check. Kings next to each other are never in check, as neither
+// may capture the other.
+var Atomic Variant = atomic{}
+
+type atomic struct{}
+
+func (atomic) Name() string {
+	return "Atomic"
+}
+
+func (v atomic) NewBoard() *Board {
+	b := NewBoard()
+	b.Variant = v
+	return b
+}
+
+func (atomic) CheckMove(b *Board, m *Move) error {
+	if m.IsDrop() {
+		return nil
+	}
+
+	p, captured := b.PieceAt(m.From), b.PieceAt(m.To)
+	if (p == WhiteKing || p == BlackKing) && captured != EmptyPiece && captured.Color() != p.Color() {
+		return fmt.Errorf("kings can't capture in Atomic")
+	}
+
+	after := b.Copy()
+	after.UnsafeMove(m)
+	if after.Pieces[Piece(WhiteKing).ofColor(b.Turn)] == 0 {
+		return fmt.Errorf("can't make a move that explodes own king")
+	}
+
+	return nil
+}
+
+func (atomic) Moved(b *Board, m *Move) {}
+
+func (atomic) Outcome(b *Board) (Outcome, string, bool) {
+	for _, c := range []Color{White, Black} {
+		if b.Pieces[Piece(WhiteKing).ofColor(c)] == 0 {
+			return wins(c.Other()), fmt.Sprintf("%v won by exploding the king", c.Other()), true
+		}
+	}
+
+	return 0, "", false
+}
+
+func (atomic) inCheck(b *Board, c Color) bool {
+	king, other := b.Pieces[Piece(WhiteKing).ofColor(c)], b.Pieces[Piece(WhiteKing).ofColor(c.Other())]
+	if king == 0 || other == 0 || AttackMap[WhiteKing][king]&other != 0 {
+		return false
+	}
+
+	return b.kingAttacked(c)
+}
+
+// captured explodes the pieces around the square of the capture, which loses the castling rights of exploded rooks
+func (atomic) captured(b *Board, m *Move) {
+	center := bitmap(m.To)
+	blast := center | AttackMap[WhiteKing][center]
+	for _, p := range AllPieceTypes {
+		if p == WhitePawn || p == BlackPawn {
+			b.Pieces[p] &^= center
+		} else {
+			b.Pieces[p] &^= blast
+		}
+	}
+
+	for right := range b.castlingRooks {
+		king, rook := Piece(WhiteKing), Piece(WhiteRook)
+		if right == blackKingside || right == blackQueenside {
+			king, rook = BlackKing, BlackRook
+		}
+
+		if b.Pieces[king] == 0 || b.Pieces[rook]&b.castlingRook(right) == 0 {
+			*b.castlingRight(right) = false
+		}
+	}
+}
@@ -116,6 +116,17 @@ func initPawnMaps() {
 		MoveMap[BlackPawn][cursor] = bpmm[i]
 		cursor <<= 1
 	}
+
+	// pawns on their first rank, which only variants such as Horde have, move like pawns on their second rank
+	for cursor := bitmap(1); cursor != 1<<8; cursor <<= 1 {
+		AttackMap[WhitePawn][cursor] = AttackMap[WhitePawn][cursor<<8] >> 8
+		MoveMap[WhitePawn][cursor] = cursor<<8 | cursor<<16
+	}
+
+	for cursor := bitmap(1) << 56; cursor != 0; cursor <<= 1 {
+		AttackMap[BlackPawn][cursor] = AttackMap[BlackPawn][cursor>>8] << 8
+		MoveMap[BlackPawn][cursor] = cursor>>8 | cursor>>16
+	}
 }
 
 func init() {
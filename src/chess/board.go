@@ -1,6 +1,9 @@
 package chess
 
-import "fmt"
+import (
+	"fmt"
+	"math/bits"
+)
 
 var coord map[bitmap][2]uint8
 
@@ -34,6 +37,15 @@ const (
 	Black = iota
 )
 
+// Other returns the opposite color
+func (c Color) Other() Color {
+	if c == White {
+		return Black
+	}
+
+	return White
+}
+
 func (c Color) String() string {
 	switch c {
 	case White:
@@ -70,13 +82,51 @@ type Board struct {
 	CanBlackCastleKingside  bool
 	CanBlackCastleQueenside bool
 
+	// Chess960 is true iff the board plays Fischer Random Chess, where the king and rooks may start on other files, and
+	// castling is written as the king capturing its own rook instead of moving 2 squares
+	Chess960 bool
+
+	// squares of the rooks each castling right castles with, by castling right, zero for the corners
+	castlingRooks [4]bitmap
+
+	// Variant is the variant the board plays, nil for standard chess
+	Variant Variant
+
+	// Checks is the number of checks given by each player, by color, as counted by variants such as Three-Check
+	Checks [2]int
+
+	// Pockets is the number of pieces each player holds in hand, by piece, in variants such as Crazyhouse: captured
+	// pieces change color, so Pockets[WhiteKnight] counts the knights white captured and may drop
+	Pockets [12]int
+
+	// Promoted is the bitmap of pieces that promoted from pawns, which go back to the pockets as pawns when captured
+	Promoted bitmap
+
 	Turn Color
 
-	AllPieces      *bitmap
-	WhiteAttackMap *bitmap
-	BlackAttackMap *bitmap
+	// number of half-moves since the last capture or pawn advance, used for the 50-move rule
+	HalfMoveClock int
+
+	cache boardCache
+}
+
+// boardCache holds values lazily derived from the pieces on a board. It is stored by value, so copies of a board never
+// share any state, and each copy can safely be used by a different goroutine.
+type boardCache struct {
+	valid uint8
+
+	allPieces      bitmap
+	whiteAttackMap bitmap
+	blackAttackMap bitmap
 }
 
+// boardCache validity flags
+const (
+	cachedAllPieces      = 1 << iota
+	cachedWhiteAttackMap = 1 << iota
+	cachedBlackAttackMap = 1 << iota
+)
+
 // NewBoard creates a new board, and returns it.
 func NewBoard() *Board {
 	return &Board{
@@ -90,26 +140,9 @@ func NewBoard() *Board {
 	}
 }
 
-// Copy creates a copy of this board
+// Copy creates a copy of this board. The copy shares no state with this board.
 func (b *Board) Copy() *Board {
 	nb := *b
-
-	// Don't copy pointers
-	if b.AllPieces != nil {
-		tmp := *b.AllPieces
-		nb.AllPieces = &tmp
-	}
-
-	if b.WhiteAttackMap != nil {
-		tmp := *b.WhiteAttackMap
-		nb.WhiteAttackMap = &tmp
-	}
-
-	if b.BlackAttackMap != nil {
-		tmp := *b.BlackAttackMap
-		nb.BlackAttackMap = &tmp
-	}
-
 	return &nb
 }
 
@@ -145,6 +178,38 @@ func (b *Board) String() string {
 	return board
 }
 
+// PieceAt returns the piece on the given square, or EmptyPiece if the square is empty.
+func (b *Board) PieceAt(s Square) Piece {
+	for _, pt := range AllPieceTypes {
+		if b.Pieces[pt]&bitmap(s) != 0 {
+			return pt
+		}
+	}
+
+	return EmptyPiece
+}
+
+// PieceCount returns the number of pieces on the board, kings included
+func (b *Board) PieceCount() int {
+	n := 0
+	for _, pieces := range b.Pieces {
+		n += bits.OnesCount64(uint64(pieces))
+	}
+
+	return n
+}
+
+// IsCapture returns true iff m captures a piece of the other player, en passant included. A Chess960 king castling
+// onto its own rook is not a capture.
+func (b *Board) IsCapture(m *Move) bool {
+	p, captured := b.PieceAt(m.From), b.PieceAt(m.To)
+	if captured != EmptyPiece {
+		return captured.Color() != p.Color()
+	}
+
+	return (p == WhitePawn || p == BlackPawn) && bitmap(m.To) == b.EnPassent
+}
+
 // Returns the piece on a given square on this board. Returns nil if no piece exists on the square.
 // Returns an "invisible" pawn of the appropriate color if square is an EnPassent square.
 func (b *Board) detectPiece(square bitmap) Piece {
@@ -165,6 +230,29 @@ func (b *Board) detectPiece(square bitmap) Piece {
 	return EmptyPiece
 }
 
+// positionKey identifies a position for the purposes of detecting repetitions
+type positionKey struct {
+	Pieces    [12]bitmap
+	EnPassent bitmap
+	Castling  [4]bool
+	Checks    [2]int
+	Pockets   [12]int
+	Promoted  bitmap
+	Turn      Color
+}
+
+func (b *Board) positionKey() positionKey {
+	return positionKey{
+		Pieces:    b.Pieces,
+		EnPassent: b.EnPassent,
+		Castling:  [4]bool{b.CanWhiteCastleKingside, b.CanWhiteCastleQueenside, b.CanBlackCastleKingside, b.CanBlackCastleQueenside},
+		Checks:    b.Checks,
+		Pockets:   b.Pockets,
+		Promoted:  b.Promoted,
+		Turn:      b.Turn,
+	}
+}
+
 type moveCache struct {
 	FromPiece *Piece
 	ToPiece   *Piece
@@ -181,6 +269,9 @@ func (c *moveCache) resolveFromPiece(b *Board, fromSquare bitmap) {
 func (c *moveCache) resolveToPiece(b *Board, toSquare bitmap) {
 	if c.ToPiece == nil {
 		x := b.detectPiece(toSquare)
+		if b.EnPassent&toSquare != 0 && !c.isPawnMove() { // only pawns can capture "invisible" pawns
+			x = EmptyPiece
+		}
 		c.ToPiece = &x
 	}
 }
@@ -188,7 +279,7 @@ func (c *moveCache) resolveToPiece(b *Board, toSquare bitmap) {
 func (c *moveCache) resolveEnPassent(b *Board, toSquare bitmap) {
 	if c.EnPassent == nil {
 		var x bitmap
-		if b.EnPassent == toSquare {
+		if b.EnPassent == toSquare && c.isPawnMove() {
 			x = toSquare
 		}
 
@@ -196,12 +287,23 @@ func (c *moveCache) resolveEnPassent(b *Board, toSquare bitmap) {
 	}
 }
 
+// isPawnMove returns true iff the resolved FromPiece is a pawn. Assumes FromPiece is resolved.
+func (c *moveCache) isPawnMove() bool {
+	return *c.FromPiece == WhitePawn || *c.FromPiece == BlackPawn
+}
+
 // UnsafeMove performs a move on this board without any validity checking.
 func (b *Board) UnsafeMove(m *Move) {
 	b.unsafeMoveWithCache(m, &moveCache{})
 }
 
 func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
+	if m.IsDrop() {
+		b.drop(m)
+		b.endTurn(m)
+		return
+	}
+
 	fromSquare := bitmap(m.From)
 	toSquare := bitmap(m.To)
 
@@ -218,20 +320,25 @@ func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
 	//      b. toPiece is one of {Q,N,B,R} of the same color as fromPiece
 	//      c. toSquare is on the 1st rank if fromPiece is black, and on the 8th rank if fromPiece is white
 	//  4. if a pawn moved up (or down) two squares, it started from its initial position
-	//  5. if a king is moved 2 squares to left or right:
+	//  5. if a king is castling, i.e. moved 2 squares to left or right, or onto its own rook in Chess960:
 	//		a. the king has never been moved before
-	//		b. there is a rook that has never been moved in the corner of the direction of movement
+	//		b. the rook it castles with has never been moved
 	//		c. the king is not currently in check
 	// 		d. the squares between the king and the rook are empty
 	//		e. the two squares the king is moving through are not controlled by a piece of the opposite color
 	fromPiece := *c.FromPiece
 	toPiece := *c.ToPiece
 
-	// moves fromPiece fromSquare -> toSquare
-	b.Pieces[fromPiece] ^= (fromSquare | toSquare)
-	if m.Promotion != EmptyPiece {
-		b.Pieces[fromPiece] ^= toSquare   // remove piece from destination square
-		b.Pieces[m.Promotion] ^= toSquare // add piece to destination square
+	if right := b.castling(fromPiece, toPiece, fromSquare, toSquare); right != noCastling {
+		b.castle(right)
+		toPiece = EmptyPiece // a Chess960 king "captures" its own rook
+	} else {
+		// moves fromPiece fromSquare -> toSquare
+		b.Pieces[fromPiece] ^= (fromSquare | toSquare)
+		if m.Promotion != EmptyPiece {
+			b.Pieces[fromPiece] ^= toSquare   // remove piece from destination square
+			b.Pieces[m.Promotion] ^= toSquare // add piece to destination square
+		}
 	}
 
 	// removes toPiece from existing square
@@ -249,6 +356,31 @@ func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
 		}
 
 		b.Pieces[toPiece] ^= capturedSquare
+		if b.hasPockets() {
+			b.pocket(toPiece, capturedSquare)
+		}
+
+		if v, ok := b.Variant.(captureVariant); ok {
+			v.captured(b, m)
+		}
+	}
+
+	// promoted pieces keep track of being promoted
+	if b.hasPockets() {
+		if b.Promoted&fromSquare != 0 {
+			b.Promoted ^= fromSquare | toSquare
+		}
+
+		if m.Promotion != EmptyPiece {
+			b.Promoted |= toSquare
+		}
+	}
+
+	// captures and pawn advances reset the 50-move rule counter
+	if toPiece != EmptyPiece || fromPiece == WhitePawn || fromPiece == BlackPawn {
+		b.HalfMoveClock = 0
+	} else {
+		b.HalfMoveClock++
 	}
 
 	// check if EnPassent is possible
@@ -261,17 +393,6 @@ func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
 		b.EnPassent = 0
 	}
 
-	switch {
-	case fromPiece == WhiteKing && fromSquare>>2 == toSquare: // white castling king-side
-		b.Pieces[WhiteRook] ^= 5
-	case fromPiece == WhiteKing && fromSquare<<2 == toSquare: // white castling queen-side
-		b.Pieces[WhiteRook] ^= 144
-	case fromPiece == BlackKing && fromSquare>>2 == toSquare: // black castling king-side
-		b.Pieces[BlackRook] ^= 360287970189639680
-	case fromPiece == BlackKing && fromSquare<<2 == toSquare: // black castling queen-side
-		b.Pieces[BlackRook] ^= 10376293541461622784
-	}
-
 	// moving the king strips castling rights
 	if fromPiece == WhiteKing {
 		b.CanWhiteCastleKingside = false
@@ -283,27 +404,20 @@ func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
 		b.CanBlackCastleQueenside = false
 	}
 
-	// moving the rook strips castling rights on that side
-	if fromPiece == WhiteRook && fromSquare == 128 {
-		b.CanWhiteCastleQueenside = false
-	}
-
-	if fromPiece == WhiteRook && fromSquare == 1 {
-		b.CanWhiteCastleKingside = false
-	}
-
-	if fromPiece == BlackRook && fromSquare == 9223372036854775808 {
-		b.CanBlackCastleQueenside = false
+	// moving (or capturing) the rook strips castling rights on that side
+	for right := range b.castlingRooks {
+		if (fromSquare|toSquare)&b.castlingRook(right) != 0 {
+			*b.castlingRight(right) = false
+		}
 	}
 
-	if fromPiece == BlackRook && fromSquare == 72057594037927936 {
-		b.CanBlackCastleKingside = false
-	}
+	b.endTurn(m)
+}
 
+// endTurn passes the turn to the other player once m was played on this board
+func (b *Board) endTurn(m *Move) {
 	// pieces changed, reset cache
-	b.AllPieces = nil
-	b.WhiteAttackMap = nil
-	b.BlackAttackMap = nil
+	b.cache = boardCache{}
 
 	// toggle turn
 	if b.Turn == White {
@@ -311,6 +425,10 @@ func (b *Board) unsafeMoveWithCache(m *Move, c *moveCache) {
 	} else {
 		b.Turn = White
 	}
+
+	if b.Variant != nil {
+		b.Variant.Moved(b, m)
+	}
 }
 
 // UndoLastMove undos the last move played on this board.
@@ -323,8 +441,22 @@ func (b *Board) CheckMove(m *Move) error {
 	return b.checkMoveWithCache(m, &moveCache{})
 }
 
-// InCheck returns true iff the king of the given color is currently in check
+// InCheck returns true iff the king of the given color is currently in check. Kings are never in check in variants
+// where they are common pieces.
 func (b *Board) InCheck(c Color) bool {
+	if v, ok := b.Variant.(checkVariant); ok {
+		return v.inCheck(b, c)
+	}
+
+	if b.hasCommonKing() {
+		return false
+	}
+
+	return b.kingAttacked(c)
+}
+
+// kingAttacked returns true iff the king of the given color is attacked by a piece of the other color
+func (b *Board) kingAttacked(c Color) bool {
 	if c == White {
 		return b.dynamicAttackMap(Black)&b.Pieces[WhiteKing] != 0
 	}
@@ -334,21 +466,130 @@ func (b *Board) InCheck(c Color) bool {
 
 // IsCheckmate returns true iff the position is checkmate
 func (b *Board) IsCheckmate() bool {
-	// TODO
-	return false
+	return b.InCheck(b.Turn) && !b.hasLegalMove()
 }
 
 // IsStalemate returns true iff the position is stalemate
 func (b *Board) IsStalemate() bool {
-	// TODO
+	return !b.InCheck(b.Turn) && !b.hasLegalMove()
+}
+
+// IsInsufficientMaterial returns true iff neither side has enough material left to checkmate, i.e.
+// K vs K, K+N vs K, K+B vs K, or K+B vs K+B with both bishops on the same square color.
+func (b *Board) IsInsufficientMaterial() bool {
+	if v, ok := b.Variant.(materialVariant); ok {
+		return v.insufficientMaterial(b)
+	}
+
+	if b.Pockets != [12]int{} {
+		return false // pieces in hand can still be dropped
+	}
+
+	if b.hasCommonKing() {
+		return false // checkmate isn't what wins
+	}
+
+	if b.Pieces[WhiteQueen]|b.Pieces[BlackQueen]|b.Pieces[WhiteRook]|b.Pieces[BlackRook]|b.Pieces[WhitePawn]|b.Pieces[BlackPawn] != 0 {
+		return false
+	}
+
+	minors := b.Pieces[WhiteKnight] | b.Pieces[BlackKnight] | b.Pieces[WhiteBishop] | b.Pieces[BlackBishop]
+	if bits.OnesCount64(uint64(minors)) <= 1 {
+		return true
+	}
+
+	// bishops of the same square color can never deliver mate
+	var lightSquares bitmap = 0x55AA55AA55AA55AA
+	bishops := b.Pieces[WhiteBishop] | b.Pieces[BlackBishop]
+	if b.Pieces[WhiteKnight]|b.Pieces[BlackKnight] == 0 && b.Pieces[WhiteBishop] != 0 && b.Pieces[BlackBishop] != 0 {
+		return bishops&lightSquares == 0 || bishops&^lightSquares == 0
+	}
+
 	return false
 }
 
+// LegalMoves returns all legal moves for the player whose turn it is. There are none once the variant of the board has
+// ended the game.
+func (b *Board) LegalMoves() []*Move {
+	if _, _, over := b.VariantOutcome(); over {
+		return nil
+	}
+
+	var moves []*Move
+	b.forEachLegalMove(func(m *Move) bool {
+		moves = append(moves, m)
+		return true
+	})
+
+	return moves
+}
+
+// hasLegalMove returns true iff the player whose turn it is has at least one legal move.
+func (b *Board) hasLegalMove() bool {
+	found := false
+	b.forEachLegalMove(func(m *Move) bool {
+		found = true
+		return false
+	})
+
+	return found
+}
+
+// forEachLegalMove calls f on each legal move of the player whose turn it is, stopping early if f returns false.
+func (b *Board) forEachLegalMove(f func(m *Move) bool) {
+	pieceTypes := WhitePieceTypes
+	promotions := []Piece{WhiteQueen, WhiteKnight, WhiteRook, WhiteBishop}
+	if b.Turn == Black {
+		pieceTypes = BlackPieceTypes
+		promotions = []Piece{BlackQueen, BlackKnight, BlackRook, BlackBishop}
+	}
+
+	if b.hasCommonKing() {
+		promotions = append(promotions, Piece(WhiteKing).ofColor(b.Turn))
+	}
+
+	for _, p := range pieceTypes {
+		for fromSquare := bitmap(1); fromSquare != 0; fromSquare <<= 1 {
+			if b.Pieces[p]&fromSquare == 0 {
+				continue
+			}
+
+			targets := MoveMap[p][fromSquare] | AttackMap[p][fromSquare]
+			if b.Chess960 && (p == WhiteKing || p == BlackKing) {
+				targets |= b.castlingTargets(p.Color())
+			}
+			for toSquare := bitmap(1); toSquare != 0; toSquare <<= 1 {
+				if targets&toSquare == 0 {
+					continue
+				}
+
+				candidates := []Piece{EmptyPiece}
+				if (p == WhitePawn || p == BlackPawn) && toSquare&lastRanks != 0 {
+					candidates = promotions
+				}
+
+				for _, promotion := range candidates {
+					m := NewMove(Square(fromSquare), Square(toSquare), promotion)
+					if b.CheckMove(m) != nil {
+						continue
+					}
+
+					if !f(m) {
+						return
+					}
+				}
+			}
+		}
+	}
+
+	b.forEachLegalDrop(f)
+}
+
 func (b *Board) dynamicAttackMap(c Color) bitmap {
 	switch c {
 	case White:
-		if b.WhiteAttackMap != nil {
-			return *b.WhiteAttackMap
+		if b.cache.valid&cachedWhiteAttackMap != 0 {
+			return b.cache.whiteAttackMap
 		}
 
 		var dam bitmap = 0
@@ -356,11 +597,12 @@ func (b *Board) dynamicAttackMap(c Color) bitmap {
 			dam |= b.attackedSquares(p)
 		}
 
-		b.WhiteAttackMap = &dam
+		b.cache.whiteAttackMap = dam
+		b.cache.valid |= cachedWhiteAttackMap
 		return dam
 	case Black:
-		if b.BlackAttackMap != nil {
-			return *b.BlackAttackMap
+		if b.cache.valid&cachedBlackAttackMap != 0 {
+			return b.cache.blackAttackMap
 		}
 
 		var dam bitmap = 0
@@ -368,7 +610,8 @@ func (b *Board) dynamicAttackMap(c Color) bitmap {
 			dam |= b.attackedSquares(p)
 		}
 
-		b.BlackAttackMap = &dam
+		b.cache.blackAttackMap = dam
+		b.cache.valid |= cachedBlackAttackMap
 		return dam
 	default:
 		panic("Unhandled Color case")
@@ -457,14 +700,17 @@ func (b *Board) attackedSquares(p Piece) bitmap {
 	return attacked
 }
 
-func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
-	if b.IsCheckmate() {
-		return fmt.Errorf("game is over")
-	}
+// lastRanks is the bitmap of all squares on the 1st and 8th ranks
+const lastRanks bitmap = 0xFF000000000000FF
 
+func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
 	fromSquare := bitmap(m.From)
 	toSquare := bitmap(m.To)
 
+	if m.IsDrop() {
+		return b.checkDrop(m)
+	}
+
 	c.resolveFromPiece(b, fromSquare)
 	c.resolveToPiece(b, toSquare)
 	c.resolveEnPassent(b, toSquare)
@@ -476,12 +722,28 @@ func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
 		return fmt.Errorf("there must be a piece on the source square")
 	}
 
+	if fromPiece.Color() != b.Turn {
+		return fmt.Errorf("cannot move piece of different color of turn")
+	}
+
+	if right := b.castling(fromPiece, toPiece, fromSquare, toSquare); right != noCastling {
+		if m.Promotion != EmptyPiece {
+			return fmt.Errorf("only pawns can promote")
+		}
+
+		if err := b.checkCastle(m, right); err != nil {
+			return err
+		}
+
+		return b.checkVariantMove(m)
+	}
+
 	if toPiece != EmptyPiece && toPiece.Color() == fromPiece.Color() {
 		return fmt.Errorf("can't capture piece of same color")
 	}
 
-	if fromPiece.Color() != b.Turn {
-		return fmt.Errorf("cannot move piece of different color of turn")
+	if b.Chess960 && (fromPiece == WhiteKing || fromPiece == BlackKing) && (fromSquare>>2 == toSquare || fromSquare<<2 == toSquare) {
+		return fmt.Errorf("castling is written as the king capturing its own rook in Chess960")
 	}
 
 	if (MoveMap[fromPiece][fromSquare]|AttackMap[fromPiece][fromSquare])&toSquare == 0 {
@@ -492,6 +754,14 @@ func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
 		return fmt.Errorf("pawn captures can't occur on empty squares")
 	}
 
+	if (fromPiece == WhitePawn || fromPiece == BlackPawn) && MoveMap[fromPiece][fromSquare]&toSquare != 0 && toPiece != EmptyPiece {
+		return fmt.Errorf("pawns can't capture moving forward")
+	}
+
+	if (fromPiece == WhitePawn || fromPiece == BlackPawn) && toSquare&lastRanks != 0 && m.Promotion == EmptyPiece {
+		return fmt.Errorf("pawns must promote on the last rank")
+	}
+
 	if fromSquare == toSquare {
 		return fmt.Errorf("destination square can't be same as source square")
 	}
@@ -501,75 +771,21 @@ func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
 	}
 
 	copy := b.Copy()
-	copy.UnsafeMove(m)
+	copy.unsafeMoveWithCache(m, c)
 	if copy.InCheck(b.Turn) {
 		return fmt.Errorf("can't make a move that leaves king in check")
 	}
 
-	switch {
-	case fromPiece == WhiteKing && fromSquare>>2 == toSquare: // white castling king-side
-		if !b.CanWhiteCastleKingside {
-			return fmt.Errorf("white is not allowed to castle kingside")
-		}
-
-		copy := b.Copy()
-		copy.UnsafeMove(NewMove(m.From, m.From>>1, EmptyPiece))
-		if copy.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle through check")
-		}
-
-		if b.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle out of check")
-		}
-	case fromPiece == WhiteKing && fromSquare<<2 == toSquare: // white castling queen-side
-		if !b.CanWhiteCastleQueenside {
-			return fmt.Errorf("white is not allowed to castle queenside")
-		}
-
-		copy := b.Copy()
-		copy.UnsafeMove(NewMove(m.From, m.From<<1, EmptyPiece))
-		if copy.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle through check")
-		}
-
-		if b.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle out of check")
-		}
-	case fromPiece == BlackKing && fromSquare>>2 == toSquare: // black castling king-side
-		if !b.CanBlackCastleKingside {
-			return fmt.Errorf("black is not allowed to castle kingside")
-		}
-
-		copy := b.Copy()
-		copy.UnsafeMove(NewMove(m.From, m.From>>1, EmptyPiece))
-		if copy.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle through check")
-		}
-
-		if b.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle out of check")
-		}
-	case fromPiece == BlackKing && fromSquare<<2 == toSquare: // black castling queen-side
-		if !b.CanBlackCastleQueenside {
-			return fmt.Errorf("black is not allowed to castle queenside")
-		}
-
-		copy := b.Copy()
-		copy.UnsafeMove(NewMove(m.From, m.From<<1, EmptyPiece))
-		if copy.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle through check")
-		}
-
-		if b.InCheck(b.Turn) {
-			return fmt.Errorf("can't castle out of check")
-		}
-	}
-
 	switch m.Promotion {
 	case EmptyPiece:
 		break // do nothing
-	case WhitePawn, BlackPawn, WhiteKing, BlackKing:
+	case WhitePawn, BlackPawn:
 		return fmt.Errorf("can't promote to a pawn or king")
+	case WhiteKing, BlackKing:
+		if !b.hasCommonKing() {
+			return fmt.Errorf("can't promote to a pawn or king")
+		}
+		fallthrough
 	default:
 		if fromPiece != WhitePawn && fromPiece != BlackPawn {
 			return fmt.Errorf("only pawns can promote")
@@ -588,8 +804,16 @@ func (b *Board) checkMoveWithCache(m *Move, c *moveCache) error {
 		}
 	}
 
-	// All checks passed
-	return nil
+	return b.checkVariantMove(m)
+}
+
+// checkVariantMove checks that a move legal in standard chess is legal in the variant of the board
+func (b *Board) checkVariantMove(m *Move) error {
+	if b.Variant == nil {
+		return nil
+	}
+
+	return b.Variant.CheckMove(b, m)
 }
 
 func direction(fromSquare, toSquare bitmap) Direction {
@@ -659,8 +883,8 @@ func (b *Board) Test(c Color) bitmap {
 }
 
 func (b *Board) allPieces() bitmap {
-	if b.AllPieces != nil {
-		return *b.AllPieces
+	if b.cache.valid&cachedAllPieces != 0 {
+		return b.cache.allPieces
 	}
 
 	var m bitmap = 0
@@ -668,6 +892,7 @@ func (b *Board) allPieces() bitmap {
 		m |= b.Pieces[p]
 	}
 
-	b.AllPieces = &m
+	b.cache.allPieces = m
+	b.cache.valid |= cachedAllPieces
 	return m
 }
@@ -1,6 +1,7 @@
 package chess
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -496,3 +497,1085 @@ func TestMove(t *testing.T) {
 		t.Fatalf("Expected an errors, but got: %v", err)
 	}
 }
+
+func TestIsInsufficientMaterial(t *testing.T) {
+	// squares by index, where h1 is 0 and a8 is 63
+	sq := func(i uint) bitmap { return 1 << i }
+
+	tests := []struct {
+		pieces   map[Piece]bitmap
+		expected bool
+	}{
+		{map[Piece]bitmap{}, true},
+		{map[Piece]bitmap{WhiteKnight: sq(10)}, true},
+		{map[Piece]bitmap{BlackBishop: sq(10)}, true},
+		{map[Piece]bitmap{WhiteBishop: sq(0), BlackBishop: sq(2)}, true},
+		{map[Piece]bitmap{WhiteBishop: sq(0), BlackBishop: sq(1)}, false},
+		{map[Piece]bitmap{WhiteKnight: sq(10), BlackKnight: sq(20)}, false},
+		{map[Piece]bitmap{WhiteRook: sq(10)}, false},
+		{map[Piece]bitmap{BlackPawn: sq(20)}, false},
+	}
+
+	for i, test := range tests {
+		b := &Board{}
+		b.Pieces[WhiteKing] = sq(3)
+		b.Pieces[BlackKing] = sq(59)
+		for p, pieces := range test.pieces {
+			b.Pieces[p] = pieces
+		}
+
+		if b.IsInsufficientMaterial() != test.expected {
+			t.Errorf("Test %v: expected insufficient material to be %v", i, test.expected)
+		}
+	}
+}
+
+func TestHalfMoveClock(t *testing.T) {
+	b := NewBoard()
+
+	// 1. Nf3 Nc6 resets nothing, 2. e4 does
+	playMoves(t, b, [][2]Coordinate{{"g1", "f3"}, {"b8", "c6"}})
+	if b.HalfMoveClock != 2 {
+		t.Fatalf("Expected 2 half-moves without captures or pawn advances, but got: %v", b.HalfMoveClock)
+	}
+
+	playMoves(t, b, [][2]Coordinate{{"e2", "e4"}})
+	if b.HalfMoveClock != 0 {
+		t.Fatalf("Expected a pawn advance to reset the half-move clock, but got: %v", b.HalfMoveClock)
+	}
+}
+
+// replayPlayer plays the given moves in order, and waits for the end of the game once it runs out of them
+type replayPlayer struct {
+	moves  [][2]Coordinate
+	prompt chan Prompt
+	move   chan *Move
+}
+
+func (rp *replayPlayer) Init(c Color, gc GameClient, prompt chan Prompt, move chan *Move) {
+	rp.prompt = prompt
+	rp.move = move
+}
+
+func (rp *replayPlayer) Run() {
+	i := 0
+	for range rp.prompt {
+		if i == len(rp.moves) {
+			continue
+		}
+
+		rp.move <- newMove(rp.moves[i][0], rp.moves[i][1])
+		i++
+	}
+}
+
+func TestGameResult(t *testing.T) {
+	tests := []struct {
+		white, black [][2]Coordinate
+		outcome      Outcome
+		reason       string
+		moves        int
+	}{
+		// fool's mate
+		{[][2]Coordinate{{"f2", "f3"}, {"g2", "g4"}}, [][2]Coordinate{{"e7", "e5"}, {"d8", "h4"}}, BlackWon,
+			"Black won via checkmate", 4},
+
+		// knights back and forth repeat the starting position for the 3rd time
+		{[][2]Coordinate{{"g1", "f3"}, {"f3", "g1"}, {"g1", "f3"}, {"f3", "g1"}},
+			[][2]Coordinate{{"g8", "f6"}, {"f6", "g8"}, {"g8", "f6"}, {"f6", "g8"}}, Draw, "draw by 3-fold repetition", 8},
+
+		// illegal moves lose
+		{[][2]Coordinate{{"e2", "e5"}}, nil, BlackWon, "", 0},
+	}
+
+	for i, test := range tests {
+		g := NewGame(&replayPlayer{moves: test.white}, &replayPlayer{moves: test.black}, InfiniteTime{})
+		result := g.Start()
+
+		if result.Outcome != test.outcome || test.reason != "" && result.Reason != test.reason || len(result.Moves) != test.moves {
+			t.Errorf("Test %v: expected %v (%v) after %v moves, but got: %v (%v) after %v moves", i, test.outcome,
+				test.reason, test.moves, result.Outcome, result.Reason, len(result.Moves))
+		}
+	}
+}
+
+func playMoves(t *testing.T, b *Board, moves [][2]Coordinate) {
+	for i, m := range moves {
+		if err := b.Move(newMove(m[0], m[1])); err != nil {
+			t.Fatalf("Move %v (%v -> %v) failed: %v", i+1, m[0], m[1], err)
+		}
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	b := NewBoard()
+
+	if n := len(b.LegalMoves()); n != 20 {
+		t.Fatalf("Expected 20 legal moves in the starting position, but got: %v", n)
+	}
+
+	// 1. e4 f5 2. Qh5+
+	playMoves(t, b, [][2]Coordinate{{"e2", "e4"}, {"f7", "f5"}, {"d1", "h5"}})
+
+	// only g6 blocks the check
+	moves := b.LegalMoves()
+	if len(moves) != 1 || *moves[0] != *newMove("g7", "g6") {
+		t.Fatalf("Expected only g6 to be legal, but got: %v", moves)
+	}
+}
+
+func TestIsCheckmate(t *testing.T) {
+	b := NewBoard()
+
+	// 1. f3 e5 2. g4
+	playMoves(t, b, [][2]Coordinate{{"f2", "f3"}, {"e7", "e5"}, {"g2", "g4"}})
+	if b.IsCheckmate() {
+		t.Fatalf("Expected no checkmate before Qh4#")
+	}
+
+	// Qh4#
+	playMoves(t, b, [][2]Coordinate{{"d8", "h4"}})
+	if !b.IsCheckmate() {
+		t.Fatalf("Expected checkmate after Qh4#")
+	}
+
+	if b.IsStalemate() {
+		t.Fatalf("Expected checkmate not to be stalemate")
+	}
+}
+
+func TestIsStalemate(t *testing.T) {
+	b := NewBoard()
+
+	// Sam Loyd's 10-move stalemate
+	playMoves(t, b, [][2]Coordinate{
+		{"e2", "e3"}, {"a7", "a5"},
+		{"d1", "h5"}, {"a8", "a6"},
+		{"h5", "a5"}, {"h7", "h5"},
+		{"h2", "h4"}, {"a6", "h6"},
+		{"a5", "c7"}, {"f7", "f6"},
+		{"c7", "d7"}, {"e8", "f7"},
+		{"d7", "b7"}, {"d8", "d3"},
+		{"b7", "b8"}, {"d3", "h7"},
+		{"b8", "c8"}, {"f7", "g6"},
+	})
+
+	if b.IsStalemate() {
+		t.Fatalf("Expected no stalemate before Qe6")
+	}
+
+	playMoves(t, b, [][2]Coordinate{{"c8", "e6"}})
+	if !b.IsStalemate() {
+		t.Fatalf("Expected stalemate after Qe6")
+	}
+}
+
+func TestCastlingRequiresRook(t *testing.T) {
+	b := NewBoard()
+
+	// 1. g3 b6 2. Bh3 Bb7 3. Nf3 Bxf3 4. Nc3 Bxh1
+	playMoves(t, b, [][2]Coordinate{
+		{"g2", "g3"}, {"b7", "b6"},
+		{"f1", "h3"}, {"c8", "b7"},
+		{"g1", "f3"}, {"b7", "f3"},
+		{"b1", "c3"}, {"f3", "h1"},
+	})
+
+	// rook on h1 was captured
+	if err := b.Move(newMove("e1", "g1")); err == nil {
+		t.Fatalf("Expected an error castling without a rook, but got: %v", err)
+	}
+}
+
+func TestFEN(t *testing.T) {
+	b, err := NewBoardFromFEN(StartFEN)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if *b != *NewBoard() {
+		t.Fatalf("Expected the starting position, but got:\n%v", b)
+	}
+
+	if fen := b.FEN(); fen != StartFEN {
+		t.Fatalf("Expected %v, but got: %v", StartFEN, fen)
+	}
+
+	playMoves(t, b, [][2]Coordinate{{"e2", "e4"}, {"c7", "c5"}, {"g1", "f3"}, {"c5", "c4"}, {"d2", "d4"}})
+
+	expected := "rnbqkbnr/pp1ppppp/8/8/2pPP3/5N2/PPP2PPP/RNBQKB1R b KQkq d3 0 1"
+	if fen := b.FEN(); fen != expected {
+		t.Fatalf("Expected %v, but got: %v", expected, fen)
+	}
+
+	// en-passent must still be possible after a round trip
+	b, err = NewBoardFromFEN(expected)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if err := b.Move(newMove("c4", "d3")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	for _, fen := range []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQXBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1",
+	} {
+		if _, err := NewBoardFromFEN(fen); err == nil {
+			t.Errorf("Expected an error parsing %q", fen)
+		}
+	}
+}
+
+func TestUCIMove(t *testing.T) {
+	tests := []struct {
+		uci   string
+		color Color
+		move  *Move
+	}{
+		{"e2e4", White, newMove("e2", "e4")},
+		{"e7e8q", White, newMovePromotion("e7", "e8", WhiteQueen)},
+		{"a2a1n", Black, newMovePromotion("a2", "a1", BlackKnight)},
+	}
+
+	for _, test := range tests {
+		m, err := NewMoveUCI(test.uci, test.color)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		if *m != *test.move {
+			t.Errorf("Expected %v, but got: %v", test.move, m)
+		}
+
+		if m.UCI() != test.uci {
+			t.Errorf("Expected %v, but got: %v", test.uci, m.UCI())
+		}
+
+		if m.IsDrop() || m.Drop != EmptyPiece {
+			t.Errorf("Expected %v to drop no piece, but got: %v", test.uci, m.Drop)
+		}
+	}
+
+	for _, uci := range []string{"e2e", "e2e4p", "i2e4", "e2e9"} {
+		if _, err := NewMoveUCI(uci, White); err == nil {
+			t.Errorf("Expected an error parsing %v", uci)
+		}
+	}
+}
+
+func TestXBoardMove(t *testing.T) {
+	tests := []struct {
+		fen    string
+		xboard string
+		uci    string
+	}{
+		{StartFEN, "e2e4", "e2e4"},
+		// standard castling is written as the king's move
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "e1g1"},
+		// Chess960 castling is written O-O or O-O-O, the king capturing its rook in UCI
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1", "O-O", "f1g1"},
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1", "O-O-O", "f1b1"},
+	}
+
+	for _, test := range tests {
+		b, err := NewBoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		m, err := b.ParseXBoardMove(test.xboard)
+		if err != nil {
+			t.Fatalf("Expected no errors parsing %v, but got: %v", test.xboard, err)
+		}
+
+		if m.UCI() != test.uci {
+			t.Errorf("Expected %v to be %v, but got: %v", test.xboard, test.uci, m.UCI())
+		}
+
+		if s := b.XBoardMove(m); s != test.xboard {
+			t.Errorf("Expected %v to be written %v, but got: %v", test.uci, test.xboard, s)
+		}
+	}
+
+	b, _ := NewBoardFromFEN("r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1")
+	if m, err := b.ParseXBoardMove("0-0"); err != nil || m.UCI() != "f1g1" {
+		t.Errorf("Expected 0-0 to be f1g1, but got: %v (%v)", m, err)
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen string
+		uci string
+		san string
+	}{
+		{StartFEN, "g1f3", "Nf3"},
+		{StartFEN, "e2e4", "e4"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "b1d2", "Nbd2"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+		{"4k3/8/8/3p4/8/2N5/8/4K3 w - - 0 1", "c3d5", "Nxd5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8#"},
+	}
+
+	for _, test := range tests {
+		b, err := NewBoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		m, err := NewMoveUCI(test.uci, b.Turn)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		if san := b.SAN(m); san != test.san {
+			t.Errorf("Expected %v for %v on %v, but got: %v", test.san, test.uci, test.fen, san)
+		}
+
+		if parsed, err := b.ParseSAN(test.san); err != nil || *parsed != *m {
+			t.Errorf("Expected %v for %v on %v, but got: %v, %v", test.uci, test.san, test.fen, parsed, err)
+		}
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen string
+		san string
+		uci string
+	}{
+		{StartFEN, "Ngf3", "g1f3"},
+		{StartFEN, "e4!?", "e2e4"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0", "e1c1"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8N", "b7b8n"},
+		{"4k3/8/8/3p4/8/2N5/8/4K3 w - - 0 1", "Nd5", "c3d5"},
+	}
+
+	for _, test := range tests {
+		b, _ := NewBoardFromFEN(test.fen)
+		m, err := b.ParseSAN(test.san)
+		if err != nil {
+			t.Fatalf("Expected no errors parsing %v, but got: %v", test.san, err)
+		}
+
+		if m.UCI() != test.uci {
+			t.Errorf("Expected %v for %v, but got: %v", test.uci, test.san, m.UCI())
+		}
+	}
+
+	b, _ := NewBoardFromFEN("4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1")
+	for _, san := range []string{"Nd2", "Ne4", "e4", "b8=Q", "Nz2", "O-O", ""} {
+		if _, err := b.ParseSAN(san); err == nil {
+			t.Errorf("Expected an error parsing %v", san)
+		}
+	}
+}
+
+// scriptedPlayer plays the given moves in order, scoring each one with score if set
+type scriptedPlayer struct {
+	moves  []string
+	score  *int
+	prompt chan Prompt
+	move   chan *Move
+	color  Color
+}
+
+func (sp *scriptedPlayer) Init(c Color, gc GameClient, prompt chan Prompt, move chan *Move) {
+	sp.color = c
+	sp.prompt = prompt
+	sp.move = move
+}
+
+func (sp *scriptedPlayer) Run() {
+	i := 0
+	for range sp.prompt {
+		m, _ := NewMoveUCI(sp.moves[i], sp.color)
+		i++
+		sp.move <- m
+	}
+}
+
+func (sp *scriptedPlayer) Score() (int, bool) {
+	if sp.score == nil {
+		return 0, false
+	}
+
+	return *sp.score, true
+}
+
+// endgameTablebase knows black wins once white has played a4
+type endgameTablebase struct{}
+
+func (endgameTablebase) Outcome(b *Board) (Outcome, bool) {
+	a4, _ := Coordinate("a4").Square()
+	return BlackWon, b.PieceAt(a4) == WhitePawn
+}
+
+func TestAdjudication(t *testing.T) {
+	whiteMoves := []string{"a2a3", "b2b3", "c2c3", "d2d3", "e2e3", "f2f3", "g2g3", "h2h3", "a3a4"}
+	blackMoves := []string{"a7a6", "b7b6", "c7c6", "d7d6", "e7e6", "f7f6", "g7g6", "h7h6", "a6a5"}
+	winning, losing, even := 700, -700, 20
+
+	tests := []struct {
+		adjudication Adjudication
+		white, black *int
+		outcome      Outcome
+		moves        int
+	}{
+		{Adjudication{MaxMoves: 3}, nil, nil, Draw, 6},
+		{Adjudication{ResignScore: 600, ResignMoves: 3}, &winning, &losing, WhiteWon, 6},
+		{Adjudication{ResignScore: 600, ResignMoves: 3}, &losing, &winning, BlackWon, 6},
+
+		// the scores must agree
+		{Adjudication{ResignScore: 600, ResignMoves: 3, MaxMoves: 5}, &winning, &winning, Draw, 10},
+		{Adjudication{ResignScore: 600, ResignMoves: 3, MaxMoves: 5}, &winning, nil, Draw, 10},
+
+		{Adjudication{DrawMoveNumber: 4, DrawScore: 30, DrawMoves: 2}, &even, &even, Draw, 8},
+		{Adjudication{DrawMoveNumber: 1, DrawScore: 30, DrawMoves: 2}, &even, &even, Draw, 4},
+		{Adjudication{Tablebase: endgameTablebase{}}, nil, nil, BlackWon, 17},
+	}
+
+	for i, test := range tests {
+		white := &scriptedPlayer{moves: whiteMoves, score: test.white}
+		black := &scriptedPlayer{moves: blackMoves, score: test.black}
+
+		g := NewGame(white, black, InfiniteTime{})
+		g.Adjudication = test.adjudication
+		result := g.Start()
+
+		if result.Outcome != test.outcome || len(result.Moves) != test.moves {
+			t.Errorf("Test %v: expected %v after %v moves, but got: %v after %v moves (%v)", i, test.outcome,
+				test.moves, result.Outcome, len(result.Moves), result.Reason)
+		}
+	}
+
+	if g := NewGame(&scriptedPlayer{}, &scriptedPlayer{}, InfiniteTime{}); g.Adjudication.MaxMoves != DefaultMaxMoves {
+		t.Errorf("Expected new games to be drawn after %v moves, but got: %+v", DefaultMaxMoves, g.Adjudication)
+	}
+}
+
+func TestChess960(t *testing.T) {
+	// the standard starting position, and the first and last in the numbering
+	for index, rank := range map[int]string{518: "RNBQKBNR", 0: "BBQNNRKR", 959: "RKRNNQBB"} {
+		b, err := NewChess960Board(index)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		fen := strings.ToLower(rank) + "/pppppppp/8/8/8/8/PPPPPPPP/" + rank + " w KQkq - 0 1"
+		if b.FEN() != fen {
+			t.Errorf("Expected position %v to be %v, but got: %v", index, fen, b.FEN())
+		}
+	}
+
+	positions := make(map[string]bool)
+	for index := 0; index < 960; index++ {
+		b, _ := NewChess960Board(index)
+		positions[b.FEN()] = true
+
+		if n := len(b.LegalMoves()); n < 18 {
+			t.Fatalf("Expected at least 18 legal moves in position %v, but got: %v", index, n)
+		}
+	}
+
+	if len(positions) != 960 {
+		t.Errorf("Expected 960 different positions, but got: %v", len(positions))
+	}
+
+	if _, err := NewChess960Board(960); err == nil {
+		t.Errorf("Expected an error for position 960")
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tests := []struct {
+		fen   string
+		move  string
+		san   string
+		after string
+	}{
+		// the king and rooks start on other files
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1", "f1g1", "O-O", "r3k2r/8/8/8/8/8/8/1R3RK1 b kq - 1 1"},
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1", "f1b1", "O-O-O", "r3k2r/8/8/8/8/8/8/2KR2R1 b kq - 1 1"},
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 b KQkq - 0 1", "e8a8", "O-O-O", "2kr3r/8/8/8/8/8/8/1R3KR1 w KQ - 1 1"},
+
+		// the king doesn't move
+		{"4k3/8/8/8/8/8/8/R5KR w HA - 0 1", "g1h1", "O-O", "4k3/8/8/8/8/8/8/R4RK1 b - - 1 1"},
+
+		// in Shredder-FEN, with an inner rook castling
+		{"4k3/8/8/8/8/8/8/RR2K3 w B - 0 1", "e1b1", "O-O-O", "4k3/8/8/8/8/8/8/R1KR4 b - - 1 1"},
+	}
+
+	for _, test := range tests {
+		b, err := NewBoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		if !b.Chess960 {
+			t.Fatalf("Expected %v to be a Chess960 position", test.fen)
+		}
+
+		m, err := b.ParseSAN(test.san)
+		if err != nil {
+			t.Fatalf("Expected %v to be legal in %v, but got: %v", test.san, test.fen, err)
+		}
+
+		if m.UCI() != test.move {
+			t.Errorf("Expected %v to be written %v, but got: %v", test.san, test.move, m.UCI())
+		}
+
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		if b.FEN() != test.after {
+			t.Errorf("Expected %v after %v, but got: %v", test.after, test.san, b.FEN())
+		}
+	}
+
+	illegal := []struct {
+		fen  string
+		move string
+	}{
+		// d1 is attacked
+		{"3rk3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1", "f1b1"},
+		// the king would capture its own rook in standard chess
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1h1"},
+		// a bishop stands on the way of the rook
+		{"4k3/8/8/8/8/8/8/R1B1K2R w KQ - 0 1", "e1a1"},
+		// no right is left
+		{"4k3/8/8/8/8/8/8/1R3KR1 w - - 0 1", "f1g1"},
+	}
+
+	for _, test := range illegal {
+		b, _ := NewBoardFromFEN(test.fen)
+		b.Chess960 = b.Chess960 || test.move != "e1h1"
+
+		m, _ := NewMoveUCI(test.move, b.Turn)
+		if err := b.Move(m); err == nil {
+			t.Errorf("Expected %v to be illegal in %v", test.move, test.fen)
+		}
+	}
+
+	// X-FEN only gives the file of rooks that aren't the outermost ones
+	for fen, xfen := range map[string]string{
+		"4k3/8/8/8/8/8/8/R5KR w HA - 0 1":   "4k3/8/8/8/8/8/8/R5KR w KQ - 0 1",
+		"4k3/8/8/8/8/8/8/RR2K2R w HB - 0 1": "4k3/8/8/8/8/8/8/RR2K2R w KB - 0 1",
+	} {
+		if b, _ := NewBoardFromFEN(fen); b.FEN() != xfen {
+			t.Errorf("Expected %v in X-FEN, but got: %v", xfen, b.FEN())
+		}
+	}
+
+	// standard castling isn't written the Chess960 way
+	b := NewBoard()
+	b.Chess960 = true
+	for _, s := range []string{"e2e4", "e7e5", "g1f3", "g8f6", "f1c4", "f8c5"} {
+		m, _ := NewMoveUCI(s, b.Turn)
+		b.UnsafeMove(m)
+	}
+
+	if err := b.CheckMove(newMove("e1", "g1")); err == nil {
+		t.Errorf("Expected castling to be written as the king capturing its rook in Chess960")
+	}
+
+	if err := b.CheckMove(newMove("e1", "h1")); err != nil {
+		t.Errorf("Expected no errors, but got: %v", err)
+	}
+}
+
+func TestIsCapture(t *testing.T) {
+	tests := []struct {
+		fen     string
+		move    string
+		capture bool
+	}{
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", true},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4e5", false},
+		// en passant lands on an empty square
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", true},
+		// a Chess960 king castles onto its own rook
+		{"r3k2r/8/8/8/8/8/8/1R3KR1 w KQkq - 0 1", "f1g1", false},
+	}
+
+	for _, test := range tests {
+		b, err := NewBoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		m, _ := NewMoveUCI(test.move, b.Turn)
+		if b.IsCapture(m) != test.capture {
+			t.Errorf("Expected %v to be a capture in %v: %v", test.move, test.fen, test.capture)
+		}
+	}
+
+	if n := NewBoard().PieceCount(); n != 32 {
+		t.Errorf("Expected 32 pieces, but got: %v", n)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		variant      Variant
+		white, black []string
+		outcome      Outcome
+		moves        int
+	}{
+		{KingOfTheHill, []string{"e2e4", "e1e2", "e2d3", "d3d4"}, []string{"a7a6", "a6a5", "h7h6", "h6h5"}, WhiteWon, 7},
+		{ThreeCheck, []string{"e2e4", "f1b5", "b5c6", "d1h5", "h5f7"}, []string{"d7d5", "c7c6", "b8c6", "e7e6", "a7a6"},
+			WhiteWon, 9},
+	}
+
+	for _, test := range tests {
+		white := &scriptedPlayer{moves: test.white}
+		black := &scriptedPlayer{moves: test.black}
+
+		result := NewGameFromBoard(white, black, InfiniteTime{}, test.variant.NewBoard()).Start()
+		if result.Outcome != test.outcome || len(result.Moves) != test.moves {
+			t.Errorf("%v: expected %v after %v moves, but got: %v after %v moves (%v)", test.variant.Name(),
+				test.outcome, test.moves, result.Outcome, len(result.Moves), result.Reason)
+		}
+	}
+
+	// Three-Check counts the checks left to give in FEN, or the checks given at its end
+	b, err := NewBoardFromFEN("rnbqkbnr/ppp2ppp/8/3pp3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1+3 0 1")
+	if err != nil || b.Variant != ThreeCheck || b.Checks != [2]int{2, 0} {
+		t.Fatalf("Expected white to have given 2 checks, but got: %v, %+v", err, b)
+	}
+
+	if other, _ := NewBoardFromFEN("rnbqkbnr/ppp2ppp/8/3pp3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1 +2+0"); other.FEN() != b.FEN() {
+		t.Errorf("Expected %v, but got: %v", b.FEN(), other.FEN())
+	}
+
+	if err := b.Move(newMove("f1", "b5")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if o, _, over := b.VariantOutcome(); !over || o != WhiteWon || len(b.LegalMoves()) != 0 {
+		t.Errorf("Expected white to win by giving a third check")
+	}
+
+	if !strings.Contains(b.FEN(), " - 0+3 ") {
+		t.Errorf("Expected no checks left to give for white, but got: %v", b.FEN())
+	}
+
+	// a lone bishop may still give the third check, but bare kings can't
+	b, _ = NewVariantBoardFromFEN("4k3/8/8/8/8/8/8/4KB2 w - - 1+3 0 1", ThreeCheck)
+	if b.IsInsufficientMaterial() {
+		t.Errorf("Expected a bishop to be able to give the third check")
+	}
+
+	if err := b.Move(newMove("f1", "b5")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if o, _, over := b.VariantOutcome(); !over || o != WhiteWon {
+		t.Errorf("Expected white to win by giving a third check with the bishop")
+	}
+
+	if b, _ = NewVariantBoardFromFEN("4k3/8/8/8/8/8/8/4K3 w - - 1+3 0 1", ThreeCheck); !b.IsInsufficientMaterial() {
+		t.Errorf("Expected bare kings to be insufficient material")
+	}
+
+	for name, expected := range map[string]Variant{"kingofthehill": KingOfTheHill, "three check": ThreeCheck, "": nil} {
+		if v, err := VariantByName(name); err != nil || v != expected {
+			t.Errorf("Expected variant %v for %q, but got: %v, %v", expected, name, v, err)
+		}
+	}
+
+	if _, err := VariantByName("bughouse"); err == nil {
+		t.Errorf("Expected bughouse to be unsupported")
+	}
+}
+
+func TestCrazyhouse(t *testing.T) {
+	b := Crazyhouse.NewBoard()
+	for _, s := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3"} {
+		m, _ := NewMoveUCI(s, b.Turn)
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors playing %v, but got: %v", s, err)
+		}
+	}
+
+	expected := "rnb1kbnr/ppp1pppp/8/3q4/8/2N5/PPPP1PPP/R1BQKBNR[Pp] b KQkq - 1 1"
+	if b.FEN() != expected {
+		t.Fatalf("Expected %v, but got: %v", expected, b.FEN())
+	}
+
+	// the captured pawn is dropped to block the check
+	if err := b.Move(newMove("d5", "e5")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	drop, _ := NewMoveUCI("P@e2", b.Turn)
+	if err := b.Move(drop); err != nil || b.Pockets[WhitePawn] != 0 || b.PieceAt(drop.To) != WhitePawn {
+		t.Fatalf("Expected the pawn to be dropped, but got: %v\n%v", err, b)
+	}
+
+	if drop.UCI() != "P@e2" {
+		t.Errorf("Expected P@e2, but got: %v", drop.UCI())
+	}
+
+	illegal := []struct {
+		fen  string
+		move string
+	}{
+		{"4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", "N@e8"},  // onto a piece
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@a8"},  // pawn on the last rank
+		{"4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@a1"},  // pawn on the first rank
+		{"4k3/8/8/8/8/8/8/4K3[n] w - - 0 1", "N@a1"},  // not in the pocket
+		{"4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1", "N@h1"}, // leaves the king in check
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "Q@a1"},     // not playing Crazyhouse
+	}
+
+	for _, test := range illegal {
+		b, err := NewBoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("Expected no errors parsing %v, but got: %v", test.fen, err)
+		}
+
+		m, _ := NewMoveUCI(test.move, b.Turn)
+		if err := b.Move(m); err == nil {
+			t.Errorf("Expected %v to be illegal in %v", test.move, test.fen)
+		}
+	}
+
+	// promoted pieces go back to the pocket as pawns
+	b, _ = NewBoardFromFEN("1n2k3/P7/8/8/8/8/8/4K3[] w - - 0 1")
+	b.UnsafeMove(newMovePromotion("a7", "b8", WhiteQueen))
+	if b.FEN() != "1Q~2k3/8/8/8/8/8/8/4K3[N] b - - 0 1" {
+		t.Fatalf("Expected a promoted queen, but got: %v", b.FEN())
+	}
+
+	b, _ = NewBoardFromFEN(b.FEN())
+	b.UnsafeMove(newMove("e8", "d8"))
+	b.UnsafeMove(newMove("b8", "c8"))
+	b.UnsafeMove(newMove("d8", "c8"))
+	if b.Pockets[BlackPawn] != 1 || b.Pockets[BlackQueen] != 0 || b.Promoted != 0 {
+		t.Errorf("Expected the queen to be pocketed as a pawn, but got: %v", b.FEN())
+	}
+
+	// drops are legal moves, written in SAN with the piece dropped
+	b, _ = NewBoardFromFEN("4k3/8/8/8/8/8/8/4K3/Q w - - 0 1")
+	if n := len(b.LegalMoves()); n != 5+62 {
+		t.Errorf("Expected 67 legal moves, but got: %v", n)
+	}
+
+	if m, err := b.ParseSAN("Q@e7+"); err != nil || b.SAN(m) != "Q@e7+" {
+		t.Errorf("Expected Q@e7+, but got: %v, %v", m, err)
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	// the capture explodes the capturing pawn, the knight and the bishop, but not the pawn next to them
+	b, _ := NewVariantBoardFromFEN("4k3/8/2p1b3/3n4/4P3/8/8/4K3 w - - 0 1", Atomic)
+	if err := b.Move(newMove("e4", "d5")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if b.FEN() != "4k3/8/2p5/8/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("Expected an explosion, but got: %v", b.FEN())
+	}
+
+	illegal := []struct {
+		fen  string
+		move string
+	}{
+		{"4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", "e1e2"},  // kings can't capture
+		{"4k3/8/8/8/8/8/3p4/3RK3 w - - 0 1", "d1d2"}, // explodes own king
+	}
+
+	for _, test := range illegal {
+		b, _ := NewVariantBoardFromFEN(test.fen, Atomic)
+		m, _ := NewMoveUCI(test.move, b.Turn)
+		if err := b.Move(m); err == nil {
+			t.Errorf("Expected %v to be illegal in %v", test.move, test.fen)
+		}
+	}
+
+	// exploding the king wins, even in check
+	b, _ = NewVariantBoardFromFEN("3rk3/8/8/8/8/8/8/3RK2q w - - 0 1", Atomic)
+	if err := b.Move(newMove("d1", "d8")); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if o, _, over := b.VariantOutcome(); !over || o != WhiteWon {
+		t.Errorf("Expected white to win by exploding the king")
+	}
+
+	// kings next to each other are never in check
+	b, _ = NewVariantBoardFromFEN("8/8/8/8/8/4k3/r3K3/8 w - - 0 1", Atomic)
+	if b.InCheck(White) {
+		t.Errorf("Expected no check between connected kings")
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	// captures are compulsory
+	b := Antichess.NewBoard()
+	for _, s := range []string{"e2e3", "b7b5"} {
+		m, _ := NewMoveUCI(s, b.Turn)
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors playing %v, but got: %v", s, err)
+		}
+	}
+
+	if moves := b.LegalMoves(); len(moves) != 1 || moves[0].UCI() != "f1b5" {
+		t.Errorf("Expected Bxb5 to be the only legal move, but got: %v", moves)
+	}
+
+	// kings are common pieces, which pawns may promote to
+	if _, err := NewBoardFromFEN("8/8/8/8/8/8/1p6/2R5 b - - 0 1"); err == nil {
+		t.Errorf("Expected an error without kings in standard chess")
+	}
+
+	b, err := NewVariantBoardFromFEN("8/8/8/8/8/8/1p6/2R5 b - - 0 1", Antichess)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if n := len(b.LegalMoves()); n != 5 {
+		t.Errorf("Expected 5 promotions, but got: %v", n)
+	}
+
+	m, _ := NewMoveUCI("b2c1k", b.Turn)
+	if err := b.Move(m); err != nil || b.PieceAt(m.To) != BlackKing {
+		t.Fatalf("Expected a promotion to king, but got: %v\n%v", err, b)
+	}
+
+	if o, reason, over := b.VariantOutcome(); !over || o != WhiteWon {
+		t.Errorf("Expected white to win by losing all pieces, but got: %v", reason)
+	}
+
+	// having no legal move wins
+	b, _ = NewVariantBoardFromFEN("8/8/8/8/8/p7/P7/8 w - - 0 1", Antichess)
+	if o, reason, over := b.VariantOutcome(); !over || o != WhiteWon {
+		t.Errorf("Expected white to win by having no legal move, but got: %v", reason)
+	}
+}
+
+func TestHorde(t *testing.T) {
+	b := Horde.NewBoard()
+	if b.FEN() != hordeFEN {
+		t.Fatalf("Expected %v, but got: %v", hordeFEN, b.FEN())
+	}
+
+	if _, err := NewBoardFromFEN(hordeFEN); err == nil {
+		t.Errorf("Expected an error without a white king in standard chess")
+	}
+
+	// pawns on the first rank may advance two squares, and be captured en passent
+	b, _ = NewVariantBoardFromFEN("4k3/8/8/8/8/1p6/8/P7 w - - 0 1", Horde)
+	for _, s := range []string{"a1a3", "b3a2"} {
+		m, _ := NewMoveUCI(s, b.Turn)
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors playing %v, but got: %v", s, err)
+		}
+	}
+
+	if o, reason, over := b.VariantOutcome(); !over || o != BlackWon {
+		t.Errorf("Expected black to win by capturing the horde, but got: %v", reason)
+	}
+
+	// white wins by checkmate
+	b, _ = NewVariantBoardFromFEN("7k/6pp/8/8/8/8/P7/1R6 w - - 0 1", Horde)
+	if err := b.Move(newMove("b1", "b8")); err != nil || !b.IsCheckmate() {
+		t.Errorf("Expected checkmate, but got: %v\n%v", err, b)
+	}
+
+	// black may still capture a lone knight
+	b, _ = NewVariantBoardFromFEN("4k3/8/8/8/8/8/8/5N2 w - - 0 1", Horde)
+	if b.IsInsufficientMaterial() {
+		t.Errorf("Expected black to be able to win by capturing the knight")
+	}
+}
+
+func TestRacingKings(t *testing.T) {
+	b := RacingKings.NewBoard()
+	if n := len(b.LegalMoves()); n != 21 {
+		t.Errorf("Expected 21 legal moves, but got: %v", n)
+	}
+
+	// no move may give check
+	if err := b.Move(newMove("c2", "b3")); err == nil {
+		t.Errorf("Expected a move giving check to be illegal")
+	}
+
+	tests := []struct {
+		fen     string
+		outcome Outcome
+		over    bool
+	}{
+		{"K7/8/8/8/8/8/8/7k b - - 0 1", WhiteWon, true}, // black can't reach the 8th rank in time
+		{"K7/7k/8/8/8/8/8/8 b - - 0 1", 0, false},       // black may still draw
+		{"K6k/8/8/8/8/8/8/8 w - - 0 1", Draw, true},     // both kings reached the 8th rank
+		{"7k/8/8/8/8/8/8/K7 w - - 0 1", BlackWon, true}, // black reached the 8th rank first
+		{"8/8/8/8/8/8/8/K6k w - - 0 1", 0, false},       // kings alone race
+	}
+
+	for _, test := range tests {
+		b, _ := NewVariantBoardFromFEN(test.fen, RacingKings)
+		if o, reason, over := b.VariantOutcome(); over != test.over || over && o != test.outcome {
+			t.Errorf("Expected %v (%v) in %v, but got: %v (%v, %v)", test.outcome, test.over, test.fen, o, over, reason)
+		}
+
+		if b.IsInsufficientMaterial() {
+			t.Errorf("Expected kings alone to be able to race in %v", test.fen)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	b := NewBoard()
+	m := newMove("e2", "e4")
+	b.UnsafeMove(m)
+
+	expected := "8  r  n  b  q  k  b  n  r \n" +
+		"7  p  p  p  p  p  p  p  p \n" +
+		"6  .  .  .  .  .  .  .  . \n" +
+		"5  .  .  .  .  .  .  .  . \n" +
+		"4  .  .  .  . [P] .  .  . \n" +
+		"3  .  .  .  .  .  .  .  . \n" +
+		"2  P  P  P  P [.] P  P  P \n" +
+		"1  R  N  B  Q  K  B  N  R \n" +
+		"   a  b  c  d  e  f  g  h \n"
+	if s := b.Render(RenderOptions{Coordinates: true, LastMove: m}); s != expected {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", expected, s)
+	}
+
+	// black sees the board from its side
+	flipped := b.Render(RenderOptions{Unicode: true, Perspective: Black})
+	if lines := strings.Split(flipped, "\n"); lines[0] != " ♖  ♘  ♗  ♔  ♕  ♗  ♘  ♖ " || lines[7] != " ♜  ♞  ♝  ♚  ♛  ♝  ♞  ♜ " {
+		t.Errorf("Expected the board from the side of black, but got:\n%v", flipped)
+	}
+
+	// hinted squares are marked, with the piece on them if any
+	b = NewBoard()
+	e3, e4, e2 := newMove("e3", "e4").From, newMove("e3", "e4").To, newMove("e2", "e3").From
+	if lines := strings.Split(b.Render(RenderOptions{Hints: []Square{e3, e4, e2}}), "\n"); lines[4] != " .  .  .  .  *  .  .  . " ||
+		lines[6] != " P  P  P  P *P* P  P  P " {
+		t.Errorf("Expected hinted squares to be marked, but got:\n%v", strings.Join(lines, "\n"))
+	}
+
+	// the checked king stands out
+	b, _ = NewBoardFromFEN("4k3/8/8/8/8/8/8/4K2r w - - 0 1")
+	if s := b.Render(RenderOptions{Check: true}); !strings.Contains(s, "!K!") {
+		t.Errorf("Expected the king in check to be highlighted, but got:\n%v", s)
+	}
+
+	colored := b.Render(RenderOptions{Unicode: true, Color: true, Check: true})
+	if !strings.Contains(colored, ansiCheck+ansiWhitePieces+" ♚ "+ansiReset) || strings.Count(colored, "\n") != 8 {
+		t.Errorf("Expected colored squares, but got:\n%q", colored)
+	}
+}
+
+// negotiatingPlayer plays the given moves in order, making an offer on its turn before the move at index offerAt,
+// and resigns once it runs out of moves. It accepts the offers of its opponent iff accept is set.
+type negotiatingPlayer struct {
+	scriptedPlayer
+	gc      GameClient
+	offer   Offer
+	offerAt int
+	accept  bool
+
+	// offered, if set, is sent whether the offer was accepted
+	offered chan bool
+}
+
+func (np *negotiatingPlayer) Init(c Color, gc GameClient, prompt chan Prompt, move chan *Move) {
+	np.scriptedPlayer.Init(c, gc, prompt, move)
+	np.gc = gc
+}
+
+func (np *negotiatingPlayer) Run() {
+	i := 0
+	for range np.prompt {
+		if i == np.offerAt {
+			accepted := np.gc.(Negotiator).Offer(np.color, np.offer)
+			if np.offered != nil {
+				np.offered <- accepted
+			}
+
+			if accepted && np.offer == DrawOffer {
+				continue
+			}
+		}
+
+		if i == len(np.moves) {
+			np.gc.(Negotiator).Resign(np.color)
+			continue
+		}
+
+		m, _ := NewMoveUCI(np.moves[i], np.color)
+		i++
+		np.move <- m
+	}
+}
+
+func (np *negotiatingPlayer) AcceptOffer(o Offer) bool {
+	return np.accept
+}
+
+func TestOffers(t *testing.T) {
+	tests := []struct {
+		offer   Offer
+		accept  bool
+		outcome Outcome
+		reason  string
+		moves   string
+	}{
+		{DrawOffer, true, Draw, "draw by agreement", "e2e4 e7e5"},
+		{DrawOffer, false, BlackWon, "White resigned", "e2e4 e7e5 d2d4 d7d5"},
+		{UndoOffer, true, BlackWon, "White resigned", "d2d4 d7d5"},
+		{UndoOffer, false, BlackWon, "White resigned", "e2e4 e7e5 d2d4 d7d5"},
+	}
+
+	for i, test := range tests {
+		white := &negotiatingPlayer{scriptedPlayer: scriptedPlayer{moves: []string{"e2e4", "d2d4"}}, offer: test.offer, offerAt: 1}
+		black := &negotiatingPlayer{scriptedPlayer: scriptedPlayer{moves: []string{"e7e5", "d7d5", "c7c5"}}, offerAt: -1, accept: test.accept}
+
+		g := NewGame(white, black, InfiniteTime{})
+		result := g.Start()
+
+		var moves []string
+		for _, m := range result.Moves {
+			moves = append(moves, m.UCI())
+		}
+
+		if result.Outcome != test.outcome || result.Reason != test.reason || strings.Join(moves, " ") != test.moves {
+			t.Errorf("Test %v: expected %v (%v) after %v, but got: %v (%v) after %v", i, test.outcome, test.reason,
+				test.moves, result.Outcome, result.Reason, moves)
+		}
+	}
+
+	// a draw offered right after being checkmated is too late, even if accepted
+	offered := make(chan bool, 1)
+	white := &negotiatingPlayer{scriptedPlayer: scriptedPlayer{moves: []string{"f2f3", "g2g4"}}, offer: DrawOffer, offerAt: 2, offered: offered}
+	black := &negotiatingPlayer{scriptedPlayer: scriptedPlayer{moves: []string{"e7e5", "d8h4"}}, offerAt: -1, accept: true}
+	if result := NewGame(white, black, InfiniteTime{}).Start(); result.Outcome != BlackWon {
+		t.Errorf("Expected black to win by checkmate, but got: %v (%v)", result.Outcome, result.Reason)
+	}
+
+	if <-offered {
+		t.Errorf("Expected the draw offer to fail once the game is over")
+	}
+
+	// offers are made on the player's turn, and undone moves must have been played
+	g := NewGame(&scriptedPlayer{}, &negotiatingPlayer{accept: true}, InfiniteTime{})
+	if g.Offer(Black, DrawOffer) || g.Offer(White, UndoOffer) {
+		t.Errorf("Expected offers out of turn or without moves to undo to be refused")
+	}
+}
@@ -0,0 +1,253 @@
+package chess
+
+import "fmt"
+
+// castling rights, in the order of Board.castlingRooks
+const (
+	whiteKingside  = iota
+	whiteQueenside = iota
+	blackKingside  = iota
+	blackQueenside = iota
+
+	noCastling = -1
+)
+
+// cornerRooks are the squares of the rooks of each castling right in standard chess: h1, a1, h8 and a8
+var cornerRooks = [4]bitmap{1, 1 << 7, 1 << 56, 1 << 63}
+
+// castlingNames are the names of castling rights, as used in errors
+var castlingNames = [4]string{"white kingside", "white queenside", "black kingside", "black queenside"}
+
+// square returns the square of the given file and rank, both starting at 0
+func square(file, rank int) bitmap {
+	return 1 << uint(8*rank+7-file)
+}
+
+// castlingRook returns the square of the rook the given castling right castles with
+func (b *Board) castlingRook(right int) bitmap {
+	if b.castlingRooks[right] != 0 {
+		return b.castlingRooks[right]
+	}
+
+	return cornerRooks[right]
+}
+
+// castlingRight returns the flag of the given castling right
+func (b *Board) castlingRight(right int) *bool {
+	switch right {
+	case whiteKingside:
+		return &b.CanWhiteCastleKingside
+	case whiteQueenside:
+		return &b.CanWhiteCastleQueenside
+	case blackKingside:
+		return &b.CanBlackCastleKingside
+	default:
+		return &b.CanBlackCastleQueenside
+	}
+}
+
+// castlingSquares returns the squares the king and the rook end up on when castling with the given right: the g and f
+// files kingside, and the c and d files queenside, whatever files they start from
+func castlingSquares(right int) (king, rook bitmap) {
+	rank := 0
+	if right == blackKingside || right == blackQueenside {
+		rank = 7
+	}
+
+	if right == whiteKingside || right == blackKingside {
+		return square(6, rank), square(5, rank)
+	}
+
+	return square(2, rank), square(3, rank)
+}
+
+// castling returns the castling right a move of fromPiece from fromSquare onto toPiece on toSquare castles with, or
+// noCastling if it isn't castling. Castling is the king moving 2 squares from the e file in standard chess, and the
+// king capturing its own castling rook in Chess960.
+func (b *Board) castling(fromPiece, toPiece Piece, fromSquare, toSquare bitmap) int {
+	kingside, queenside, rook, start := whiteKingside, whiteQueenside, Piece(WhiteRook), square(4, 0)
+	switch fromPiece {
+	case WhiteKing:
+	case BlackKing:
+		kingside, queenside, rook, start = blackKingside, blackQueenside, BlackRook, square(4, 7)
+	default:
+		return noCastling
+	}
+
+	switch {
+	case b.Chess960 && toPiece == rook && toSquare == b.castlingRook(kingside):
+		return kingside
+	case b.Chess960 && toPiece == rook && toSquare == b.castlingRook(queenside):
+		return queenside
+	case b.Chess960 || fromSquare != start:
+		return noCastling
+	case fromSquare>>2 == toSquare:
+		return kingside
+	case fromSquare<<2 == toSquare:
+		return queenside
+	default:
+		return noCastling
+	}
+}
+
+// castle moves the king and rook of the given castling right to their squares after castling
+func (b *Board) castle(right int) {
+	king, rook := Piece(WhiteKing), Piece(WhiteRook)
+	if right == blackKingside || right == blackQueenside {
+		king, rook = BlackKing, BlackRook
+	}
+
+	kingTo, rookTo := castlingSquares(right)
+	b.Pieces[king] = kingTo // there is only one king
+	b.Pieces[rook] = b.Pieces[rook]&^b.castlingRook(right) | rookTo
+}
+
+// between returns the bitmap of all squares from s1 to s2 on a rank, both included
+func between(s1, s2 bitmap) bitmap {
+	if s1 > s2 {
+		s1, s2 = s2, s1
+	}
+
+	var squares bitmap
+	for s := s1; s != 0 && s <= s2; s <<= 1 {
+		squares |= s
+	}
+
+	return squares
+}
+
+// checkCastle checks that castling with the given right is allowed: the right is held, the rook is there, no other
+// pieces stand between the king or the rook and their destinations, and the king isn't in check, doesn't pass
+// through check, and doesn't end up in check
+func (b *Board) checkCastle(m *Move, right int) error {
+	if !*b.castlingRight(right) {
+		return fmt.Errorf("%v castling is not allowed", castlingNames[right])
+	}
+
+	rook := Piece(WhiteRook)
+	if right == blackKingside || right == blackQueenside {
+		rook = BlackRook
+	}
+
+	kingFrom, rookFrom := bitmap(m.From), b.castlingRook(right)
+	kingTo, rookTo := castlingSquares(right)
+	if b.Pieces[rook]&rookFrom == 0 {
+		return fmt.Errorf("can't castle without the rook it castles with")
+	}
+
+	others := b.allPieces() &^ (kingFrom | rookFrom)
+	if others&(between(kingFrom, kingTo)|between(rookFrom, rookTo)) != 0 {
+		return fmt.Errorf("can't castle through other pieces")
+	}
+
+	if b.InCheck(b.Turn) {
+		return fmt.Errorf("can't castle out of check")
+	}
+
+	if b.dynamicAttackMap(b.Turn.Other())&between(kingFrom, kingTo)&^kingTo != 0 {
+		return fmt.Errorf("can't castle through check")
+	}
+
+	copy := b.Copy()
+	copy.UnsafeMove(m)
+	if copy.InCheck(b.Turn) {
+		return fmt.Errorf("can't make a move that leaves king in check")
+	}
+
+	return nil
+}
+
+// castlingTargets returns the squares the king of the given color moves to when castling in Chess960, i.e. those of
+// the rooks it may still castle with
+func (b *Board) castlingTargets(c Color) bitmap {
+	rights := []int{whiteKingside, whiteQueenside}
+	if c == Black {
+		rights = []int{blackKingside, blackQueenside}
+	}
+
+	var targets bitmap
+	for _, right := range rights {
+		if *b.castlingRight(right) {
+			targets |= b.castlingRook(right)
+		}
+	}
+
+	return targets
+}
+
+// NewChess960Board returns the starting position of Chess960 of the given index, from 0 to 959, in the numbering of
+// Reinhard Scharnagl, where 518 is the standard starting position
+func NewChess960Board(index int) (*Board, error) {
+	if index < 0 || index >= 960 {
+		return nil, fmt.Errorf("Chess960 positions are numbered from 0 to 959, not: %v", index)
+	}
+
+	// the back rank of white, by file
+	var rank [8]Piece
+	for i := range rank {
+		rank[i] = EmptyPiece
+	}
+
+	// place puts p on the n-th empty square of the back rank, starting at 0
+	place := func(n int, p Piece) {
+		for file := range rank {
+			if rank[file] != EmptyPiece {
+				continue
+			}
+
+			if n == 0 {
+				rank[file] = p
+				return
+			}
+			n--
+		}
+	}
+
+	n := index
+	rank[2*(n%4)+1] = WhiteBishop // light squares: b, d, f or h
+	n /= 4
+	rank[2*(n%4)] = WhiteBishop // dark squares: a, c, e or g
+	n /= 4
+	place(n%6, WhiteQueen)
+	n /= 6
+
+	// the 10 ways of placing 2 knights on the 5 empty squares left, as in the numbering
+	knights := [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+	// placing the first knight leaves one empty square less before the second one
+	place(knights[n][0], WhiteKnight)
+	place(knights[n][1]-1, WhiteKnight)
+
+	// the king stands between the rooks on the 3 squares left
+	place(0, WhiteRook)
+	place(0, WhiteKing)
+	place(0, WhiteRook)
+
+	b := &Board{
+		CanWhiteCastleKingside:  true,
+		CanWhiteCastleQueenside: true,
+		CanBlackCastleKingside:  true,
+		CanBlackCastleQueenside: true,
+		Chess960:                true,
+		Turn:                    White,
+	}
+
+	for file, p := range rank {
+		b.Pieces[p] |= square(file, 0)
+		b.Pieces[p+BlackKing] |= square(file, 7)
+		b.Pieces[WhitePawn] |= square(file, 1)
+		b.Pieces[BlackPawn] |= square(file, 6)
+
+		if p != WhiteRook {
+			continue
+		}
+
+		// the rook on the a side castles queenside
+		if b.castlingRooks[whiteQueenside] == 0 {
+			b.castlingRooks[whiteQueenside], b.castlingRooks[blackQueenside] = square(file, 0), square(file, 7)
+		} else {
+			b.castlingRooks[whiteKingside], b.castlingRooks[blackKingside] = square(file, 0), square(file, 7)
+		}
+	}
+
+	return b, nil
+}
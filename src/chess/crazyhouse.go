@@ -0,0 +1,160 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Crazyhouse is the variant where captured pieces change sides: they go to the pocket of the player who captured them,
+// who may later drop them on any empty square instead of moving. Promoted pieces go back to the pocket as pawns.
+var Crazyhouse Variant = crazyhouse{}
+
+type crazyhouse struct{}
+
+func (crazyhouse) Name() string {
+	return "Crazyhouse"
+}
+
+func (v crazyhouse) NewBoard() *Board {
+	b := NewBoard()
+	b.Variant = v
+	return b
+}
+
+func (crazyhouse) CheckMove(b *Board, m *Move) error {
+	return nil
+}
+
+func (crazyhouse) Moved(b *Board, m *Move) {}
+
+func (crazyhouse) Outcome(b *Board) (Outcome, string, bool) {
+	return 0, "", false
+}
+
+// pocketPieces are the white pieces that may be dropped, in the order they are written in FEN
+var pocketPieces = [5]Piece{WhiteQueen, WhiteRook, WhiteBishop, WhiteKnight, WhitePawn}
+
+// hasPockets returns true iff the variant of this board puts captured pieces in pockets
+func (b *Board) hasPockets() bool {
+	return b.Variant == Crazyhouse
+}
+
+// ofColor returns the piece of the same type as p, of color c
+func (p Piece) ofColor(c Color) Piece {
+	if p >= BlackKing {
+		p -= BlackKing
+	}
+
+	if c == Black {
+		return p + BlackKing
+	}
+
+	return p
+}
+
+// pocket puts the piece captured on the given square in the pocket of the player to move, as a pawn if it promoted
+func (b *Board) pocket(captured Piece, capturedSquare bitmap) {
+	if b.Promoted&capturedSquare != 0 {
+		captured = WhitePawn
+		b.Promoted &^= capturedSquare
+	}
+
+	b.Pockets[captured.ofColor(b.Turn)]++
+}
+
+// drop performs a drop on this board without any validity checking
+func (b *Board) drop(m *Move) {
+	b.Pieces[m.Drop] |= bitmap(m.To)
+	b.Pockets[m.Drop]--
+
+	b.EnPassent = 0
+	b.HalfMoveClock++
+}
+
+// checkDrop checks if a drop is valid on this board. Returns an error if it is invalid.
+func (b *Board) checkDrop(m *Move) error {
+	if !b.hasPockets() {
+		return fmt.Errorf("pieces can only be dropped in variants with pockets")
+	}
+
+	if m.Drop >= EmptyPiece || m.Drop.Color() != b.Turn {
+		return fmt.Errorf("cannot drop piece of different color of turn")
+	}
+
+	if b.Pockets[m.Drop] == 0 {
+		return fmt.Errorf("there is no %v in the pocket to drop", m.Drop)
+	}
+
+	if m.Promotion != EmptyPiece {
+		return fmt.Errorf("dropped pieces can't promote")
+	}
+
+	if b.allPieces()&bitmap(m.To) != 0 {
+		return fmt.Errorf("pieces can only be dropped on empty squares")
+	}
+
+	if (m.Drop == WhitePawn || m.Drop == BlackPawn) && bitmap(m.To)&lastRanks != 0 {
+		return fmt.Errorf("pawns can't be dropped on the 1st or 8th rank")
+	}
+
+	copy := b.Copy()
+	copy.UnsafeMove(m)
+	if copy.InCheck(b.Turn) {
+		return fmt.Errorf("can't make a move that leaves king in check")
+	}
+
+	return b.checkVariantMove(m)
+}
+
+// forEachLegalDrop calls f on each legal drop of the player whose turn it is, stopping early if f returns false
+func (b *Board) forEachLegalDrop(f func(m *Move) bool) {
+	if !b.hasPockets() {
+		return
+	}
+
+	for _, p := range pocketPieces {
+		p = p.ofColor(b.Turn)
+		if b.Pockets[p] == 0 {
+			continue
+		}
+
+		for toSquare := bitmap(1); toSquare != 0; toSquare <<= 1 {
+			m := NewDrop(p, Square(toSquare))
+			if b.checkDrop(m) != nil {
+				continue
+			}
+
+			if !f(m) {
+				return
+			}
+		}
+	}
+}
+
+// parsePocket sets the pockets of this board from their FEN, e.g. QPp, where white pieces are upper case
+func (b *Board) parsePocket(pocket string) error {
+	for i := 0; i < len(pocket); i++ {
+		p, ok := fenPieces[pocket[i]]
+		if !ok || p == WhiteKing || p == BlackKing {
+			return fmt.Errorf("invalid piece in pocket: %v", string(pocket[i]))
+		}
+
+		b.Pockets[p]++
+	}
+
+	b.Variant = Crazyhouse
+	return nil
+}
+
+// pocketFEN returns the FEN of the pockets of this board, white pieces first
+func (b *Board) pocketFEN() string {
+	var sb strings.Builder
+	for _, c := range []Color{White, Black} {
+		for _, p := range pocketPieces {
+			p = p.ofColor(c)
+			sb.WriteString(strings.Repeat(p.String(), b.Pockets[p]))
+		}
+	}
+
+	return sb.String()
+}
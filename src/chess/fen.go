@@ -0,0 +1,349 @@
+package chess
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the FEN of the starting position
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// fenPieces maps the letters used by FEN to pieces
+var fenPieces = map[byte]Piece{
+	'K': WhiteKing,
+	'Q': WhiteQueen,
+	'N': WhiteKnight,
+	'B': WhiteBishop,
+	'R': WhiteRook,
+	'P': WhitePawn,
+	'k': BlackKing,
+	'q': BlackQueen,
+	'n': BlackKnight,
+	'b': BlackBishop,
+	'r': BlackRook,
+	'p': BlackPawn,
+}
+
+// NewBoardFromFEN creates a board from its Forsyth-Edwards Notation. The fullmove number is optional and ignored, as
+// is the halfmove clock if missing. Castling rights of Chess960 may be given in X-FEN, where K and Q castle with the
+// outermost rook on their side of the king, or in Shredder-FEN, with the file of the rook; the board plays Chess960 if
+// the king or a castling rook isn't where it starts in standard chess. The board plays Three-Check if the FEN counts
+// checks, either as the checks left to give by each player after the en-passent square, e.g. 3+2, or as the checks
+// given at the end, e.g. +0+1. The board plays Crazyhouse if the FEN gives pockets, in brackets after the ranks, e.g.
+// [Qp], or as a 9th rank.
+func NewBoardFromFEN(fen string) (*Board, error) {
+	return NewVariantBoardFromFEN(fen, nil)
+}
+
+// NewVariantBoardFromFEN creates a board playing variant v from its Forsyth-Edwards Notation, as NewBoardFromFEN does.
+// The variant is the one the FEN implies if v is nil. Players may have any number of kings in variants where they are
+// common pieces, and none in variants without kings.
+func NewVariantBoardFromFEN(fen string, v Variant) (*Board, error) {
+	b := &Board{}
+
+	// the check counters of Three-Check, which aren't standard FEN fields
+	var fields []string
+	for _, field := range strings.Fields(fen) {
+		if !strings.Contains(field, "+") {
+			fields = append(fields, field)
+			continue
+		}
+
+		if err := b.parseChecks(field); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fields) < 4 || len(fields) > 6 {
+		return nil, fmt.Errorf("FEN must have 4 to 6 fields, has: %v", len(fields))
+	}
+
+	// the pockets of Crazyhouse, written after the ranks in brackets, or as a 9th rank
+	placement := fields[0]
+	if i := strings.IndexByte(placement, '['); i >= 0 && strings.HasSuffix(placement, "]") {
+		if err := b.parsePocket(placement[i+1 : len(placement)-1]); err != nil {
+			return nil, err
+		}
+		placement = placement[:i]
+	}
+
+	ranks := strings.Split(placement, "/")
+	if len(ranks) == 9 {
+		if err := b.parsePocket(ranks[8]); err != nil {
+			return nil, err
+		}
+		ranks = ranks[:8]
+	}
+
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("FEN must have 8 ranks, has: %v", len(ranks))
+	}
+
+	for i, rank := range ranks {
+		y := 7 - i
+		x := 0
+		for j := 0; j < len(rank); j++ {
+			c := rank[j]
+			if c >= '1' && c <= '8' {
+				x += int(c - '0')
+				continue
+			}
+
+			// promoted pieces of Crazyhouse are marked by a tilde
+			if c == '~' && x > 0 {
+				b.Promoted |= 1 << uint(8*y+7-(x-1))
+				b.Variant = Crazyhouse
+				continue
+			}
+
+			p, ok := fenPieces[c]
+			if !ok {
+				return nil, fmt.Errorf("invalid piece: %v", string(c))
+			}
+
+			if x > 7 {
+				return nil, fmt.Errorf("too many squares on rank %v", y+1)
+			}
+
+			b.Pieces[p] |= 1 << uint(8*y+7-x)
+			x++
+		}
+
+		if x != 8 {
+			return nil, fmt.Errorf("rank %v must have 8 squares, has: %v", y+1, x)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		b.Turn = White
+	case "b":
+		b.Turn = Black
+	default:
+		return nil, fmt.Errorf("player to move must be one of {w,b}, is: %v", fields[1])
+	}
+
+	if fields[2] != "-" {
+		if err := b.parseCastling(fields[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	if fields[3] != "-" {
+		s, err := Coordinate(fields[3]).Square()
+		if err != nil {
+			return nil, fmt.Errorf("invalid en-passent square: %v", err)
+		}
+
+		b.EnPassent = bitmap(s)
+	}
+
+	if len(fields) > 4 {
+		clock, err := strconv.Atoi(fields[4])
+		if err != nil || clock < 0 {
+			return nil, fmt.Errorf("invalid halfmove clock: %v", fields[4])
+		}
+
+		b.HalfMoveClock = clock
+	}
+
+	if v != nil {
+		b.Variant = v
+	}
+
+	for _, c := range []Color{White, Black} {
+		if b.hasKing(c) && bits.OnesCount64(uint64(b.Pieces[Piece(WhiteKing).ofColor(c)])) != 1 {
+			return nil, fmt.Errorf("each player must have exactly one king")
+		}
+	}
+
+	return b, nil
+}
+
+// FEN returns the Forsyth-Edwards Notation of this board, in X-FEN for Chess960, with the checks left to give by each
+// player for Three-Check, and with the pockets in brackets and promoted pieces marked by a tilde for Crazyhouse. The
+// board doesn't count moves, so the fullmove number is always 1.
+func (b *Board) FEN() string {
+	var sb strings.Builder
+
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := 0; x < 8; x++ {
+			p := b.PieceAt(1 << uint(8*y+7-x))
+			if p == EmptyPiece {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+
+			sb.WriteString(p.String())
+			if b.Promoted&(1<<uint(8*y+7-x)) != 0 {
+				sb.WriteByte('~')
+			}
+		}
+
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+
+		if y > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if b.hasPockets() {
+		sb.WriteString("[" + b.pocketFEN() + "]")
+	}
+
+	if b.Turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	sb.WriteString(b.castlingFEN())
+
+	if b.EnPassent != 0 {
+		c, _ := Square(b.EnPassent).toCoord()
+		sb.WriteString(" " + string(c))
+	} else {
+		sb.WriteString(" -")
+	}
+
+	if b.Variant == ThreeCheck {
+		sb.WriteString(fmt.Sprintf(" %d+%d", checksToWin-b.Checks[White], checksToWin-b.Checks[Black]))
+	}
+
+	sb.WriteString(fmt.Sprintf(" %d 1", b.HalfMoveClock))
+	return sb.String()
+}
+
+// castlingLetters are the letters of each castling right in FEN
+const castlingLetters = "KQkq"
+
+// outermostRook returns the square of the rook farthest from the king on the side of the given castling right, on
+// the back rank, or 0 if there is none
+func (b *Board) outermostRook(right int) bitmap {
+	king, rook, rank := b.Pieces[WhiteKing], b.Pieces[WhiteRook], 0
+	if right == blackKingside || right == blackQueenside {
+		king, rook, rank = b.Pieces[BlackKing], b.Pieces[BlackRook], 7
+	}
+
+	for i := 0; i < 8; i++ {
+		// from the h file kingside, from the a file queenside
+		file := i
+		if right == whiteKingside || right == blackKingside {
+			file = 7 - i
+		}
+
+		s := square(file, rank)
+		switch {
+		case s&king != 0:
+			return 0
+		case s&rook != 0:
+			return s
+		}
+	}
+
+	return 0
+}
+
+// parseCastling sets the castling rights given in FEN, X-FEN or Shredder-FEN
+func (b *Board) parseCastling(castling string) error {
+	for _, c := range castling {
+		var right int
+		var rook bitmap
+		switch {
+		case strings.ContainsRune(castlingLetters, c):
+			right = strings.IndexRune(castlingLetters, c)
+			rook = b.outermostRook(right)
+		case c >= 'A' && c <= 'H' || c >= 'a' && c <= 'h':
+			// Shredder-FEN: the file of the rook, upper case for white
+			king, rank, file := b.Pieces[WhiteKing], 0, int(c-'A')
+			right = whiteKingside
+			if c >= 'a' {
+				king, rank, file, right = b.Pieces[BlackKing], 7, int(c-'a'), blackKingside
+			}
+
+			rook = square(file, rank)
+			if rook > king {
+				right++ // queenside
+			}
+		default:
+			return fmt.Errorf("invalid castling right: %v", string(c))
+		}
+
+		*b.castlingRight(right) = true
+		if rook != 0 && rook != cornerRooks[right] {
+			b.castlingRooks[right] = rook
+		}
+	}
+
+	for right, king := range []bitmap{b.Pieces[WhiteKing], b.Pieces[WhiteKing], b.Pieces[BlackKing], b.Pieces[BlackKing]} {
+		if *b.castlingRight(right) && (b.castlingRook(right) != cornerRooks[right] || king&(square(4, 0)|square(4, 7)) == 0) {
+			b.Chess960 = true
+		}
+	}
+
+	return nil
+}
+
+// castlingFEN returns the castling rights in FEN, or in X-FEN for Chess960
+func (b *Board) castlingFEN() string {
+	castling := ""
+	for right := range b.castlingRooks {
+		if !*b.castlingRight(right) {
+			continue
+		}
+
+		rook := b.castlingRook(right)
+		if !b.Chess960 || rook == b.outermostRook(right) {
+			castling += castlingLetters[right : right+1]
+			continue
+		}
+
+		c, _ := Square(rook).toCoord()
+		if right == whiteKingside || right == whiteQueenside {
+			castling += strings.ToUpper(string(c[0]))
+		} else {
+			castling += string(c[0])
+		}
+	}
+
+	if castling == "" {
+		return "-"
+	}
+
+	return castling
+}
+
+// parseChecks sets the check counters of Three-Check, given as the checks left to give, e.g. 3+2, or as the checks
+// given, e.g. +0+1
+func (b *Board) parseChecks(field string) error {
+	given := strings.HasPrefix(field, "+")
+	counts := strings.Split(strings.TrimPrefix(field, "+"), "+")
+	if len(counts) != 2 {
+		return fmt.Errorf("invalid check counters: %v", field)
+	}
+
+	for c, count := range counts {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 || n > checksToWin {
+			return fmt.Errorf("invalid check counters: %v", field)
+		}
+
+		if !given {
+			n = checksToWin - n
+		}
+		b.Checks[c] = n
+	}
+
+	b.Variant = ThreeCheck
+	return nil
+}
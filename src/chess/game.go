@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 )
 
+// Player plays one side of a Game. A Player is prompted for each of its moves, and replies on its move channel; a
+// nil move abandons the game.
 type Player interface {
 	Init(c Color, g GameClient, prompt chan Prompt, move chan *Move)
 	Run()
@@ -16,9 +19,44 @@ type Prompt struct {
 	OppMove *Move
 }
 
+// Outcome is the final outcome of a Game
+type Outcome uint8
+
+// Outcome representations
+const (
+	WhiteWon = iota
+	BlackWon = iota
+	Draw     = iota
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case WhiteWon:
+		return "1-0"
+	case BlackWon:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		panic("Unhandled outcome type")
+	}
+}
+
+// GameResult describes how a Game ended
+type GameResult struct {
+	Outcome Outcome
+	Reason  string
+
+	// Moves is the list of all moves played in the game, in order
+	Moves []*Move
+}
+
 type Game struct {
 	board *Board
 
+	// start is the board the game started from, replayed when moves are taken back
+	start *Board
+
 	timeControl TimeControl
 
 	blackTimeLeft time.Duration
@@ -34,6 +72,33 @@ type Game struct {
 
 	moveWhite chan *Move
 	moveBlack chan *Move
+
+	moves []*Move
+
+	// number of times each position has occurred, for detecting 3-fold repetition
+	positions map[positionKey]int
+
+	// scores reported by each player for its last consecutive moves, from white's point of view
+	scores [2][]int
+
+	// guards the board and clocks, which players may read at any time
+	mu sync.Mutex
+
+	// interrupt takes the requests to end the game other than by a move, e.g. by resignation, while waiting for a
+	// move, and ended is the result the last one led to; both are only used by the game loop
+	interrupt chan ending
+	ended     *GameResult
+
+	// undos takes the requests to take back the last move of each player, closing the channel of each once done, and
+	// over is closed once the game is over
+	undos chan chan struct{}
+	over  chan struct{}
+
+	// OnMove, if set, is called with every move played, right after it is played
+	OnMove func(c Color, m *Move)
+
+	// Adjudication may end the game early, checked after every move. New games get DefaultAdjudication.
+	Adjudication Adjudication
 }
 
 type GameClient interface {
@@ -43,8 +108,14 @@ type GameClient interface {
 }
 
 func NewGame(white, black Player, tc TimeControl) *Game {
+	return NewGameFromBoard(white, black, tc, NewBoard())
+}
+
+// NewGameFromBoard returns a Game starting from the position on b, instead of the starting position
+func NewGameFromBoard(white, black Player, tc TimeControl, b *Board) *Game {
 	return &Game{
-		board: NewBoard(),
+		board: b.Copy(),
+		start: b.Copy(),
 
 		timeControl: tc,
 
@@ -61,6 +132,14 @@ func NewGame(white, black Player, tc TimeControl) *Game {
 
 		moveWhite: make(chan *Move, 1),
 		moveBlack: make(chan *Move, 1),
+
+		interrupt: make(chan ending),
+		undos:     make(chan chan struct{}),
+		over:      make(chan struct{}),
+
+		positions: make(map[positionKey]int),
+
+		Adjudication: DefaultAdjudication(),
 	}
 }
 
@@ -69,10 +148,16 @@ func (g *Game) GetTimeControl() TimeControl {
 }
 
 func (g *Game) GetBoard() *Board {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	return g.board.Copy()
 }
 
 func (g *Game) GetTimeLeft(c Color) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	switch {
 	case c == White && g.board.Turn == Black:
 		return g.whiteTimeLeft
@@ -93,100 +178,177 @@ func (g *Game) handleMove(c Color) error {
 		ctx, cancel := context.WithTimeout(context.Background(), g.whiteTimeLeft)
 		defer cancel()
 
-		select {
-		case m := <-g.moveWhite:
-			tmp := *m
-
-			err := g.board.Move(&tmp)
-			if err != nil {
-				return fmt.Errorf("white made an invalid move: %v", err)
+		for {
+			select {
+			case m := <-g.moveWhite:
+				if m == nil {
+					return fmt.Errorf("white abandoned the game")
+				}
+
+				tmp := *m
+
+				g.mu.Lock()
+				err := g.board.Move(&tmp)
+				if err != nil {
+					g.mu.Unlock()
+					return fmt.Errorf("white made an invalid move: %v", err)
+				}
+
+				g.whiteTimeLeft -= time.Since(g.timestamp)
+				g.whiteTimeLeft += g.timeControl.Increment()
+
+				g.moves = append(g.moves, &tmp)
+				g.positions[g.board.positionKey()]++
+
+				g.timestamp = time.Now()
+				g.mu.Unlock()
+
+				g.promptBlack <- Prompt{&tmp}
+				return nil
+			case done := <-g.undos:
+				g.undo(2)
+				close(done)
+			case e := <-g.interrupt:
+				return g.interrupted(e)
+			case <-ctx.Done():
+				return fmt.Errorf("white ran out of time")
 			}
-
-			g.whiteTimeLeft -= time.Since(g.timestamp)
-			g.whiteTimeLeft += g.timeControl.Increment()
-
-			g.promptBlack <- Prompt{&tmp}
-			g.timestamp = time.Now()
-			return nil
-		case <-ctx.Done():
-			return fmt.Errorf("white ran out of time")
 		}
 
 	case Black:
 		ctx, cancel := context.WithTimeout(context.Background(), g.blackTimeLeft)
 		defer cancel()
 
-		select {
-		case m := <-g.moveBlack:
-			tmp := *m
-
-			err := g.board.Move(&tmp)
-			if err != nil {
-				return fmt.Errorf("black made an invalid move: %v", err)
+		for {
+			select {
+			case m := <-g.moveBlack:
+				if m == nil {
+					return fmt.Errorf("black abandoned the game")
+				}
+
+				tmp := *m
+
+				g.mu.Lock()
+				err := g.board.Move(&tmp)
+				if err != nil {
+					g.mu.Unlock()
+					return fmt.Errorf("black made an invalid move: %v", err)
+				}
+
+				g.blackTimeLeft -= time.Since(g.timestamp)
+				g.blackTimeLeft += g.timeControl.Increment()
+
+				g.moves = append(g.moves, &tmp)
+				g.positions[g.board.positionKey()]++
+
+				g.timestamp = time.Now()
+				g.mu.Unlock()
+
+				g.promptWhite <- Prompt{&tmp}
+				return nil
+			case done := <-g.undos:
+				g.undo(2)
+				close(done)
+			case e := <-g.interrupt:
+				return g.interrupted(e)
+			case <-ctx.Done():
+				return fmt.Errorf("black ran out of time")
 			}
-
-			g.blackTimeLeft -= time.Since(g.timestamp)
-			g.blackTimeLeft += g.timeControl.Increment()
-
-			g.promptWhite <- Prompt{&tmp}
-			g.timestamp = time.Now()
-			return nil
-		case <-ctx.Done():
-			return fmt.Errorf("black ran out of time")
 		}
 	default:
 		panic("Unhandled color type")
 	}
 }
 
-func (g *Game) Start() {
+// Start plays the game to completion, and returns its result. Players are notified that the game is over by
+// closing their prompt channels.
+func (g *Game) Start() *GameResult {
 	wp := g.whitePlayer
 	bp := g.blackPlayer
 
 	wp.Init(White, g, g.promptWhite, g.moveWhite)
 	bp.Init(Black, g, g.promptBlack, g.moveBlack)
 
+	g.mu.Lock()
+	g.timestamp = time.Now()
+	g.mu.Unlock()
+
 	go wp.Run()
 	go bp.Run()
 
-	g.promptWhite <- Prompt{}
-	g.timestamp = time.Now()
+	g.positions[g.board.positionKey()]++
+	if g.board.Turn == White {
+		g.promptWhite <- Prompt{}
+	} else {
+		g.promptBlack <- Prompt{}
+	}
 
-	var err error
-
-game:
-	for {
-		// white's turn
-		err = g.handleMove(White)
-
-		switch {
-		case err != nil:
-			log.Printf("White lost: %v", err)
-			break game
-		case g.board.IsCheckmate():
-			log.Printf("White won via checkmate")
-			break game
-		case g.board.IsStalemate():
-			log.Printf("White drew via stalemate")
-		}
+	var result *GameResult
+	for c := g.board.Turn; result == nil; c = c.Other() {
+		result = g.playTurn(c)
+	}
+
+	close(g.promptWhite)
+	close(g.promptBlack)
+	close(g.over)
+
+	log.Printf("Game over (%v): %v", result.Outcome, result.Reason)
+	return result
+}
+
+// playTurn waits for the player of the given color to move, and returns the result of the game if it ended.
+func (g *Game) playTurn(c Color) *GameResult {
+	winner, loser := Outcome(WhiteWon), Outcome(BlackWon)
+	if c == Black {
+		winner, loser = BlackWon, WhiteWon
+	}
 
-		// black's turn
-		err = g.handleMove(Black)
-
-		switch {
-		case err != nil:
-			log.Printf("Black lost: %v", err)
-			break game
-		case g.board.IsCheckmate():
-			log.Printf("Black won via checkmate")
-			break game
-		case g.board.IsStalemate():
-			log.Printf("Black drew via stalemate")
-			break game
+	player := g.whitePlayer
+	if c == Black {
+		player = g.blackPlayer
+	}
+
+	err := g.handleMove(c)
+	if err == errEnded {
+		return g.ended
+	}
+
+	if err == nil {
+		g.recordScore(c, player)
+		if g.OnMove != nil {
+			g.OnMove(c, g.moves[len(g.moves)-1])
 		}
+	}
+
+	if err != nil {
+		return g.result(loser, fmt.Sprintf("%v lost: %v", c, err))
+	}
+
+	// the variant ends the game first, e.g. a third check in Three-Check that is also checkmate
+	if o, reason, over := g.board.VariantOutcome(); over {
+		return g.result(o, reason)
+	}
+
+	switch {
+	case g.board.IsCheckmate():
+		return g.result(winner, fmt.Sprintf("%v won via checkmate", c))
+	case g.board.IsStalemate():
+		return g.result(Draw, fmt.Sprintf("%v drew via stalemate", c))
+	case g.board.IsInsufficientMaterial():
+		return g.result(Draw, "draw by insufficient material")
+	case g.positions[g.board.positionKey()] >= 3:
+		return g.result(Draw, "draw by 3-fold repetition")
+	case g.board.HalfMoveClock >= 100:
+		return g.result(Draw, "draw by 50-move rule")
+	}
+
+	return g.adjudicate()
+}
 
-		// TODO cap the number of moves in a game to 200
-		// TODO handle 3-fold repetition
-		// TODO handle 50-move rule
+func (g *Game) result(o Outcome, reason string) *GameResult {
+	return &GameResult{
+		Outcome: o,
+		Reason:  reason,
+		Moves:   g.moves,
 	}
 }
@@ -0,0 +1,50 @@
+package chess
+
+import "fmt"
+
+// hordeFEN is the starting position of Horde
+const hordeFEN = "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+
+// Horde is the variant where white plays a horde of 36 pawns, and no king, against the pieces of black. White wins by
+// checkmate, and black by capturing all white pieces. White pawns on the first rank may advance two squares.
+var Horde Variant = horde{}
+
+type horde struct{}
+
+func (horde) Name() string {
+	return "Horde"
+}
+
+func (v horde) NewBoard() *Board {
+	b, err := NewVariantBoardFromFEN(hordeFEN, v)
+	if err != nil {
+		panic(fmt.Sprintf("invalid starting position of Horde: %v", err))
+	}
+
+	return b
+}
+
+func (horde) CheckMove(b *Board, m *Move) error {
+	return nil
+}
+
+func (horde) Moved(b *Board, m *Move) {}
+
+// insufficientMaterial returns false, as black may still win by capturing the horde while white has pieces left
+func (horde) insufficientMaterial(b *Board) bool {
+	return false
+}
+
+func (horde) Outcome(b *Board) (Outcome, string, bool) {
+	for _, p := range WhitePieceTypes {
+		if b.Pieces[p] != 0 {
+			return 0, "", false
+		}
+	}
+
+	return BlackWon, "Black won by capturing the horde", true
+}
+
+func (horde) kingless(c Color) bool {
+	return c == White
+}
@@ -3,6 +3,7 @@ package chess
 import (
 	"fmt"
 	"math"
+	"strings"
 )
 
 var alphabet = "abcdefgh"
@@ -39,8 +40,8 @@ func (s Square) toCoord() (Coordinate, error) {
 // Coordinate is a human-readable representation of a Chess square
 type Coordinate string
 
-// toSquare translates this Coordinate into a Square representation
-func (c Coordinate) toSquare() (Square, error) {
+// Square translates this Coordinate into a Square representation. Returns an error if it is not a valid coordinate.
+func (c Coordinate) Square() (Square, error) {
 	if len(c) != 2 {
 		return 0, fmt.Errorf("coordinate must be a length-2 string: %v", c)
 	}
@@ -61,11 +62,13 @@ func (c Coordinate) toSquare() (Square, error) {
 	return s << (8*y + (7 - x)), nil
 }
 
-// Move is a representation of a Chess move
+// Move is a representation of a Chess move. A move without a From square drops the Drop piece from the pocket of
+// the player to move onto the To square, in variants such as Crazyhouse; other moves have EmptyPiece as Drop.
 type Move struct {
 	From      Square
 	To        Square
 	Promotion Piece
+	Drop      Piece
 }
 
 // NewMove returns a new Move from square f to square t
@@ -74,9 +77,24 @@ func NewMove(f, t Square, p Piece) *Move {
 		From:      f,
 		To:        t,
 		Promotion: p,
+		Drop:      EmptyPiece,
 	}
 }
 
+// NewDrop returns a new Move dropping piece p onto square t
+func NewDrop(p Piece, t Square) *Move {
+	return &Move{
+		To:        t,
+		Promotion: EmptyPiece,
+		Drop:      p,
+	}
+}
+
+// IsDrop returns true iff this move drops a piece instead of moving one
+func (m *Move) IsDrop() bool {
+	return m.From == 0
+}
+
 // NewMoveCoord returns a new Move from coordinate f to coordinate t. Returns an
 // error if Coordinate could not be parsed correctly.
 func NewMoveCoord(f, t Coordinate) (*Move, error) {
@@ -90,11 +108,11 @@ func NewMoveCoordPromotion(f, t Coordinate, p Piece) (*Move, error) {
 	var to Square
 	var err error
 
-	if from, err = f.toSquare(); err != nil {
+	if from, err = f.Square(); err != nil {
 		return nil, fmt.Errorf("could not parse from coordinate: %v", err)
 	}
 
-	if to, err = t.toSquare(); err != nil {
+	if to, err = t.Square(); err != nil {
 		return nil, fmt.Errorf("could not parse to coordinate: %v", err)
 	}
 
@@ -102,8 +120,12 @@ func NewMoveCoordPromotion(f, t Coordinate, p Piece) (*Move, error) {
 }
 
 func (m *Move) String() string {
-	src, _ := m.From.toCoord()
 	dst, _ := m.To.toCoord()
+	if m.IsDrop() {
+		return fmt.Sprintf("%v@%v", m.Drop, dst)
+	}
+
+	src, _ := m.From.toCoord()
 
 	promotion := ""
 	if m.Promotion != EmptyPiece {
@@ -112,3 +134,73 @@ func (m *Move) String() string {
 
 	return fmt.Sprintf("%v -> %v", src, dst) + promotion
 }
+
+// uciPromotions maps the promotion letters of UCI moves to the promoted white and black pieces, kings included for
+// variants such as Antichess
+var uciPromotions = map[byte][2]Piece{
+	'q': {WhiteQueen, BlackQueen},
+	'r': {WhiteRook, BlackRook},
+	'b': {WhiteBishop, BlackBishop},
+	'n': {WhiteKnight, BlackKnight},
+	'k': {WhiteKing, BlackKing},
+}
+
+// uciDrops maps the piece letters of UCI drops to the dropped white and black pieces
+var uciDrops = map[byte][2]Piece{
+	'Q': {WhiteQueen, BlackQueen},
+	'R': {WhiteRook, BlackRook},
+	'B': {WhiteBishop, BlackBishop},
+	'N': {WhiteKnight, BlackKnight},
+	'P': {WhitePawn, BlackPawn},
+}
+
+// NewMoveUCI returns a new Move from its UCI long algebraic notation, e.g. e2e4, e7e8q, or P@e4 for a drop, for the
+// player of color c. Returns an error if the notation could not be parsed correctly.
+func NewMoveUCI(s string, c Color) (*Move, error) {
+	if len(s) == 4 && s[1] == '@' {
+		pieces, ok := uciDrops[s[0]]
+		if !ok {
+			return nil, fmt.Errorf("dropped piece must be one of {Q,R,B,N,P}, is: %v", string(s[0]))
+		}
+
+		to, err := Coordinate(s[2:4]).Square()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse drop coordinate: %v", err)
+		}
+
+		return NewDrop(pieces[c], to), nil
+	}
+
+	if len(s) != 4 && len(s) != 5 {
+		return nil, fmt.Errorf("UCI move must be a length-4 or length-5 string: %v", s)
+	}
+
+	promotion := Piece(EmptyPiece)
+	if len(s) == 5 {
+		pieces, ok := uciPromotions[s[4]]
+		if !ok {
+			return nil, fmt.Errorf("promotion must be one of {q,r,b,n,k}, is: %v", string(s[4]))
+		}
+
+		promotion = pieces[c]
+	}
+
+	return NewMoveCoordPromotion(Coordinate(s[0:2]), Coordinate(s[2:4]), promotion)
+}
+
+// UCI returns the UCI long algebraic notation of this move, e.g. e2e4, e7e8q, or P@e4 for a drop
+func (m *Move) UCI() string {
+	dst, _ := m.To.toCoord()
+	if m.IsDrop() {
+		return strings.ToUpper(m.Drop.String()) + "@" + string(dst)
+	}
+
+	src, _ := m.From.toCoord()
+
+	promotion := ""
+	if m.Promotion != EmptyPiece {
+		promotion = strings.ToLower(m.Promotion.String())
+	}
+
+	return string(src) + string(dst) + promotion
+}
@@ -0,0 +1,140 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Offer is a proposal of a player to its opponent during a Game, which only takes effect if the opponent accepts it
+type Offer uint8
+
+// Offer types
+const (
+	// DrawOffer ends the game in a draw
+	DrawOffer Offer = iota
+
+	// UndoOffer takes back the last move of each player, so that the player who offered it moves again
+	UndoOffer
+)
+
+func (o Offer) String() string {
+	switch o {
+	case DrawOffer:
+		return "draw"
+	case UndoOffer:
+		return "undo"
+	default:
+		panic("Unhandled offer type")
+	}
+}
+
+// OfferAccepter is implemented by Players that consider the offers of their opponent. Players that don't implement it
+// decline all offers.
+type OfferAccepter interface {
+	// AcceptOffer returns true iff the player accepts the offer. It is called while the opponent is to move.
+	AcceptOffer(o Offer) bool
+}
+
+// Negotiator is implemented by GameClients through which players may resign, or make offers to their opponent
+type Negotiator interface {
+	// Resign makes player c lose the game
+	Resign(c Color)
+
+	// Offer makes an offer of player c to its opponent, on its turn, and returns true iff the opponent accepted it
+	Offer(c Color, o Offer) bool
+}
+
+// errEnded is returned while waiting for a move once the game ended otherwise, e.g. by resignation
+var errEnded = errors.New("game ended")
+
+// ending is a request to end the game other than by a move
+type ending struct {
+	outcome Outcome
+	reason  string
+}
+
+// end ends the game with the given result, and returns true, unless the game is already over. The request waits for
+// the game to wait for a move, so that a move ending the game takes precedence over the requests made meanwhile.
+func (g *Game) end(o Outcome, reason string) bool {
+	select {
+	case g.interrupt <- ending{o, reason}:
+		return true
+	case <-g.over:
+		return false
+	}
+}
+
+// interrupted records the result of e while waiting for a move, and returns errEnded
+func (g *Game) interrupted(e ending) error {
+	g.ended = g.result(e.outcome, e.reason)
+	return errEnded
+}
+
+// Resign makes player c lose the game
+func (g *Game) Resign(c Color) {
+	loser := Outcome(BlackWon)
+	if c == Black {
+		loser = WhiteWon
+	}
+
+	g.end(loser, fmt.Sprintf("%v resigned", c))
+}
+
+// Offer makes an offer of player c to its opponent, on its turn, and returns true iff the opponent accepted it before
+// the game was over. Undo offers can only be made once both players have moved.
+func (g *Game) Offer(c Color, o Offer) bool {
+	g.mu.Lock()
+	ok := g.board.Turn == c && (o != UndoOffer || len(g.moves) >= 2)
+	select {
+	case <-g.over:
+		ok = false
+	default:
+	}
+	g.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	opponent := g.blackPlayer
+	if c == Black {
+		opponent = g.whitePlayer
+	}
+
+	if a, ok := opponent.(OfferAccepter); !ok || !a.AcceptOffer(o) {
+		return false
+	}
+
+	switch o {
+	case DrawOffer:
+		return g.end(Draw, "draw by agreement")
+	case UndoOffer:
+		// moves are taken back while waiting for the next one, as the game checks the last one until then
+		done := make(chan struct{})
+		select {
+		case g.undos <- done:
+			<-done
+		case <-g.over:
+			return false
+		}
+	}
+
+	return true
+}
+
+// undo takes back the last n moves of the game, by playing the moves before them again from the start. Clocks are
+// left as they are.
+func (g *Game) undo(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.moves = g.moves[:len(g.moves)-n]
+	g.board = g.start.Copy()
+	g.positions = map[positionKey]int{g.board.positionKey(): 1}
+	for _, m := range g.moves {
+		g.board.UnsafeMove(m)
+		g.positions[g.board.positionKey()]++
+	}
+
+	// only consecutive scores count
+	g.scores = [2][]int{}
+}
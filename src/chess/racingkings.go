@@ -0,0 +1,74 @@
+package chess
+
+import "fmt"
+
+// racingKingsFEN is the starting position of Racing Kings
+const racingKingsFEN = "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+
+// eighthRank is the bitmap of all squares on the 8th rank
+const eighthRank bitmap = 0xFF00000000000000
+
+// RacingKings is the variant where the first player to bring the king to the 8th rank wins, and no move may give
+// check. As white moves first, black still draws by bringing the king to the 8th rank on the next move.
+var RacingKings Variant = racingKings{}
+
+type racingKings struct{}
+
+func (racingKings) Name() string {
+	return "Racing Kings"
+}
+
+func (v racingKings) NewBoard() *Board {
+	b, err := NewVariantBoardFromFEN(racingKingsFEN, v)
+	if err != nil {
+		panic(fmt.Sprintf("invalid starting position of Racing Kings: %v", err))
+	}
+
+	return b
+}
+
+func (racingKings) CheckMove(b *Board, m *Move) error {
+	after := b.Copy()
+	after.UnsafeMove(m)
+	if after.InCheck(after.Turn) {
+		return fmt.Errorf("can't give check in Racing Kings")
+	}
+
+	return nil
+}
+
+func (racingKings) Moved(b *Board, m *Move) {}
+
+func (racingKings) Outcome(b *Board) (Outcome, string, bool) {
+	white, black := b.Pieces[WhiteKing]&eighthRank != 0, b.Pieces[BlackKing]&eighthRank != 0
+	switch {
+	case white && black:
+		return Draw, "draw as both kings reached the 8th rank", true
+	case black:
+		return BlackWon, "Black won by bringing the king to the 8th rank", true
+	case white && b.Turn == Black && b.canReachEighthRank():
+		return 0, "", false // black may still draw
+	case white:
+		return WhiteWon, "White won by bringing the king to the 8th rank", true
+	default:
+		return 0, "", false
+	}
+}
+
+// insufficientMaterial returns false, as kings alone may still race
+func (racingKings) insufficientMaterial(b *Board) bool {
+	return false
+}
+
+// canReachEighthRank returns true iff the king of the player to move may move to the 8th rank
+func (b *Board) canReachEighthRank() bool {
+	king := b.Pieces[Piece(WhiteKing).ofColor(b.Turn)]
+
+	found := false
+	b.forEachLegalMove(func(m *Move) bool {
+		found = bitmap(m.From) == king && bitmap(m.To)&eighthRank != 0
+		return !found
+	})
+
+	return found
+}
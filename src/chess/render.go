@@ -0,0 +1,150 @@
+package chess
+
+import "strings"
+
+// RenderOptions are the options of rendering a board as text, e.g. for terminals
+type RenderOptions struct {
+	// Unicode draws pieces with their Unicode glyphs, e.g. ♔, instead of letters
+	Unicode bool
+
+	// Color colors the squares and pieces with ANSI escape codes, instead of telling squares apart by brackets
+	Color bool
+
+	// Coordinates labels the ranks and files
+	Coordinates bool
+
+	// Perspective is the player seeing the board from the bottom
+	Perspective Color
+
+	// LastMove, if set, is the move that led to the board, whose squares are highlighted
+	LastMove *Move
+
+	// Check highlights the king of the player to move when in check
+	Check bool
+
+	// Hints are squares marked for the player, e.g. the squares a selected piece may move to
+	Hints []Square
+}
+
+// glyphs are the Unicode glyphs of pieces, by piece
+var glyphs = [12]string{"♔", "♕", "♘", "♗", "♖", "♙", "♚", "♛", "♞", "♝", "♜", "♟"}
+
+// ANSI escape codes of the colors of squares and pieces
+const (
+	ansiReset       = "\x1b[0m"
+	ansiLight       = "\x1b[48;5;223m"
+	ansiDark        = "\x1b[48;5;137m"
+	ansiLightMoved  = "\x1b[48;5;186m"
+	ansiDarkMoved   = "\x1b[48;5;143m"
+	ansiCheck       = "\x1b[48;5;160m"
+	ansiHint        = "\x1b[48;5;108m"
+	ansiWhitePieces = "\x1b[1;97m"
+	ansiBlackPieces = "\x1b[1;30m"
+)
+
+// Render returns a human-readable representation of this board, as given by the options, one rank per line. Each
+// square is 3 characters wide.
+func (b *Board) Render(o RenderOptions) string {
+	var highlighted bitmap
+	if o.LastMove != nil {
+		highlighted = bitmap(o.LastMove.From | o.LastMove.To)
+	}
+
+	var hinted bitmap
+	for _, s := range o.Hints {
+		hinted |= bitmap(s)
+	}
+
+	var checked bitmap
+	if o.Check && b.InCheck(b.Turn) {
+		checked = b.Pieces[Piece(WhiteKing).ofColor(b.Turn)]
+	}
+
+	// ranks and files from the top left corner
+	ranks, files := []int{7, 6, 5, 4, 3, 2, 1, 0}, []int{0, 1, 2, 3, 4, 5, 6, 7}
+	if o.Perspective == Black {
+		ranks, files = files, ranks
+	}
+
+	var sb strings.Builder
+	for _, rank := range ranks {
+		if o.Coordinates {
+			sb.WriteString(string(digits[rank]) + " ")
+		}
+
+		for _, file := range files {
+			s := square(file, rank)
+			sb.WriteString(b.renderSquare(o, s, (file+rank)%2 == 1, s&highlighted != 0, s&hinted != 0, s&checked != 0))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	if o.Coordinates {
+		sb.WriteString("  ")
+		for _, file := range files {
+			sb.WriteString(" " + string(alphabet[file]) + " ")
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// renderSquare returns the representation of a square, given its color and whether it is highlighted or hinted
+func (b *Board) renderSquare(o RenderOptions, s bitmap, light, highlighted, hinted, checked bool) string {
+	p := b.PieceAt(Square(s))
+
+	piece := "."
+	switch {
+	case p == EmptyPiece && o.Color:
+		piece = " "
+	case p == EmptyPiece:
+	case o.Unicode && o.Color:
+		piece = glyphs[p.ofColor(Black)] // filled glyphs, colored below
+	case o.Unicode:
+		piece = glyphs[p]
+	default:
+		piece = p.String()
+	}
+
+	if !o.Color {
+		switch {
+		case checked:
+			return "!" + piece + "!"
+		case hinted && piece == ".":
+			return " * "
+		case hinted:
+			return "*" + piece + "*"
+		case highlighted:
+			return "[" + piece + "]"
+		default:
+			return " " + piece + " "
+		}
+	}
+
+	background := ansiDark
+	switch {
+	case checked:
+		background = ansiCheck
+	case hinted:
+		background = ansiHint
+	case highlighted && light:
+		background = ansiLightMoved
+	case highlighted:
+		background = ansiDarkMoved
+	case light:
+		background = ansiLight
+	}
+
+	foreground := ""
+	if p != EmptyPiece {
+		foreground = ansiWhitePieces
+		if p.Color() == Black {
+			foreground = ansiBlackPieces
+		}
+	}
+
+	return background + foreground + " " + piece + " " + ansiReset
+}
@@ -0,0 +1,208 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SAN returns the Standard Algebraic Notation of a legal move on this board, e.g. Nf3, exd5, O-O, e8=Q+, or N@f7 for
+// a drop
+func (b *Board) SAN(m *Move) string {
+	var san string
+	if m.IsDrop() {
+		to, _ := m.To.toCoord()
+		san = strings.ToUpper(m.Drop.String()) + "@" + string(to)
+		return san + b.checkSuffix(m)
+	}
+
+	p := b.PieceAt(m.From)
+	from, _ := m.From.toCoord()
+	to, _ := m.To.toCoord()
+
+	right := b.castling(p, b.PieceAt(m.To), bitmap(m.From), bitmap(m.To))
+
+	switch {
+	case right == whiteQueenside || right == blackQueenside:
+		san = "O-O-O"
+	case right == whiteKingside || right == blackKingside:
+		san = "O-O"
+	case p == WhitePawn || p == BlackPawn:
+		// pawns only change files when capturing, en passant included
+		if from[0] != to[0] {
+			san = string(from[0]) + "x"
+		}
+
+		san += string(to)
+		if m.Promotion != EmptyPiece {
+			san += "=" + strings.ToUpper(m.Promotion.String())
+		}
+	default:
+		san = strings.ToUpper(p.String()) + b.disambiguation(m, p, from)
+		if b.PieceAt(m.To) != EmptyPiece {
+			san += "x"
+		}
+
+		san += string(to)
+	}
+
+	return san + b.checkSuffix(m)
+}
+
+// checkSuffix returns the suffix of the SAN of a legal move on this board: # if it checkmates, + if it checks
+func (b *Board) checkSuffix(m *Move) string {
+	after := b.Copy()
+	after.UnsafeMove(m)
+	switch {
+	case after.IsCheckmate():
+		return "#"
+	case after.InCheck(after.Turn):
+		return "+"
+	default:
+		return ""
+	}
+}
+
+// parseDrop returns the legal drop of this board of the given piece, P if empty, onto the given coordinate
+func (b *Board) parseDrop(s, piece, to string) (*Move, error) {
+	if piece == "" {
+		piece = "P"
+	}
+
+	square, err := Coordinate(to).Square()
+	if err != nil {
+		return nil, fmt.Errorf("invalid move %v: %v", s, err)
+	}
+
+	for _, m := range b.LegalMoves() {
+		if m.IsDrop() && m.To == square && strings.ToUpper(m.Drop.String()) == piece {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("illegal move: %v", s)
+}
+
+// disambiguation returns the file, rank, or both, of the square a move of piece p starts from, as needed to tell it
+// apart from the legal moves of other pieces of the same type to the same square
+func (b *Board) disambiguation(m *Move, p Piece, from Coordinate) string {
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range b.LegalMoves() {
+		if other.To != m.To || other.From == m.From || b.PieceAt(other.From) != p {
+			continue
+		}
+
+		otherFrom, _ := other.From.toCoord()
+		ambiguous = true
+		sameFile = sameFile || otherFrom[0] == from[0]
+		sameRank = sameRank || otherFrom[1] == from[1]
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(from[0])
+	case !sameRank:
+		return string(from[1])
+	default:
+		return string(from)
+	}
+}
+
+// ParseSAN returns the legal move of this board written in the given Standard Algebraic Notation. Check and annotation
+// suffixes are optional, as are captures and the "=" of promotions, castling may be written with zeros, and moves may
+// be disambiguated more than needed. Returns an error if no legal move, or more than one, matches.
+func (b *Board) ParseSAN(s string) (*Move, error) {
+	san := strings.TrimRight(s, "+#!?")
+	san = strings.Replace(san, "0", "O", -1)
+
+	if san == "O-O" || san == "O-O-O" {
+		for _, m := range b.LegalMoves() {
+			p := b.PieceAt(m.From)
+			if (p == WhiteKing || p == BlackKing) && strings.TrimRight(b.SAN(m), "+#") == san {
+				return m, nil
+			}
+		}
+
+		return nil, fmt.Errorf("illegal castling: %v", s)
+	}
+
+	if i := strings.IndexByte(san, '@'); i >= 0 {
+		return b.parseDrop(s, san[:i], san[i+1:])
+	}
+
+	promotion := ""
+	if i := strings.Index(san, "="); i >= 0 {
+		promotion, san = san[i+1:], san[:i]
+	} else if n := len(san); n >= 3 && strings.Contains("QRBNK", san[n-1:]) && san[n-2] >= '1' && san[n-2] <= '8' {
+		promotion, san = san[n-1:], san[:n-1]
+	}
+
+	if len(san) < 2 || len(promotion) > 1 {
+		return nil, fmt.Errorf("invalid move: %v", s)
+	}
+
+	to, err := Coordinate(san[len(san)-2:]).Square()
+	if err != nil {
+		return nil, fmt.Errorf("invalid move %v: %v", s, err)
+	}
+
+	piece, hints := "P", san[:len(san)-2]
+	if len(hints) > 0 && strings.Contains("KQRBN", hints[:1]) {
+		piece, hints = hints[:1], hints[1:]
+	}
+	hints = strings.NewReplacer("x", "", "-", "", ":", "").Replace(hints)
+
+	var found *Move
+	for _, m := range b.LegalMoves() {
+		if m.To != to || strings.ToUpper(b.PieceAt(m.From).String()) != piece {
+			continue
+		}
+
+		if m.Promotion != EmptyPiece && strings.ToUpper(m.Promotion.String()) != promotion ||
+			m.Promotion == EmptyPiece && promotion != "" {
+			continue
+		}
+
+		from, _ := m.From.toCoord()
+		matches := true
+		for i := 0; i < len(hints); i++ {
+			matches = matches && strings.IndexByte(string(from), hints[i]) >= 0
+		}
+
+		if !matches {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous move: %v", s)
+		}
+		found = m
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("illegal move: %v", s)
+	}
+
+	return found, nil
+}
+
+// XBoardMove returns a legal move on this board in the coordinate notation of the xboard protocol, which is UCI
+// notation except for castling in Chess960, written O-O or O-O-O
+func (b *Board) XBoardMove(m *Move) string {
+	if san := strings.TrimRight(b.SAN(m), "+#"); b.Chess960 && strings.HasPrefix(san, "O-O") {
+		return san
+	}
+
+	return m.UCI()
+}
+
+// ParseXBoardMove parses a move on this board in the coordinate notation of the xboard protocol, or written O-O or
+// O-O-O for castling
+func (b *Board) ParseXBoardMove(s string) (*Move, error) {
+	if strings.HasPrefix(s, "O-O") || strings.HasPrefix(s, "0-0") {
+		return b.ParseSAN(s)
+	}
+
+	return NewMoveUCI(s, b.Turn)
+}
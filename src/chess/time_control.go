@@ -4,6 +4,10 @@ import "time"
 
 const (
 	INFINITY = 999999999
+
+	// UntimedClock is the amount of time left above which a clock is treated as untimed, such as the clocks of an
+	// InfiniteTime game
+	UntimedClock = 24 * time.Hour
 )
 
 type TimeControl interface {
@@ -30,3 +34,18 @@ func (it InfiniteTime) InitialTime() time.Duration {
 func (it InfiniteTime) Increment() time.Duration {
 	return 0 * time.Second
 }
+
+// Fischer is a time control with an initial time, and an increment added to the clock of a player after each of its
+// moves
+type Fischer struct {
+	Initial time.Duration
+	Inc     time.Duration
+}
+
+func (f Fischer) InitialTime() time.Duration {
+	return f.Initial
+}
+
+func (f Fischer) Increment() time.Duration {
+	return f.Inc
+}
@@ -0,0 +1,202 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is a set of rules a game is played by, differing from standard chess. A Board plays standard chess when its
+// Variant is nil. Variants hold no state of their own: whatever they need to remember, e.g. the number of checks
+// given, is kept by the board, so that copies of a board never share any state.
+type Variant interface {
+	// Name returns the name of the variant, as written in the Variant tag of PGN
+	Name() string
+
+	// NewBoard returns the starting position of the variant
+	NewBoard() *Board
+
+	// CheckMove returns an error if m, legal in standard chess on b, is illegal in the variant
+	CheckMove(b *Board, m *Move) error
+
+	// Moved updates the state of the variant on b, after m was played on it
+	Moved(b *Board, m *Move)
+
+	// Outcome returns the outcome of the game, and why it ended, if the variant ends it in the position on b. Standard
+	// endings, e.g. checkmate, apply as well, unless the variant changes them.
+	Outcome(b *Board) (Outcome, string, bool)
+}
+
+// checkVariant is implemented by variants changing when a king is in check
+type checkVariant interface {
+	// inCheck returns true iff the king of color c is in check on b
+	inCheck(b *Board, c Color) bool
+}
+
+// captureVariant is implemented by variants where captures have side effects
+type captureVariant interface {
+	// captured updates b once m captured a piece, before the turn passes to the other player
+	captured(b *Board, m *Move)
+}
+
+// commonKingVariant is implemented by variants where the king is a common piece: it is never in check, it may be
+// captured, and pawns may promote to it
+type commonKingVariant interface {
+	commonKing()
+}
+
+// materialVariant is implemented by variants changing when neither player has enough material left to win
+type materialVariant interface {
+	insufficientMaterial(b *Board) bool
+}
+
+// kinglessVariant is implemented by variants where a player has no king
+type kinglessVariant interface {
+	// kingless returns true iff the player of color c has no king
+	kingless(c Color) bool
+}
+
+// hasCommonKing returns true iff the king is a common piece in the variant of this board
+func (b *Board) hasCommonKing() bool {
+	_, ok := b.Variant.(commonKingVariant)
+	return ok
+}
+
+// hasKing returns true iff the player of color c has a single king in the variant of this board, as in standard chess
+func (b *Board) hasKing(c Color) bool {
+	v, ok := b.Variant.(kinglessVariant)
+	return !b.hasCommonKing() && !(ok && v.kingless(c))
+}
+
+// Variants are the supported variants, by name
+var Variants = map[string]Variant{}
+
+func init() {
+	for _, v := range []Variant{KingOfTheHill, ThreeCheck, Crazyhouse, Atomic, Antichess, Horde, RacingKings} {
+		Variants[v.Name()] = v
+	}
+}
+
+// VariantByName returns the variant of the given name, ignoring case, spaces and dashes, or nil for standard chess.
+// Returns an error if the variant isn't supported.
+func VariantByName(name string) (Variant, error) {
+	normalize := strings.NewReplacer(" ", "", "-", "").Replace
+	switch key := strings.ToLower(normalize(name)); key {
+	case "", "standard", "chess":
+		return nil, nil
+	default:
+		for n, v := range Variants {
+			if strings.ToLower(normalize(n)) == key {
+				return v, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported variant: %v", name)
+}
+
+// VariantOutcome returns the outcome of the game, and why it ended, if the variant of this board ends it in this
+// position
+func (b *Board) VariantOutcome() (Outcome, string, bool) {
+	if b.Variant == nil {
+		return 0, "", false
+	}
+
+	return b.Variant.Outcome(b)
+}
+
+// wins returns the outcome of c winning the game
+func wins(c Color) Outcome {
+	if c == White {
+		return WhiteWon
+	}
+
+	return BlackWon
+}
+
+// KingOfTheHill is the variant where a player also wins by bringing its king to one of the 4 central squares
+var KingOfTheHill Variant = kingOfTheHill{}
+
+type kingOfTheHill struct{}
+
+// hill is the bitmap of d4, e4, d5 and e5
+const hill bitmap = 0x0000001818000000
+
+func (kingOfTheHill) Name() string {
+	return "King of the Hill"
+}
+
+func (v kingOfTheHill) NewBoard() *Board {
+	b := NewBoard()
+	b.Variant = v
+	return b
+}
+
+func (kingOfTheHill) CheckMove(b *Board, m *Move) error {
+	return nil
+}
+
+func (kingOfTheHill) Moved(b *Board, m *Move) {}
+
+// insufficientMaterial returns false, as kings alone may still reach the center
+func (kingOfTheHill) insufficientMaterial(b *Board) bool {
+	return false
+}
+
+func (kingOfTheHill) Outcome(b *Board) (Outcome, string, bool) {
+	for _, c := range []Color{White, Black} {
+		king := b.Pieces[WhiteKing]
+		if c == Black {
+			king = b.Pieces[BlackKing]
+		}
+
+		if king&hill != 0 {
+			return wins(c), fmt.Sprintf("%v won by bringing the king to the center", c), true
+		}
+	}
+
+	return 0, "", false
+}
+
+// ThreeCheck is the variant where a player also wins by giving check for the third time. The checks given by each
+// player are counted in Board.Checks.
+var ThreeCheck Variant = threeCheck{}
+
+type threeCheck struct{}
+
+// checksToWin is the number of checks that wins a game of Three-Check
+const checksToWin = 3
+
+func (threeCheck) Name() string {
+	return "Three-Check"
+}
+
+func (v threeCheck) NewBoard() *Board {
+	b := NewBoard()
+	b.Variant = v
+	return b
+}
+
+func (threeCheck) CheckMove(b *Board, m *Move) error {
+	return nil
+}
+
+func (threeCheck) Moved(b *Board, m *Move) {
+	if b.InCheck(b.Turn) {
+		b.Checks[b.Turn.Other()]++
+	}
+}
+
+// insufficientMaterial returns true only for bare kings, as any other piece may still give the checks that win
+func (threeCheck) insufficientMaterial(b *Board) bool {
+	return b.PieceCount() == 2
+}
+
+func (threeCheck) Outcome(b *Board) (Outcome, string, bool) {
+	for _, c := range []Color{White, Black} {
+		if b.Checks[c] >= checksToWin {
+			return wins(c), fmt.Sprintf("%v won by giving check %v times", c, checksToWin), true
+		}
+	}
+
+	return 0, "", false
+}
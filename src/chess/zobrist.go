@@ -0,0 +1,114 @@
+package chess
+
+import "math/bits"
+
+var (
+	// zobristPieces [p][i] is the key of piece p standing on the square represented by 2^i
+	zobristPieces [12][64]uint64
+
+	// zobristCastling holds the keys of the white kingside, white queenside, black kingside and black queenside
+	// castling rights, in that order
+	zobristCastling [4]uint64
+
+	// zobristEnPassent [x] is the key of an EnPassent square on file x, where x = 0 is the a-file
+	zobristEnPassent [8]uint64
+
+	// zobristBlack is the key of Black being the player to move
+	zobristBlack uint64
+
+	// zobristChecks [c][n] is the key of player c having given n+1 checks, for variants counting them
+	zobristChecks [2][checksToWin]uint64
+
+	// zobristPockets [p][n] is the key of n+1 pieces p being in hand, for variants with pockets
+	zobristPockets [12][16]uint64
+)
+
+func init() {
+	// xorshift64*, with a fixed seed so hashes are the same across runs
+	var state uint64 = 0x9E3779B97F4A7C15
+	next := func() uint64 {
+		state ^= state >> 12
+		state ^= state << 25
+		state ^= state >> 27
+		return state * 2685821657736338717
+	}
+
+	for p := range zobristPieces {
+		for i := range zobristPieces[p] {
+			zobristPieces[p][i] = next()
+		}
+	}
+
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+
+	for i := range zobristEnPassent {
+		zobristEnPassent[i] = next()
+	}
+
+	zobristBlack = next()
+
+	for c := range zobristChecks {
+		for n := range zobristChecks[c] {
+			zobristChecks[c][n] = next()
+		}
+	}
+
+	for p := range zobristPockets {
+		for n := range zobristPockets[p] {
+			zobristPockets[p][n] = next()
+		}
+	}
+}
+
+// Hash returns the Zobrist hash of this board, which is equal for boards with the same pieces, EnPassent square,
+// castling rights, checks given, pieces in hand, and player to move.
+func (b *Board) Hash() uint64 {
+	var h uint64
+
+	for p, pieces := range b.Pieces {
+		for pieces != 0 {
+			i := bits.TrailingZeros64(uint64(pieces))
+			h ^= zobristPieces[p][i]
+			pieces &= pieces - 1
+		}
+	}
+
+	castling := [4]bool{b.CanWhiteCastleKingside, b.CanWhiteCastleQueenside, b.CanBlackCastleKingside, b.CanBlackCastleQueenside}
+	for i, ok := range castling {
+		if ok {
+			h ^= zobristCastling[i]
+		}
+	}
+
+	if b.EnPassent != 0 {
+		h ^= zobristEnPassent[b.EnPassent.Coordinates()[0]]
+	}
+
+	if b.Turn == Black {
+		h ^= zobristBlack
+	}
+
+	for c, n := range b.Checks {
+		if n > checksToWin {
+			n = checksToWin
+		}
+
+		if n > 0 {
+			h ^= zobristChecks[c][n-1]
+		}
+	}
+
+	for p, n := range b.Pockets {
+		if n > len(zobristPockets[p]) {
+			n = len(zobristPockets[p])
+		}
+
+		if n > 0 {
+			h ^= zobristPockets[p][n-1]
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"Chess2020/src/chess"
+	"Chess2020/src/players/lichess"
+	"Chess2020/src/players/search"
+)
+
+func main() {
+	url := flag.String("url", lichess.DefaultBaseURL, "base URL of the Bot API")
+	threads := flag.Int("threads", 1, "number of threads to search with in each game")
+	accept := flag.Bool("accept", true, "accept challenges to games of supported variants")
+	flag.Parse()
+
+	token := os.Getenv("LICHESS_TOKEN")
+	if token == "" {
+		log.Fatalf("LICHESS_TOKEN must be set to the API access token of the bot account")
+	}
+
+	bot := lichess.NewBot(*url, token, func() chess.Player {
+		return search.Player(*threads)
+	})
+	bot.AcceptChallenges = *accept
+
+	if err := bot.Listen(); err != nil {
+		log.Fatalf("Event stream failed: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"Chess2020/src/chess"
+	neatplayer "Chess2020/src/players/neat"
+	"Chess2020/src/players/random"
+	"flag"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	goneat "github.com/yaricom/goNEAT/neat"
+	"github.com/yaricom/goNEAT/neat/genetics"
+)
+
+// defaultContext holds the NEAT parameters used when no -context file is given
+const defaultContext = `trait_param_mut_prob 0.5
+trait_mutation_power 1.0
+weight_mut_power 2.5
+disjoint_coeff 1.0
+excess_coeff 1.0
+mutdiff_coeff 0.4
+compat_threshold 3.0
+age_significance 1.0
+survival_thresh 0.2
+mutate_only_prob 0.25
+mutate_random_trait_prob 0.1
+mutate_link_trait_prob 0.1
+mutate_node_trait_prob 0.1
+mutate_link_weights_prob 0.9
+mutate_toggle_enable_prob 0.0
+mutate_gene_reenable_prob 0.0
+mutate_add_node_prob 0.03
+mutate_add_link_prob 0.08
+mutate_connect_sensors 0.5
+interspecies_mate_rate 0.001
+mate_multipoint_prob 0.3
+mate_multipoint_avg_prob 0.3
+mate_singlepoint_prob 0.3
+mate_only_prob 0.2
+recur_only_prob 0.0
+pop_size 50
+dropoff_age 15
+newlink_tries 50
+print_every 10
+babies_stolen 0
+num_runs 1
+num_generations 50
+epoch_executor 0
+genome_compat_method 1
+log_level 3
+`
+
+// trainer evolves a population of NEAT players by having them play games
+type trainer struct {
+	context *goneat.NeatContext
+
+	opponent string
+	games    int
+
+	out         string
+	bestFitness float64
+
+	logger *log.Logger
+}
+
+// points returns the number of points scored by the player of color c in a game with result r
+func points(r *chess.GameResult, c chess.Color) float64 {
+	switch {
+	case r.Outcome == chess.Draw:
+		return 0.5
+	case r.Outcome == chess.WhiteWon && c == chess.White, r.Outcome == chess.BlackWon && c == chess.Black:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// play plays a game between white and black, returning the points scored by each side
+func play(white, black chess.Player) (float64, float64) {
+	r := chess.NewGame(white, black, chess.InfiniteTime{}).Start()
+	return points(r, chess.White), points(r, chess.Black)
+}
+
+// opponentFor returns a new opponent for org to play against
+func (t *trainer) opponentFor(org *genetics.Organism, pop *genetics.Population) (chess.Player, error) {
+	if t.opponent == "random" || len(pop.Organisms) < 2 {
+		return random.Player(), nil
+	}
+
+	opp := org
+	for opp == org {
+		opp = pop.Organisms[rand.Intn(len(pop.Organisms))]
+	}
+
+	// build a separate network, as an organism's phenotype can't play both sides of two games at once
+	net, err := opp.Genotype.Genesis(opp.Genotype.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return neatplayer.Player(net)
+}
+
+// evaluate sets the fitness of every organism in pop to the average number of points it scored over its games
+func (t *trainer) evaluate(pop *genetics.Population) error {
+	for _, org := range pop.Organisms {
+		score := 0.0
+		for i := 0; i < t.games; i++ {
+			p, err := neatplayer.Player(org.Phenotype)
+			if err != nil {
+				break // networks that can't be activated score nothing
+			}
+
+			opp, err := t.opponentFor(org, pop)
+			if err != nil {
+				return err
+			}
+
+			// alternate colors between games
+			if i%2 == 0 {
+				s, _ := play(p, opp)
+				score += s
+			} else {
+				_, s := play(opp, p)
+				score += s
+			}
+		}
+
+		// NEAT requires strictly positive fitness
+		org.Fitness = score/float64(t.games) + 0.001
+
+		if org.Fitness > t.bestFitness {
+			t.bestFitness = org.Fitness
+			if err := t.save(org); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// save writes the genome of org to the output file
+func (t *trainer) save(org *genetics.Organism) error {
+	f, err := os.Create(t.out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return org.Genotype.Write(f)
+}
+
+func (t *trainer) run() error {
+	pop, err := genetics.NewPopulation(neatplayer.StartGenome(), t.context)
+	if err != nil {
+		return err
+	}
+
+	executor := &genetics.SequentialPopulationEpochExecutor{}
+	for generation := 1; generation <= t.context.NumGenerations; generation++ {
+		if err := t.evaluate(pop); err != nil {
+			return err
+		}
+
+		t.logger.Printf("Generation %d: best fitness so far %.3f (%d species)", generation, t.bestFitness, len(pop.Species))
+
+		if err := executor.NextEpoch(generation, pop, t.context); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	contextPath := flag.String("context", "", "NEAT context configuration file (plain text format); uses built-in defaults if empty")
+	generations := flag.Int("generations", 0, "number of generations to evolve; overrides the context if positive")
+	popSize := flag.Int("pop", 0, "population size; overrides the context if positive")
+	opponent := flag.String("opponent", "random", "who organisms play against: random or population")
+	games := flag.Int("games", 4, "number of games each organism plays per generation")
+	out := flag.String("out", "champion.genome", "file to save the champion genome to")
+	flag.Parse()
+
+	rand.Seed(time.Now().UnixNano())
+
+	if *opponent != "random" && *opponent != "population" {
+		log.Fatalf("Unknown opponent: %v", *opponent)
+	}
+
+	var context *goneat.NeatContext
+	if *contextPath == "" {
+		context = goneat.LoadContext(strings.NewReader(defaultContext))
+	} else {
+		f, err := os.Open(*contextPath)
+		if err != nil {
+			log.Fatalf("Could not open context file: %v", err)
+		}
+
+		context = goneat.LoadContext(f)
+		f.Close()
+	}
+
+	if *generations > 0 {
+		context.NumGenerations = *generations
+	}
+
+	if *popSize > 0 {
+		context.PopSize = *popSize
+	}
+
+	// every game logs its result; silence them so training progress is readable
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	log.SetOutput(ioutil.Discard)
+
+	t := &trainer{
+		context:  context,
+		opponent: *opponent,
+		games:    *games,
+		out:      *out,
+		logger:   logger,
+	}
+
+	if err := t.run(); err != nil {
+		logger.Fatalf("Training failed: %v", err)
+	}
+
+	logger.Printf("Champion genome (fitness %.3f) saved to %v", t.bestFitness, *out)
+}
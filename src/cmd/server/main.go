@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"Chess2020/src/chess"
+	"Chess2020/src/players/remote"
+)
+
+// server pairs up clients connecting over TCP and plays games between them. A client starts by sending either
+//
+//	join            to play the next game, as white if no other client is waiting, as black otherwise
+//	rejoin <token>  to resume a game after being disconnected
+//
+// A joining client is sent "token <token>" to rejoin with, and then speaks the protocol of remote.RemotePlayer.
+type server struct {
+	timeControl chess.TimeControl
+	timeout     time.Duration
+
+	mu      sync.Mutex
+	waiting *remote.RemotePlayer
+	players map[string]*remote.RemotePlayer
+}
+
+func newToken() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// readLine reads a single line from conn, without reading past its end, so that the rest is left to the player
+func readLine(conn net.Conn) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := conn.Read(b); err != nil {
+			return "", err
+		}
+
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+
+		line = append(line, b[0])
+	}
+}
+
+// handle reads the first line sent by a client, and seats it in a game accordingly
+func (s *server) handle(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(s.timeout))
+	line, err := readLine(conn)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1 && fields[0] == "join":
+		s.join(conn)
+	case len(fields) == 2 && fields[0] == "rejoin":
+		s.mu.Lock()
+		p := s.players[fields[1]]
+		s.mu.Unlock()
+
+		if p == nil || !p.Reconnect(conn) {
+			fmt.Fprintln(conn, "gameover")
+			conn.Close()
+			return
+		}
+
+		log.Printf("Client %v rejoined", conn.RemoteAddr())
+	default:
+		fmt.Fprintln(conn, "illegal expected: join, or rejoin <token>")
+		conn.Close()
+	}
+}
+
+// join seats a client in the next game, starting it if the client is the second to join
+func (s *server) join(conn net.Conn) {
+	token := newToken()
+	fmt.Fprintf(conn, "token %v\n", token)
+
+	p := remote.Player(conn)
+	p.Timeout = s.timeout
+
+	s.mu.Lock()
+	s.players[token] = p
+
+	white := s.waiting
+	if white == nil {
+		s.waiting = p
+		s.mu.Unlock()
+
+		log.Printf("Client %v is waiting for an opponent", conn.RemoteAddr())
+		go s.forget(token, p, conn)
+		return
+	}
+
+	s.waiting = nil
+	s.mu.Unlock()
+
+	log.Printf("Client %v joined, starting a game", conn.RemoteAddr())
+	go func() {
+		chess.NewGame(white, p, s.timeControl).Start()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for t, player := range s.players {
+			if player == white || player == p {
+				delete(s.players, t)
+			}
+		}
+	}()
+}
+
+// forget drops a waiting client once it disconnects, so that the next client to join isn't paired with it
+func (s *server) forget(token string, p *remote.RemotePlayer, conn net.Conn) {
+	<-p.Disconnected()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiting != p {
+		return // its game has started
+	}
+
+	s.waiting = nil
+	delete(s.players, token)
+	conn.Close()
+
+	log.Printf("Client %v left before its game started", conn.RemoteAddr())
+}
+
+func main() {
+	addr := flag.String("addr", ":4000", "address to listen on")
+	minutes := flag.Float64("minutes", 5, "initial time on each clock, in minutes")
+	increment := flag.Float64("increment", 0, "time added to a clock after each move, in seconds")
+	timeout := flag.Duration("timeout", remote.DefaultTimeout, "time a disconnected client has to rejoin")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Could not listen on %v: %v", *addr, err)
+	}
+
+	log.Printf("Listening on %v", l.Addr())
+
+	s := &server{
+		timeControl: chess.Fischer{
+			Initial: time.Duration(*minutes * float64(time.Minute)),
+			Inc:     time.Duration(*increment * float64(time.Second)),
+		},
+		timeout: *timeout,
+		players: make(map[string]*remote.RemotePlayer),
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Fatalf("Could not accept connection: %v", err)
+		}
+
+		go s.handle(conn)
+	}
+}
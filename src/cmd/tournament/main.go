@@ -0,0 +1,368 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"Chess2020/src/chess"
+	neatplayer "Chess2020/src/players/neat"
+	"Chess2020/src/players/random"
+	"Chess2020/src/players/search"
+	"Chess2020/src/players/uciengine"
+	"Chess2020/src/players/xboardengine"
+	"Chess2020/src/rating"
+	"Chess2020/src/syzygy"
+	"Chess2020/src/tournament"
+)
+
+const usage = `Usage: tournament [flags] player...
+
+Each player is given as [name=]kind[:options], where kind is one of:
+
+  random                 plays random legal moves
+  search[:limits]        the search engine, with optional comma-separated limits on every move, e.g.
+                         search:depth=6 or search:threads=2,movetime=100ms; without any, it budgets time from its clock
+  neat:genome            the NEAT player with the genome stored at the given path
+  uci:path [args]        an external UCI engine
+  xboard:path [args]     an external xboard engine
+
+Flags:
+`
+
+// parseEntrant returns the entrant described by a player argument
+func parseEntrant(arg string) (tournament.Entrant, error) {
+	name, spec := arg, arg
+	if i := strings.Index(arg, "="); i >= 0 && !strings.Contains(arg[:i], ":") {
+		name, spec = arg[:i], arg[i+1:]
+	}
+
+	kind, options := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, options = spec[:i], spec[i+1:]
+	}
+
+	e := tournament.Entrant{Name: name}
+	switch kind {
+	case "random":
+		e.New = func() chess.Player { return random.Player() }
+
+	case "search":
+		threads, limits, err := parseLimits(options)
+		if err != nil {
+			return e, err
+		}
+
+		e.New = func() chess.Player {
+			if limits == nil {
+				return search.Player(threads)
+			}
+
+			return search.LimitedPlayer(threads, *limits)
+		}
+
+	case "neat":
+		if _, err := neatplayer.LoadPlayer(options); err != nil {
+			return e, err
+		}
+
+		e.New = func() chess.Player {
+			p, _ := neatplayer.LoadPlayer(options)
+			return p
+		}
+
+	case "uci", "xboard":
+		fields := strings.Fields(options)
+		if len(fields) == 0 {
+			return e, fmt.Errorf("%v: path of the engine is missing", arg)
+		}
+
+		if kind == "uci" {
+			e.New = func() chess.Player { return uciengine.Player(fields[0], fields[1:]...) }
+		} else {
+			e.New = func() chess.Player { return xboardengine.Player(fields[0], fields[1:]...) }
+		}
+
+	default:
+		return e, fmt.Errorf("%v: unknown kind of player: %v", arg, kind)
+	}
+
+	return e, nil
+}
+
+// parseLimits parses the options of a search player, returning its number of threads, and the limits of every move if
+// any
+func parseLimits(options string) (int, *search.Limits, error) {
+	threads := 1
+	var limits *search.Limits
+	for _, option := range strings.Split(options, ",") {
+		if option == "" {
+			continue
+		}
+
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return 0, nil, fmt.Errorf("search option must be of the form key=value, is: %v", option)
+		}
+
+		if kv[0] != "threads" && limits == nil {
+			limits = &search.Limits{}
+		}
+
+		var err error
+		switch kv[0] {
+		case "threads":
+			threads, err = strconv.Atoi(kv[1])
+		case "depth":
+			limits.Depth, err = strconv.Atoi(kv[1])
+		case "nodes":
+			limits.Nodes, err = strconv.ParseUint(kv[1], 10, 64)
+		case "movetime":
+			limits.MoveTime, err = time.ParseDuration(kv[1])
+		default:
+			err = fmt.Errorf("must be one of {threads,depth,nodes,movetime}")
+		}
+
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid search option %v: %v", option, err)
+		}
+	}
+
+	return threads, limits, nil
+}
+
+// playSwiss plays the given number of rounds of a Swiss tournament, pairing each round once the previous one is over,
+// and returns the games played
+func playSwiss(t *tournament.Tournament, rounds int) []*tournament.Game {
+	var games []*tournament.Game
+	for round := 1; round <= rounds; round++ {
+		pairings, err := tournament.SwissRound(len(t.Entrants), round, games)
+		if err != nil {
+			log.Printf("Stopping after round %v: %v", round-1, err)
+			break
+		}
+
+		for _, p := range pairings {
+			if p.Black == tournament.Bye {
+				log.Printf("Round %v: %v has a bye", round, t.Entrants[p.White].Name)
+			}
+		}
+
+		games = append(games, t.Play(pairings)...)
+	}
+
+	return games
+}
+
+func main() {
+	schedule := flag.String("schedule", "roundrobin", "schedule of the games: roundrobin, swiss, gauntlet of the first player against the others, or sprt of the first player against the second")
+	cycles := flag.Int("cycles", 2, "number of cycles of a round-robin, 2 being a double round-robin")
+	games := flag.Int("games", 2, "number of games of the first player against each other player in a gauntlet")
+	rounds := flag.Int("rounds", 0, "number of rounds of a Swiss tournament; 0 plays enough rounds to tell the winner, i.e. log2 of the number of players")
+	elo0 := flag.Float64("elo0", 0, "Elo difference of H0 in an SPRT")
+	elo1 := flag.Float64("elo1", 5, "Elo difference of H1 in an SPRT")
+	alpha := flag.Float64("alpha", 0.05, "probability of an SPRT accepting H1 when H0 holds")
+	beta := flag.Float64("beta", 0.05, "probability of an SPRT accepting H0 when H1 holds")
+	concurrency := flag.Int("concurrency", 1, "number of games played at the same time")
+	initial := flag.Duration("time", time.Minute, "initial time on each clock; 0 plays untimed games")
+	increment := flag.Duration("increment", 0, "time added to a clock after each move")
+	maxMoves := flag.Int("maxmoves", chess.DefaultMaxMoves, "number of moves of each player after which a game is drawn; 0 plays games to the end")
+	resignScore := flag.Int("resignscore", 600, "score, in centipawns, both engines must give a side to adjudicate it the winner")
+	resignMoves := flag.Int("resignmoves", 0, "number of consecutive moves both engines must score beyond -resignscore to adjudicate a win; 0 disables it")
+	drawMoveNumber := flag.Int("drawmovenumber", 40, "number of moves of each player before a draw may be adjudicated from scores")
+	drawScore := flag.Int("drawscore", 10, "score, in centipawns, both engines must score within to adjudicate a draw")
+	drawMoves := flag.Int("drawmoves", 0, "number of consecutive moves both engines must score within -drawscore to adjudicate a draw; 0 disables it")
+	syzygyPath := flag.String("syzygy", "", "directories of Syzygy tablebases to adjudicate positions with few pieces with, separated like PATH")
+	openingsPath := flag.String("openings", "", "EPD file of positions, or PGN file of move sequences, to start games from in turn, each one played with both colors")
+	variant := flag.String("variant", "", "variant to play games in, e.g. \"King of the Hill\" or \"Three-Check\"; standard chess if empty")
+	event := flag.String("event", "Tournament", "name of the tournament")
+	pgnPath := flag.String("pgn", "tournament.pgn", "file to write all games to in PGN")
+	ratingsPath := flag.String("ratings", "", "file of the ratings of a ladder to rate the games in, created if missing")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entrants := make([]tournament.Entrant, flag.NArg())
+	for i, arg := range flag.Args() {
+		var err error
+		if entrants[i], err = parseEntrant(arg); err != nil {
+			log.Fatalf("Invalid player: %v", err)
+		}
+	}
+
+	var pairings []tournament.Pairing
+	total := 0
+	switch *schedule {
+	case "roundrobin":
+		pairings = tournament.RoundRobin(len(entrants), *cycles)
+		total = len(pairings)
+	case "gauntlet":
+		pairings = tournament.Gauntlet(len(entrants), *games)
+		total = len(pairings)
+	case "swiss":
+		if *rounds == 0 {
+			for 1<<uint(*rounds) < len(entrants) {
+				*rounds++
+			}
+		}
+		total = *rounds * (len(entrants) / 2)
+	case "sprt":
+		if len(entrants) != 2 {
+			log.Fatalf("An SPRT is played between 2 players, not: %v", len(entrants))
+		}
+	default:
+		log.Fatalf("Schedule must be one of {roundrobin,swiss,gauntlet,sprt}, is: %v", *schedule)
+	}
+
+	var tc chess.TimeControl = chess.InfiniteTime{}
+	if *initial > 0 {
+		tc = chess.Fischer{Initial: *initial, Inc: *increment}
+	}
+
+	t := &tournament.Tournament{
+		Event:       *event,
+		Entrants:    entrants,
+		Pairings:    pairings,
+		TimeControl: tc,
+		Concurrency: *concurrency,
+	}
+
+	t.Adjudication = &chess.Adjudication{
+		MaxMoves:       *maxMoves,
+		ResignScore:    *resignScore,
+		ResignMoves:    *resignMoves,
+		DrawMoveNumber: *drawMoveNumber,
+		DrawScore:      *drawScore,
+		DrawMoves:      *drawMoves,
+	}
+
+	if *syzygyPath != "" {
+		tb, err := syzygy.Open(filepath.SplitList(*syzygyPath)...)
+		if err != nil {
+			log.Fatalf("Could not open tablebases: %v", err)
+		}
+		t.Adjudication.Tablebase = tb
+	}
+
+	if *openingsPath != "" {
+		var err error
+		if t.Openings, err = tournament.LoadOpenings(*openingsPath); err != nil {
+			log.Fatalf("Could not load openings: %v", err)
+		}
+	}
+
+	var err error
+	if t.Variant, err = chess.VariantByName(*variant); err != nil {
+		log.Fatalf("Could not play variant: %v", err)
+	}
+
+	played := 0
+	t.OnGame = func(g *tournament.Game) {
+		played++
+		if total > 0 {
+			log.Printf("Game %v/%v, round %v: %v - %v %v (%v)", played, total, g.Round,
+				entrants[g.White].Name, entrants[g.Black].Name, g.Result.Outcome, g.Result.Reason)
+		} else {
+			log.Printf("Game %v, pair %v: %v - %v %v (%v)", played, g.Round,
+				entrants[g.White].Name, entrants[g.Black].Name, g.Result.Outcome, g.Result.Reason)
+		}
+	}
+
+	var results []*tournament.Game
+	var sprt *rating.SPRT
+	switch *schedule {
+	case "swiss":
+		results = playSwiss(t, *rounds)
+	case "sprt":
+		sprt = rating.NewSPRT(*elo0, *elo1, *alpha, *beta)
+		results = t.RunSPRT(sprt, func(s *rating.SPRT) {
+			log.Print(s)
+		})
+	default:
+		results = t.Run()
+	}
+
+	f, err := os.Create(*pgnPath)
+	if err != nil {
+		log.Fatalf("Could not create PGN file: %v", err)
+	}
+
+	if err := t.WritePGN(f, results); err != nil {
+		log.Fatalf("Could not write PGN: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		log.Fatalf("Could not write PGN: %v", err)
+	}
+
+	fmt.Print(tournament.NewCrosstable(entrants, results))
+
+	if len(entrants) == 2 {
+		fmt.Printf("\n%v vs %v\n%v\n", entrants[0].Name, entrants[1].Name, match(results))
+	}
+
+	if sprt != nil {
+		fmt.Printf("SPRT: %v\n", sprt)
+	}
+
+	if *ratingsPath != "" {
+		if err := rate(*ratingsPath, entrants, results); err != nil {
+			log.Fatalf("Could not rate the games: %v", err)
+		}
+	}
+}
+
+// match returns the statistics of the games played by the first entrant against the second one
+func match(games []*tournament.Game) *rating.Match {
+	m := &rating.Match{}
+	for _, g := range games {
+		switch {
+		case g.Result == nil:
+		case g.White == 0 && g.Black == 1:
+			m.Add(g.Result, chess.White)
+		case g.White == 1 && g.Black == 0:
+			m.Add(g.Result, chess.Black)
+		}
+	}
+
+	return m
+}
+
+// rate rates the games in the ladder saved at path, as one rating period, and prints the updated ladder
+func rate(path string, entrants []tournament.Entrant, games []*tournament.Game) error {
+	l, err := rating.Load(path)
+	if err != nil {
+		return err
+	}
+
+	var results []rating.Result
+	for _, g := range games {
+		if g.Result != nil {
+			results = append(results, rating.Result{
+				White:   entrants[g.White].Name,
+				Black:   entrants[g.Black].Name,
+				Outcome: g.Result.Outcome,
+			})
+		}
+	}
+
+	l.Rate(results)
+	if err := l.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%v", l)
+	return nil
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Chess2020/src/chess"
+	neatplayer "Chess2020/src/players/neat"
+	"Chess2020/src/players/random"
+	"Chess2020/src/players/search"
+	"Chess2020/src/syzygy"
+)
+
+// searcher finds moves for the UCI front-end
+type searcher interface {
+	newGame()
+
+	// start searches b in the background, calling info with the progress of the search, if known, and done with the
+	// best move found, or nil if there are no legal moves
+	start(b *chess.Board, history []uint64, limits search.Limits, info func(r *search.Result), done func(m *chess.Move))
+
+	// stop ends the running search as soon as possible
+	stop()
+}
+
+// engineSearcher searches with this project's search Engine, which supports every UCI option
+type engineSearcher struct {
+	engine *search.Engine
+
+	threads   int
+	hashMB    int
+	tablebase *syzygy.Tablebase
+}
+
+func newEngineSearcher() *engineSearcher {
+	s := &engineSearcher{
+		threads: 1,
+		hashMB:  search.DefaultHashMB,
+	}
+
+	s.engine = search.NewEngine(s.threads, s.hashMB)
+	return s
+}
+
+func (s *engineSearcher) newGame() {
+	s.engine.NewGame()
+}
+
+func (s *engineSearcher) start(b *chess.Board, history []uint64, limits search.Limits, info func(r *search.Result), done func(m *chess.Move)) {
+	s.engine.Threads = s.threads
+	s.engine.Tablebase = s.tablebase
+	s.engine.OnIteration = info
+
+	s.engine.Go(b, history, limits, func(r *search.Result) {
+		done(r.Move)
+	})
+}
+
+func (s *engineSearcher) stop() {
+	s.engine.Stop()
+}
+
+// setOption applies a UCI option, returning an error if the option is unknown or its value invalid. Must not be
+// called during a search.
+func (s *engineSearcher) setOption(name, value string) error {
+	switch strings.ToLower(name) {
+	case "threads":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of threads: %v", value)
+		}
+
+		s.threads = n
+	case "hash":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid hash size: %v", value)
+		}
+
+		s.hashMB = n
+		s.engine = search.NewEngine(s.threads, s.hashMB)
+	case "syzygypath":
+		if value == "" || value == "<empty>" {
+			s.tablebase = nil
+			return nil
+		}
+
+		tb, err := syzygy.Open(filepath.SplitList(value)...)
+		if err != nil {
+			return err
+		}
+
+		s.tablebase = tb
+	default:
+		return fmt.Errorf("unknown option: %v", name)
+	}
+
+	return nil
+}
+
+// playerSearcher asks a Player for its move, as if it were playing a game from the searched position. Players
+// can't be stopped, nor report progress; in infinite mode, their move is held until the search is stopped.
+type playerSearcher struct {
+	newPlayer func() chess.Player
+
+	// closed by stop, to release the move of an infinite search
+	stopped chan struct{}
+}
+
+func (s *playerSearcher) newGame() {}
+
+func (s *playerSearcher) start(b *chess.Board, history []uint64, limits search.Limits, info func(r *search.Result), done func(m *chess.Move)) {
+	stopped := make(chan struct{})
+	s.stopped = stopped
+
+	// UCI forbids reporting the best move of an infinite search before "stop"
+	finish := func(m *chess.Move) {
+		if limits.Infinite {
+			<-stopped
+		}
+
+		done(m)
+	}
+
+	if len(b.LegalMoves()) == 0 {
+		go finish(nil)
+		return
+	}
+
+	p := s.newPlayer()
+	prompt := make(chan chess.Prompt)
+	move := make(chan *chess.Move)
+	p.Init(b.Turn, &client{board: b.Copy(), limits: limits}, prompt, move)
+
+	go p.Run()
+	go func() {
+		prompt <- chess.Prompt{}
+		m := <-move
+		close(prompt)
+		finish(m)
+	}()
+}
+
+func (s *playerSearcher) stop() {
+	if s.stopped != nil {
+		close(s.stopped)
+		s.stopped = nil
+	}
+}
+
+// client is the GameClient of a Player asked for a move from a single position
+type client struct {
+	board  *chess.Board
+	limits search.Limits
+}
+
+func (c *client) GetBoard() *chess.Board {
+	return c.board.Copy()
+}
+
+func (c *client) GetTimeLeft(color chess.Color) time.Duration {
+	left := c.limits.WhiteTime
+	if color == chess.Black {
+		left = c.limits.BlackTime
+	}
+
+	if left <= 0 {
+		return chess.InfiniteTime{}.InitialTime()
+	}
+
+	return left
+}
+
+func (c *client) GetTimeControl() chess.TimeControl {
+	return timeControl{inc: c.limits.WhiteInc}
+}
+
+// timeControl is the time control given by the parameters of a "go" command
+type timeControl struct {
+	inc time.Duration
+}
+
+func (tc timeControl) InitialTime() time.Duration {
+	return chess.InfiniteTime{}.InitialTime()
+}
+
+func (tc timeControl) Increment() time.Duration {
+	return tc.inc
+}
+
+// uci is the state of a UCI session
+type uci struct {
+	name     string
+	searcher searcher
+
+	out   io.Writer
+	outMu sync.Mutex
+
+	board   *chess.Board
+	history []uint64
+
+	// chess960 is set by the UCI_Chess960 option: positions are Chess960 positions, and castling is written as the
+	// king capturing its own rook
+	chess960 bool
+
+	// closed when the running search, if any, has ended
+	searchDone chan struct{}
+	started    time.Time
+}
+
+func (u *uci) send(format string, args ...interface{}) {
+	u.outMu.Lock()
+	defer u.outMu.Unlock()
+
+	fmt.Fprintf(u.out, format+"\n", args...)
+}
+
+// wait blocks until the running search, if any, has ended
+func (u *uci) wait() {
+	if u.searchDone != nil {
+		<-u.searchDone
+	}
+}
+
+// run handles commands read from in until it is closed or "quit" is received
+func (u *uci) run(in io.Reader) {
+	u.board = chess.NewBoard()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			u.send("id name %v", u.name)
+			u.send("id author Chess2020")
+			if _, ok := u.searcher.(*engineSearcher); ok {
+				u.send("option name Threads type spin default 1 min 1 max 256")
+				u.send("option name Hash type spin default %d min 1 max 65536", search.DefaultHashMB)
+				u.send("option name SyzygyPath type string default <empty>")
+			}
+			u.send("option name UCI_Chess960 type check default false")
+			u.send("uciok")
+		case "isready":
+			u.send("readyok")
+		case "setoption":
+			u.wait()
+			u.setOption(fields[1:])
+		case "ucinewgame":
+			u.wait()
+			u.searcher.newGame()
+			u.board = chess.NewBoard()
+			u.history = nil
+		case "position":
+			u.wait()
+			u.position(fields[1:])
+		case "go":
+			u.wait()
+			u.goSearch(fields[1:])
+		case "stop":
+			u.searcher.stop()
+		case "quit":
+			u.searcher.stop()
+			u.wait()
+			return
+		default:
+			u.send("info string unknown command: %v", fields[0])
+		}
+	}
+
+	u.searcher.stop()
+	u.wait()
+}
+
+// setOption handles "setoption name <id> [value <x>]"
+func (u *uci) setOption(args []string) {
+	var name, value []string
+	var target *[]string
+	for _, arg := range args {
+		switch {
+		case arg == "name" && target == nil:
+			target = &name
+		case arg == "value" && target == &name:
+			target = &value
+		case target != nil:
+			*target = append(*target, arg)
+		}
+	}
+
+	if strings.EqualFold(strings.Join(name, " "), "UCI_Chess960") {
+		u.chess960 = strings.Join(value, " ") == "true"
+		return
+	}
+
+	s, ok := u.searcher.(*engineSearcher)
+	if !ok {
+		u.send("info string options are not supported by this player")
+		return
+	}
+
+	if err := s.setOption(strings.Join(name, " "), strings.Join(value, " ")); err != nil {
+		u.send("info string %v", err)
+	}
+}
+
+// position handles "position startpos|fen <fen> [moves <move>...]"
+func (u *uci) position(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	var b *chess.Board
+	var rest []string
+	switch args[0] {
+	case "startpos":
+		b = chess.NewBoard()
+		rest = args[1:]
+	case "fen":
+		end := 1
+		for end < len(args) && args[end] != "moves" {
+			end++
+		}
+
+		var err error
+		if b, err = chess.NewBoardFromFEN(strings.Join(args[1:end], " ")); err != nil {
+			u.send("info string invalid position: %v", err)
+			return
+		}
+		rest = args[end:]
+	default:
+		u.send("info string invalid position: %v", args[0])
+		return
+	}
+
+	b.Chess960 = b.Chess960 || u.chess960
+
+	var history []uint64
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, s := range rest[1:] {
+			m, err := chess.NewMoveUCI(s, b.Turn)
+			if err == nil {
+				err = b.CheckMove(m)
+			}
+
+			if err != nil {
+				u.send("info string illegal move %v: %v", s, err)
+				break
+			}
+
+			history = append(history, b.Hash())
+			b.UnsafeMove(m)
+		}
+	}
+
+	u.board = b
+	u.history = history
+}
+
+// goSearch handles "go" with its search parameters, starting a search that reports its best move when done
+func (u *uci) goSearch(args []string) {
+	var limits search.Limits
+	for i := 0; i < len(args); i++ {
+		value := func() int {
+			if i+1 >= len(args) {
+				return 0
+			}
+
+			i++
+			n, _ := strconv.Atoi(args[i])
+			return n
+		}
+
+		ms := func() time.Duration {
+			return time.Duration(value()) * time.Millisecond
+		}
+
+		switch args[i] {
+		case "wtime":
+			limits.WhiteTime = ms()
+		case "btime":
+			limits.BlackTime = ms()
+		case "winc":
+			limits.WhiteInc = ms()
+		case "binc":
+			limits.BlackInc = ms()
+		case "movestogo":
+			limits.MovesToGo = value()
+		case "depth":
+			limits.Depth = value()
+		case "nodes":
+			limits.Nodes = uint64(value())
+		case "movetime":
+			limits.MoveTime = ms()
+		case "infinite":
+			limits.Infinite = true
+		}
+	}
+
+	done := make(chan struct{})
+	u.searchDone = done
+	u.started = time.Now()
+
+	u.searcher.start(u.board, u.history, limits, u.info, func(m *chess.Move) {
+		if m == nil {
+			u.send("bestmove 0000")
+		} else {
+			u.send("bestmove %v", m.UCI())
+		}
+
+		close(done)
+	})
+}
+
+// info reports the progress of a search
+func (u *uci) info(r *search.Result) {
+	score := fmt.Sprintf("cp %d", r.Score)
+	if mate := search.MateIn(r.Score); mate != 0 {
+		score = fmt.Sprintf("mate %d", mate)
+	}
+
+	elapsed := time.Since(u.started)
+	nps := uint64(float64(r.Nodes) / (elapsed.Seconds() + 1e-9))
+
+	pv := make([]string, len(r.PV))
+	for i, m := range r.PV {
+		pv[i] = m.UCI()
+	}
+
+	u.send("info depth %d score %v nodes %d nps %d time %d pv %v", r.Depth, score, r.Nodes, nps, elapsed.Milliseconds(),
+		strings.Join(pv, " "))
+}
+
+func main() {
+	player := flag.String("player", "search", "player to search with: search, random or neat")
+	genome := flag.String("genome", "champion.genome", "genome file of the neat player")
+	flag.Parse()
+
+	rand.Seed(time.Now().UnixNano())
+
+	// the GUI reads stdout; keep log output of the players out of it
+	log.SetOutput(os.Stderr)
+
+	var s searcher
+	name := "Chess2020"
+	switch *player {
+	case "search":
+		s = newEngineSearcher()
+	case "random":
+		s = &playerSearcher{newPlayer: func() chess.Player { return random.Player() }}
+		name += " (random)"
+	case "neat":
+		if _, err := neatplayer.LoadPlayer(*genome); err != nil {
+			log.Fatalf("Could not load NEAT player: %v", err)
+		}
+
+		s = &playerSearcher{newPlayer: func() chess.Player {
+			p, _ := neatplayer.LoadPlayer(*genome)
+			return p
+		}}
+		name += " (neat)"
+	default:
+		log.Fatalf("Unknown player: %v", *player)
+	}
+
+	u := &uci{
+		name:     name,
+		searcher: s,
+		out:      os.Stdout,
+	}
+
+	u.run(os.Stdin)
+}
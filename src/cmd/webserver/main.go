@@ -0,0 +1,17 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"Chess2020/src/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	flag.Parse()
+
+	log.Printf("Listening on %v", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server.New()))
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Chess2020/src/chess"
+	"Chess2020/src/players/search"
+	"Chess2020/src/syzygy"
+)
+
+// xboard is the state of a session speaking the Chess Engine Communication Protocol
+type xboard struct {
+	engine  *search.Engine
+	threads int
+
+	out   io.Writer
+	outMu sync.Mutex
+
+	// position the game started from, and the moves played since; the current board is start with moves applied
+	start *chess.Board
+	moves []*chess.Move
+	board *chess.Board
+
+	// color played by the engine, unless in force mode, in which it only keeps track of the moves
+	engineColor chess.Color
+	force       bool
+
+	// post makes the engine report its thinking
+	post bool
+
+	// chess960 is set by "variant fischerandom", until the next game
+	chess960 bool
+
+	// time control set by "level", "st" and "sd"
+	movesPerSession int
+	base            time.Duration
+	inc             time.Duration
+	moveTime        time.Duration
+	depth           int
+
+	// clocks of the engine and its opponent, as last reported by "time" and "otim"
+	engineTime time.Duration
+	oppTime    time.Duration
+
+	// closed when the running search, if any, has ended
+	searchDone chan struct{}
+
+	// abandoned is set when the running search is stopped without playing its move
+	abandonMu sync.Mutex
+	abandoned bool
+}
+
+func (x *xboard) send(format string, args ...interface{}) {
+	x.outMu.Lock()
+	defer x.outMu.Unlock()
+
+	fmt.Fprintf(x.out, format+"\n", args...)
+}
+
+// wait blocks until the running search, if any, has ended
+func (x *xboard) wait() {
+	if x.searchDone != nil {
+		<-x.searchDone
+	}
+}
+
+// abandon stops the running search, if any, without playing its move
+func (x *xboard) abandon() {
+	x.abandonMu.Lock()
+	x.abandoned = true
+	x.abandonMu.Unlock()
+
+	x.engine.Stop()
+	x.wait()
+}
+
+// newGame resets the board to the starting position, with the engine playing black
+func (x *xboard) newGame() {
+	// the variant of the previous game must not carry over to the new board
+	x.chess960 = false
+	x.setBoard(chess.NewBoard())
+	x.engine.NewGame()
+
+	x.engineColor = chess.Black
+	x.force = false
+	x.moveTime = 0
+	x.depth = 0
+}
+
+// setBoard resets the game to start from b
+func (x *xboard) setBoard(b *chess.Board) {
+	if x.chess960 {
+		b = b.Copy()
+		b.Chess960 = true
+	}
+
+	x.start = b
+	x.moves = nil
+	x.board = b.Copy()
+}
+
+// history returns the hashes of all positions that occurred before the current board, oldest first
+func (x *xboard) history() []uint64 {
+	b := x.start.Copy()
+	history := make([]uint64, 0, len(x.moves))
+	for _, m := range x.moves {
+		history = append(history, b.Hash())
+		b.UnsafeMove(m)
+	}
+
+	return history
+}
+
+// play plays m on the board, and announces the result if it ends the game
+func (x *xboard) play(m *chess.Move) {
+	x.moves = append(x.moves, m)
+	x.board.UnsafeMove(m)
+
+	switch {
+	case x.board.IsCheckmate() && x.board.Turn == chess.White:
+		x.send("0-1 {Black mates}")
+	case x.board.IsCheckmate():
+		x.send("1-0 {White mates}")
+	case x.board.IsStalemate():
+		x.send("1/2-1/2 {Stalemate}")
+	case x.board.IsInsufficientMaterial():
+		x.send("1/2-1/2 {Insufficient material}")
+	case x.board.HalfMoveClock >= 100:
+		x.send("1/2-1/2 {50 move rule}")
+	}
+}
+
+// undo takes back the last n moves
+func (x *xboard) undo(n int) {
+	if n > len(x.moves) {
+		n = len(x.moves)
+	}
+
+	moves := x.moves[:len(x.moves)-n]
+	x.setBoard(x.start)
+	for _, m := range moves {
+		x.moves = append(x.moves, m)
+		x.board.UnsafeMove(m)
+	}
+}
+
+// limits returns the limits to search the current move with
+func (x *xboard) limits() search.Limits {
+	limits := search.Limits{
+		Depth:    x.depth,
+		MoveTime: x.moveTime,
+	}
+
+	if x.moveTime > 0 {
+		return limits
+	}
+
+	engineTime, oppTime := x.engineTime, x.oppTime
+	if engineTime <= 0 {
+		engineTime = x.base
+	}
+	if oppTime <= 0 {
+		oppTime = x.base
+	}
+
+	if engineTime <= 0 {
+		return limits // no time control: search to the given depth, or until told to move
+	}
+
+	limits.WhiteTime, limits.BlackTime = engineTime, oppTime
+	if x.engineColor == chess.Black {
+		limits.WhiteTime, limits.BlackTime = oppTime, engineTime
+	}
+	limits.WhiteInc, limits.BlackInc = x.inc, x.inc
+
+	if x.movesPerSession > 0 {
+		played := (len(x.moves) + 1) / 2
+		limits.MovesToGo = x.movesPerSession - played%x.movesPerSession
+	}
+
+	return limits
+}
+
+// think starts searching for the engine's move, which is played when the search ends
+func (x *xboard) think() {
+	if len(x.board.LegalMoves()) == 0 {
+		return
+	}
+
+	x.abandonMu.Lock()
+	x.abandoned = false
+	x.abandonMu.Unlock()
+
+	done := make(chan struct{})
+	x.searchDone = done
+
+	// the search reports from its own goroutine, while the command loop keeps changing post
+	post, started := x.post, time.Now()
+
+	x.engine.Threads = x.threads
+	x.engine.OnIteration = func(r *search.Result) {
+		if post {
+			x.thinking(r, started)
+		}
+	}
+	x.engine.Go(x.board, x.history(), x.limits(), func(r *search.Result) {
+		defer close(done)
+
+		x.abandonMu.Lock()
+		abandoned := x.abandoned
+		x.abandonMu.Unlock()
+
+		if abandoned || r.Move == nil {
+			return
+		}
+
+		x.send("move %v", x.board.XBoardMove(r.Move))
+		x.play(r.Move)
+	})
+}
+
+// thinking reports the progress of a search started at started
+func (x *xboard) thinking(r *search.Result, started time.Time) {
+	score := r.Score
+	if mate := search.MateIn(r.Score); mate > 0 {
+		score = 100000 + mate
+	} else if mate < 0 {
+		score = -100000 + mate
+	}
+
+	pv := make([]string, len(r.PV))
+	for i, m := range r.PV {
+		pv[i] = m.UCI()
+	}
+
+	centiseconds := time.Since(started).Milliseconds() / 10
+	x.send("%d %d %d %d %v", r.Depth, score, centiseconds, r.Nodes, strings.Join(pv, " "))
+}
+
+// engineToMove returns true iff the engine should think about the current position
+func (x *xboard) engineToMove() bool {
+	return !x.force && x.board.Turn == x.engineColor
+}
+
+// userMove handles a move of the opponent, in coordinate notation
+func (x *xboard) userMove(s string) {
+	m, err := x.board.ParseXBoardMove(s)
+	if err == nil {
+		err = x.board.CheckMove(m)
+	}
+
+	if err != nil {
+		x.send("Illegal move: %v", s)
+		return
+	}
+
+	x.play(m)
+	if x.engineToMove() {
+		x.think()
+	}
+}
+
+// level handles "level MPS BASE INC", where BASE is in minutes, or minutes:seconds, and INC in seconds
+func (x *xboard) level(args []string) {
+	if len(args) != 3 {
+		x.send("Error (wrong number of arguments): level")
+		return
+	}
+
+	mps, err := strconv.Atoi(args[0])
+	if err != nil {
+		x.send("Error (invalid moves per session): %v", args[0])
+		return
+	}
+
+	var base time.Duration
+	minutes := strings.SplitN(args[1], ":", 2)
+	for i, unit := range []time.Duration{time.Minute, time.Second}[:len(minutes)] {
+		n, err := strconv.Atoi(minutes[i])
+		if err != nil {
+			x.send("Error (invalid base time): %v", args[1])
+			return
+		}
+
+		base += time.Duration(n) * unit
+	}
+
+	inc, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		x.send("Error (invalid increment): %v", args[2])
+		return
+	}
+
+	x.movesPerSession = mps
+	x.base = base
+	x.inc = time.Duration(inc * float64(time.Second))
+	x.moveTime = 0
+	x.engineTime, x.oppTime = 0, 0
+}
+
+// feature handles the engine-specific commands advertised by "feature"
+func (x *xboard) feature(command string, args []string) {
+	arg := 0
+	if len(args) > 0 {
+		arg, _ = strconv.Atoi(args[0])
+	}
+
+	switch command {
+	case "memory":
+		if arg < 1 {
+			arg = search.DefaultHashMB
+		}
+		tb := x.engine.Tablebase
+		x.engine = search.NewEngine(x.threads, arg)
+		x.engine.Tablebase = tb
+	case "cores":
+		if arg < 1 {
+			arg = 1
+		}
+		x.threads = arg
+	case "egtpath":
+		if len(args) < 2 || args[0] != "syzygy" {
+			return
+		}
+
+		tb, err := syzygy.Open(filepath.SplitList(strings.Join(args[1:], " "))...)
+		if err != nil {
+			x.send("Error (%v): egtpath", err)
+			return
+		}
+
+		x.engine.Tablebase = tb
+	}
+}
+
+// run handles commands read from in until it is closed or "quit" is received
+func (x *xboard) run(in io.Reader) {
+	x.newGame()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, args := fields[0], fields[1:]
+
+		// commands that may interrupt the engine while it thinks
+		switch command {
+		case "?":
+			x.engine.Stop()
+			continue
+		case "post":
+			x.post = true
+			continue
+		case "nopost":
+			x.post = false
+			continue
+		case "xboard", "accepted", "rejected", "hard", "easy", "random", "computer", "name", "rating", "ics":
+			continue
+		case "quit":
+			x.abandon()
+			return
+		case "new", "force", "result", "setboard", "undo", "remove":
+			x.abandon()
+		default:
+			x.wait()
+		}
+
+		switch command {
+		case "protover":
+			x.send("feature myname=\"Chess2020\" usermove=1 setboard=1 ping=1 colors=0 sigint=0 sigterm=0 " +
+				"memory=1 smp=1 egt=\"syzygy\" variants=\"normal,fischerandom\" done=1")
+		case "new":
+			x.newGame()
+		case "force", "result":
+			x.force = true
+		case "go":
+			x.force = false
+			x.engineColor = x.board.Turn
+			x.think()
+		case "playother":
+			x.force = false
+			x.engineColor = x.board.Turn.Other()
+		case "usermove":
+			if len(args) == 0 {
+				x.send("Error (missing move): usermove")
+				continue
+			}
+			x.userMove(args[0])
+		case "level":
+			x.level(args)
+		case "st":
+			seconds, err := strconv.ParseFloat(strings.Join(args, ""), 64)
+			if err != nil {
+				x.send("Error (invalid time): st")
+				continue
+			}
+			x.moveTime = time.Duration(seconds * float64(time.Second))
+		case "sd":
+			depth, err := strconv.Atoi(strings.Join(args, ""))
+			if err != nil {
+				x.send("Error (invalid depth): sd")
+				continue
+			}
+			x.depth = depth
+		case "time", "otim":
+			centiseconds, err := strconv.Atoi(strings.Join(args, ""))
+			if err != nil {
+				x.send("Error (invalid time): %v", command)
+				continue
+			}
+
+			t := time.Duration(centiseconds) * 10 * time.Millisecond
+			if command == "time" {
+				x.engineTime = t
+			} else {
+				x.oppTime = t
+			}
+		case "setboard":
+			b, err := chess.NewBoardFromFEN(strings.Join(args, " "))
+			if err != nil {
+				x.send("tellusererror Illegal position: %v", err)
+				continue
+			}
+			x.setBoard(b)
+		case "undo":
+			x.undo(1)
+		case "remove":
+			x.undo(2)
+		case "ping":
+			x.send("pong %v", strings.Join(args, " "))
+		case "variant":
+			switch strings.Join(args, " ") {
+			case "normal":
+				x.chess960 = false
+			case "fischerandom":
+				x.chess960 = true
+			default:
+				x.send("Error (unsupported variant): %v", strings.Join(args, " "))
+				continue
+			}
+
+			start := x.start.Copy()
+			start.Chess960 = x.chess960
+			x.setBoard(start)
+		case "memory", "cores", "egtpath":
+			x.feature(command, args)
+		default:
+			// without the usermove feature, moves are sent as bare commands
+			if _, err := x.board.ParseXBoardMove(command); err == nil {
+				x.userMove(command)
+				continue
+			}
+
+			x.send("Error (unknown command): %v", command)
+		}
+	}
+
+	x.abandon()
+}
+
+func main() {
+	// the GUI reads stdout; keep log output out of it
+	log.SetOutput(os.Stderr)
+
+	x := &xboard{
+		engine:  search.NewEngine(1, search.DefaultHashMB),
+		threads: 1,
+		out:     os.Stdout,
+	}
+
+	x.run(os.Stdin)
+}
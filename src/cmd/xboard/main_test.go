@@ -0,0 +1,41 @@
+package main
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/search"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// session runs the given commands through a new xboard session, and returns the session and its output
+func session(commands ...string) (*xboard, string) {
+	var out bytes.Buffer
+	x := &xboard{
+		engine:  search.NewEngine(1, 1),
+		threads: 1,
+		out:     &out,
+	}
+
+	x.run(strings.NewReader(strings.Join(commands, "\n") + "\n"))
+	return x, out.String()
+}
+
+func TestNewAfterFischerandom(t *testing.T) {
+	x, out := session("new", "variant fischerandom", "force", "new", "force",
+		"usermove e2e4", "usermove e7e5", "usermove g1f3", "usermove b8c6", "usermove f1c4", "usermove g8f6",
+		"usermove e1g1")
+
+	if strings.Contains(out, "Illegal move") {
+		t.Fatalf("Expected all moves to be legal, but got: %v", out)
+	}
+
+	if x.board.Chess960 {
+		t.Errorf("Expected a new game to be standard chess")
+	}
+
+	g1, _ := chess.Coordinate("g1").Square()
+	if p := x.board.PieceAt(g1); p != chess.WhiteKing {
+		t.Errorf("Expected the white king to have castled to g1, but got: %v", p)
+	}
+}
@@ -0,0 +1,177 @@
+// Package pgn reads and writes games in Portable Game Notation, the text format of virtually every chess program.
+package pgn
+
+import (
+	"Chess2020/src/chess"
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maximum length of the lines of movetext
+const lineLength = 79
+
+// rosterTags are the tags every game has, in the order they are written in
+var rosterTags = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Game is a game as recorded in PGN
+type Game struct {
+	// Tags are the tag pairs of the game, e.g. "White": "Stockfish". Missing tags of the Seven Tag Roster are written
+	// as unknown; the Result, SetUp, FEN and Variant tags are derived from the other fields.
+	Tags map[string]string
+
+	// Start is the position the game started from, or nil for the starting position
+	Start *chess.Board
+
+	Moves []*chess.Move
+
+	// Outcome is the result of the game, or nil if it is still in progress
+	Outcome *chess.Outcome
+
+	// Comment, if set, is written after the last move, e.g. to tell how the game ended
+	Comment string
+}
+
+// NewGame returns the Game of the given result, played from start, or from the starting position if start is nil
+func NewGame(tags map[string]string, start *chess.Board, result *chess.GameResult) *Game {
+	return &Game{
+		Tags:    tags,
+		Start:   start,
+		Moves:   result.Moves,
+		Outcome: &result.Outcome,
+		Comment: result.Reason,
+	}
+}
+
+// result returns the result of the game as written in PGN
+func (g *Game) result() string {
+	if g.Outcome == nil {
+		return "*"
+	}
+
+	return g.Outcome.String()
+}
+
+// Write writes the game to w, followed by an empty line separating it from the next game
+func (g *Game) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, tag := range rosterTags {
+		value := g.Tags[tag]
+		switch {
+		case tag == "Result":
+			value = g.result()
+		case value == "" && tag == "Date":
+			value = "????.??.??"
+		case value == "":
+			value = "?"
+		}
+
+		writeTag(bw, tag, value)
+	}
+
+	start := g.Start
+	chess960 := start != nil && start.Chess960
+	variant := ""
+	switch {
+	case chess960:
+		variant = "Chess960"
+	case start != nil && start.Variant != nil:
+		variant = start.Variant.Name()
+	}
+	if variant != "" {
+		writeTag(bw, "Variant", variant)
+	}
+
+	// Chess960 games always give their starting position, as there are 960 of them
+	if start != nil && (start.FEN() != chess.StartFEN || chess960) {
+		writeTag(bw, "SetUp", "1")
+		writeTag(bw, "FEN", start.FEN())
+	} else if start == nil {
+		start = chess.NewBoard()
+	}
+
+	var others []string
+	for tag := range g.Tags {
+		switch {
+		case tag == "Event", tag == "Site", tag == "Date", tag == "Round", tag == "White", tag == "Black",
+			tag == "Result", tag == "SetUp", tag == "FEN", tag == "Variant" && variant != "":
+		default:
+			others = append(others, tag)
+		}
+	}
+	sort.Strings(others)
+
+	for _, tag := range others {
+		writeTag(bw, tag, g.Tags[tag])
+	}
+
+	fmt.Fprintln(bw)
+
+	tokens, err := g.movetext(start)
+	if err != nil {
+		return err
+	}
+
+	length := 0
+	for _, token := range tokens {
+		if length > 0 && length+1+len(token) > lineLength {
+			fmt.Fprintln(bw)
+			length = 0
+		}
+
+		if length > 0 {
+			fmt.Fprint(bw, " ")
+			length++
+		}
+
+		fmt.Fprint(bw, token)
+		length += len(token)
+	}
+	fmt.Fprint(bw, "\n\n")
+
+	return bw.Flush()
+}
+
+// movetext returns the tokens of the movetext of the game, played from start: move numbers, moves in SAN, the comment
+// and the result
+func (g *Game) movetext(start *chess.Board) ([]string, error) {
+	var tokens []string
+
+	b := start.Copy()
+	number := 1
+	for i, m := range g.Moves {
+		switch {
+		case b.Turn == chess.White:
+			tokens = append(tokens, fmt.Sprintf("%v.", number))
+		case i == 0:
+			tokens = append(tokens, fmt.Sprintf("%v...", number))
+		}
+
+		if err := b.CheckMove(m); err != nil {
+			return nil, fmt.Errorf("move %v: %v", i+1, err)
+		}
+
+		tokens = append(tokens, b.SAN(m))
+		b.UnsafeMove(m)
+
+		if b.Turn == chess.White {
+			number++
+		}
+	}
+
+	if g.Comment != "" {
+		// braces end comments, so they can't appear in them
+		comment := strings.NewReplacer("{", "(", "}", ")").Replace(g.Comment)
+		tokens = append(tokens, strings.Fields("{"+comment+"}")...)
+	}
+
+	return append(tokens, g.result()), nil
+}
+
+func writeTag(w io.Writer, tag, value string) {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	fmt.Fprintf(w, "[%v \"%v\"]\n", tag, value)
+}
@@ -0,0 +1,214 @@
+package pgn
+
+import (
+	"Chess2020/src/chess"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func moves(t *testing.T, b *chess.Board, ucis ...string) []*chess.Move {
+	b = b.Copy()
+
+	var ms []*chess.Move
+	for _, uci := range ucis {
+		m, err := chess.NewMoveUCI(uci, b.Turn)
+		if err == nil {
+			err = b.Move(m)
+		}
+
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		ms = append(ms, m)
+	}
+
+	return ms
+}
+
+func TestWrite(t *testing.T) {
+	outcome := chess.Outcome(chess.WhiteWon)
+	g := &Game{
+		Tags:    map[string]string{"White": "random", "Black": "search", "Round": "1", "Annotator": `a "b"`},
+		Moves:   moves(t, chess.NewBoard(), "e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"),
+		Outcome: &outcome,
+		Comment: "White won via checkmate",
+	}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "1"]
+[White "random"]
+[Black "search"]
+[Result "1-0"]
+[Annotator "a \"b\""]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# {White won via checkmate} 1-0
+
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%v\nbut got:\n%v", expected, buf.String())
+	}
+}
+
+func TestWriteFromPosition(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"
+	b, _ := chess.NewBoardFromFEN(fen)
+
+	var ms []string
+	for i := 0; i < 20; i++ {
+		ms = append(ms, "e8d8", "e1d1", "d8e8", "d1e1")
+	}
+
+	g := &Game{Start: b, Moves: moves(t, b, ms...)}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	s := buf.String()
+	if !strings.Contains(s, "[SetUp \"1\"]\n[FEN \""+fen+"\"]\n\n1... Kd8 2. Kd1 Ke8 3. Ke1") || !strings.HasSuffix(s, "*\n\n") {
+		t.Fatalf("Expected the game to start from %v, but got:\n%v", fen, s)
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		if len(line) > lineLength {
+			t.Fatalf("Expected lines of at most %v characters, but got: %q", lineLength, line)
+		}
+	}
+
+	g.Moves = append(g.Moves, g.Moves[1])
+	if err := g.Write(&buf); err == nil {
+		t.Fatalf("Expected an error writing an illegal move, but got none")
+	}
+}
+
+func TestRead(t *testing.T) {
+	db := `[Event "Casual \"game\""]
+[White "a"]
+[Black "b"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 {the bishop} Nc6 (2... Nf6 3. Ng5 $1) 3. Qh5 Nf6?? ; a blunder
+4.Qxf7# {White won via checkmate} 1-0
+
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"]
+[SetUp "1"]
+
+1... Kd8 2. 0-0-0 *
+
+1. d4 d5
+`
+
+	games, err := Read(strings.NewReader(db))
+	if err == nil {
+		t.Fatalf("Expected an error reading an illegal move, but got none")
+	}
+
+	games, err = Read(strings.NewReader(strings.Replace(db, "2. 0-0-0", "2. Kd1", 1)))
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if len(games) != 3 {
+		t.Fatalf("Expected 3 games, but got: %v", len(games))
+	}
+
+	g := games[0]
+	if g.Tags["Event"] != `Casual "game"` || len(g.Moves) != 7 || g.Outcome == nil || *g.Outcome != chess.WhiteWon ||
+		g.Comment != "White won via checkmate" || g.Start != nil {
+		t.Fatalf("Expected the first game to be read fully, but got: %+v", g)
+	}
+
+	var buf bytes.Buffer
+	g.Tags = map[string]string{"White": "a"}
+	g.Write(&buf)
+	if !strings.Contains(buf.String(), "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# {White won via checkmate} 1-0") {
+		t.Fatalf("Expected the game to be written back the same, but got:\n%v", buf.String())
+	}
+
+	g = games[1]
+	if g.Start == nil || g.Start.Turn != chess.Black || len(g.Moves) != 2 || g.Outcome != nil {
+		t.Fatalf("Expected the second game to start from its FEN, but got: %+v", g)
+	}
+
+	if g = games[2]; len(g.Moves) != 2 || g.Outcome != nil {
+		t.Fatalf("Expected the third game to have 2 moves, but got: %+v", g)
+	}
+
+	for _, db := range []string{`[Event "a`, "1. e4 {", "1. e4 (1... e5", "1. e5", `[Event]`} {
+		if _, err := Read(strings.NewReader(db)); err == nil {
+			t.Errorf("Expected an error reading %q", db)
+		}
+	}
+}
+
+func TestChess960(t *testing.T) {
+	start, _ := chess.NewBoardFromFEN("r3k2r/pppppppp/8/8/8/8/PPPPPPPP/1R3KR1 w KQkq - 0 1")
+	castle, _ := start.ParseSAN("O-O")
+	g := &Game{Start: start, Moves: []*chess.Move{castle}}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "[Variant \"Chess960\"]\n[SetUp \"1\"]\n[FEN \"r3k2r/") ||
+		!strings.Contains(buf.String(), "1. O-O *") {
+		t.Fatalf("Expected a Chess960 game, but got:\n%v", buf.String())
+	}
+
+	games, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if !games[0].Start.Chess960 || len(games[0].Moves) != 1 || games[0].Moves[0].UCI() != "f1g1" {
+		t.Fatalf("Expected the Chess960 game to be read back, but got: %+v", games[0])
+	}
+
+	// the standard starting position in Chess960
+	games, err = Read(strings.NewReader("[Variant \"Fischerandom\"]\n\n1. Nf3 Nf6 2. g3 g6 3. Bg2 Bg7 4. O-O *\n"))
+	if err != nil || !games[0].Start.Chess960 || games[0].Moves[6].UCI() != "e1h1" {
+		t.Fatalf("Expected Chess960 castling, but got: %v, %+v", err, games)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	start := chess.ThreeCheck.NewBoard()
+	e4, _ := start.ParseSAN("e4")
+	g := &Game{Start: start, Moves: []*chess.Move{e4}}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "[Variant \"Three-Check\"]\n[SetUp \"1\"]\n[FEN \"") ||
+		!strings.Contains(buf.String(), " 3+3 0 1\"]") {
+		t.Fatalf("Expected a Three-Check game, but got:\n%v", buf.String())
+	}
+
+	games, err := Read(&buf)
+	if err != nil || games[0].Start.Variant != chess.ThreeCheck || len(games[0].Moves) != 1 {
+		t.Fatalf("Expected the Three-Check game to be read back, but got: %v, %+v", err, games)
+	}
+
+	// King of the Hill starts from the standard starting position
+	games, err = Read(strings.NewReader("[Variant \"King of the Hill\"]\n\n1. e3 e6 2. Ke2 Ke7 3. Kd3 Kd6 4. Kd4 1-0\n"))
+	if err != nil || games[0].Start.Variant != chess.KingOfTheHill || len(games[0].Moves) != 7 {
+		t.Fatalf("Expected a King of the Hill game, but got: %v, %+v", err, games)
+	}
+
+	if _, err := Read(strings.NewReader("[Variant \"Bughouse\"]\n\n1. e4 *\n")); err == nil {
+		t.Errorf("Expected unsupported variants to be rejected")
+	}
+}
@@ -0,0 +1,266 @@
+package pgn
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// results maps the result tokens ending the movetext of a game to outcomes, nil being a game in progress
+var results = map[string]*chess.Outcome{
+	"1-0":     outcome(chess.WhiteWon),
+	"0-1":     outcome(chess.BlackWon),
+	"1/2-1/2": outcome(chess.Draw),
+	"*":       nil,
+}
+
+func outcome(o chess.Outcome) *chess.Outcome {
+	return &o
+}
+
+// reader reads the games of a PGN database
+type reader struct {
+	s   string
+	pos int
+
+	games []*Game
+
+	// game being read, and its current position
+	game  *Game
+	board *chess.Board
+}
+
+// Read reads all games of a PGN database. Comments, variations and annotations are skipped, except for a comment
+// after the last move of a game.
+func Read(r io.Reader) ([]*Game, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := &reader{s: string(data)}
+	for {
+		token, err := rd.next()
+		if err != nil {
+			return nil, fmt.Errorf("game %v: %v", len(rd.games)+1, err)
+		}
+
+		if token == "" {
+			break
+		}
+
+		if err := rd.handle(token); err != nil {
+			return nil, fmt.Errorf("game %v: %v", len(rd.games)+1, err)
+		}
+	}
+
+	if rd.game != nil {
+		rd.end()
+	}
+
+	return rd.games, nil
+}
+
+// handle handles a token of the database
+func (rd *reader) handle(token string) error {
+	if rd.game == nil {
+		rd.game = &Game{Tags: make(map[string]string)}
+	}
+
+	switch {
+	case token[0] == '[':
+		if rd.board != nil {
+			rd.end() // a game without a result
+			return rd.handle(token)
+		}
+
+		fields := strings.SplitN(strings.Trim(token, "[]"), " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid tag: %v", token)
+		}
+
+		value := strings.TrimSpace(fields[1])
+		if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+			return fmt.Errorf("invalid tag value: %v", token)
+		}
+
+		value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		rd.game.Tags[fields[0]] = value
+		return nil
+
+	case token[0] == '{':
+		rd.game.Comment = strings.Join(strings.Fields(strings.Trim(token, "{}")), " ")
+		return nil
+	}
+
+	if err := rd.startMoves(); err != nil {
+		return err
+	}
+
+	if o, ok := results[token]; ok {
+		rd.game.Outcome = o
+		rd.end()
+		return nil
+	}
+
+	// move numbers, possibly followed by the move without a space
+	if rest := strings.TrimLeft(token, "0123456789"); strings.HasPrefix(rest, ".") {
+		token = strings.TrimLeft(rest, ".")
+	}
+
+	if token == "" {
+		return nil
+	}
+
+	m, err := rd.board.ParseSAN(token)
+	if err != nil {
+		return fmt.Errorf("move %v: %v", len(rd.game.Moves)+1, err)
+	}
+
+	rd.board.UnsafeMove(m)
+	rd.game.Moves = append(rd.game.Moves, m)
+	rd.game.Comment = ""
+	return nil
+}
+
+// startMoves sets up the board of the game being read from its tags, when its movetext starts
+func (rd *reader) startMoves() error {
+	if rd.board != nil {
+		return nil
+	}
+
+	var variant chess.Variant
+	chess960 := false
+	switch tag := rd.game.Tags["Variant"]; strings.ToLower(strings.Replace(tag, " ", "", -1)) {
+	case "chess960", "fischerandom", "fischerrandom":
+		chess960 = true
+	default:
+		v, err := chess.VariantByName(tag)
+		if err != nil {
+			return err
+		}
+
+		variant = v
+	}
+
+	rd.board = chess.NewBoard()
+	if fen, ok := rd.game.Tags["FEN"]; ok {
+		b, err := chess.NewVariantBoardFromFEN(fen, variant)
+		if err != nil {
+			return fmt.Errorf("invalid FEN tag: %v", err)
+		}
+
+		rd.game.Start = b
+	} else if variant != nil {
+		rd.game.Start = variant.NewBoard()
+	}
+
+	if chess960 {
+		if rd.game.Start == nil {
+			rd.game.Start = chess.NewBoard()
+		}
+		rd.game.Start.Chess960 = true
+	}
+
+	if rd.game.Start != nil {
+		rd.board = rd.game.Start.Copy()
+	}
+
+	return nil
+}
+
+// end ends the game being read
+func (rd *reader) end() {
+	rd.games = append(rd.games, rd.game)
+	rd.game = nil
+	rd.board = nil
+}
+
+// next returns the next token of the database that isn't skipped, or an empty string at its end. Tags and comments
+// are returned as single tokens, with their brackets or braces.
+func (rd *reader) next() (string, error) {
+	depth := 0 // of variations
+	for rd.pos < len(rd.s) {
+		c := rd.s[rd.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			rd.pos++
+			continue
+
+		case c == ';' || c == '%' && (rd.pos == 0 || rd.s[rd.pos-1] == '\n'):
+			// comments and escaped lines run to the end of the line
+			end := strings.IndexByte(rd.s[rd.pos:], '\n')
+			if end < 0 {
+				end = len(rd.s) - rd.pos
+			}
+			rd.pos += end
+			continue
+
+		case c == '[' || c == '{':
+			closing := map[byte]byte{'[': ']', '{': '}'}[c]
+			end := rd.closing(closing)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated %q", c)
+			}
+
+			token := rd.s[rd.pos : end+1]
+			rd.pos = end + 1
+			if depth > 0 {
+				continue
+			}
+
+			return token, nil
+
+		case c == '(':
+			depth++
+			rd.pos++
+			continue
+
+		case c == ')':
+			if depth == 0 {
+				return "", fmt.Errorf("unexpected ')'")
+			}
+			depth--
+			rd.pos++
+			continue
+		}
+
+		start := rd.pos
+		for rd.pos < len(rd.s) && !strings.ContainsRune(" \t\r\n;[]{}()", rune(rd.s[rd.pos])) {
+			rd.pos++
+		}
+
+		token := rd.s[start:rd.pos]
+		if depth > 0 || token[0] == '$' {
+			continue // moves of variations, and numeric annotation glyphs
+		}
+
+		return token, nil
+	}
+
+	if depth > 0 {
+		return "", fmt.Errorf("unterminated variation")
+	}
+
+	return "", nil
+}
+
+// closing returns the index of the character ending the tag or comment at the current position, skipping quoted
+// values of tags, or -1 if there is none
+func (rd *reader) closing(c byte) int {
+	quoted := false
+	for i := rd.pos + 1; i < len(rd.s); i++ {
+		switch {
+		case c == ']' && rd.s[i] == '\\':
+			i++
+		case c == ']' && rd.s[i] == '"':
+			quoted = !quoted
+		case rd.s[i] == c && !quoted:
+			return i
+		}
+	}
+
+	return -1
+}
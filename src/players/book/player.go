@@ -0,0 +1,84 @@
+package book
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/polyglot"
+)
+
+// BookPlayer plays moves from a Polyglot opening book for as long as the position is in the book, then hands the
+// rest of the game over to an inner Player
+type BookPlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	Book  *polyglot.Book
+	Inner chess.Player
+
+	// prompts forwarded to Inner once out of book; nil while still in book
+	innerPrompt chan chess.Prompt
+}
+
+func (bp *BookPlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	bp.Color = c
+	bp.Prompt = prompt
+	bp.Move = move
+	bp.GameClient = gc
+
+	bp.Board = gc.GetBoard()
+	bp.innerPrompt = nil
+}
+
+func (bp *BookPlayer) Run() {
+	for {
+		// Wait for our turn
+		p, ok := <-bp.Prompt
+		if !ok {
+			if bp.innerPrompt != nil {
+				close(bp.innerPrompt)
+			}
+			return
+		}
+
+		if bp.innerPrompt != nil {
+			bp.innerPrompt <- p
+			continue
+		}
+
+		if p.OppMove != nil {
+			bp.Board.UnsafeMove(p.OppMove)
+		}
+
+		m, err := bp.Book.Pick(bp.Board)
+		if err != nil || m == nil {
+			bp.leaveBook()
+			continue
+		}
+
+		bp.Board.UnsafeMove(m)
+
+		// Send move
+		bp.Move <- m
+	}
+}
+
+// leaveBook starts the inner player from the current position and prompts it for the move the book couldn't provide
+func (bp *BookPlayer) leaveBook() {
+	bp.innerPrompt = make(chan chess.Prompt)
+	bp.Inner.Init(bp.Color, bp.GameClient, bp.innerPrompt, bp.Move)
+	go bp.Inner.Run()
+
+	// the inner player's board already includes the opponent's last move
+	bp.innerPrompt <- chess.Prompt{}
+}
+
+// Player returns a BookPlayer consulting book before delegating to inner
+func Player(book *polyglot.Book, inner chess.Player) *BookPlayer {
+	return &BookPlayer{
+		Book:  book,
+		Inner: inner,
+	}
+}
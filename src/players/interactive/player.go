@@ -2,16 +2,18 @@ package interactive
 
 import (
 	"Chess2020/src/chess"
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 var alphabet = "abcdefgh"
 var digits = "12345678"
 var pieces = "NQBRnqbr"
 
+// InteractivePlayer lets a human play at the terminal, on a full-screen board with clocks and the list of moves. Moves
+// and commands are typed on the command line at the bottom of the screen.
 type InteractivePlayer struct {
 	Color      chess.Color
 	GameClient chess.GameClient
@@ -19,6 +21,23 @@ type InteractivePlayer struct {
 	Move       chan *chess.Move
 
 	Board *chess.Board
+
+	// Render holds the options the board is drawn with, from the side of the player by default
+	Render chess.RenderOptions
+
+	// start is the board the game started from, moves are the moves played since, and sans their SAN
+	start *chess.Board
+	moves []*chess.Move
+	sans  []string
+
+	// selected is the square typed last, whose legal moves are shown, or 0
+	selected chess.Square
+
+	// status is the message shown above the command line, e.g. the error of the last command
+	status string
+
+	// drawn is set once the screen was drawn
+	drawn bool
 }
 
 func (ip *InteractivePlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
@@ -28,14 +47,16 @@ func (ip *InteractivePlayer) Init(c chess.Color, gc chess.GameClient, prompt cha
 	ip.GameClient = gc
 
 	ip.Board = gc.GetBoard()
-}
+	ip.Render.Perspective = c
+	ip.Render.LastMove = nil
+	ip.Render.Hints = nil
 
-func (ip *InteractivePlayer) readInput(r *bufio.Reader) string {
-	fmt.Print("\n>$ ")
-	text, _ := r.ReadString('\n')
-	// convert CRLF to LF
-	text = strings.Replace(text, "\n", "", -1)
-	return text
+	ip.start = ip.Board.Copy()
+	ip.moves = nil
+	ip.sans = nil
+	ip.selected = 0
+	ip.status = ""
+	ip.drawn = false
 }
 
 func validateCoord(c string) error {
@@ -88,8 +109,26 @@ func validatePromotionPiece(p string) (chess.Piece, error) {
 func (ip *InteractivePlayer) parseInput(inp string) (*chess.Move, error) {
 	f := strings.Fields(inp)
 
+	// drops are written like in UCI, e.g. N@f7
+	if len(f) == 1 && strings.Contains(f[0], "@") {
+		return chess.NewMoveUCI(strings.ToUpper(f[0][:1])+f[0][1:], ip.Color)
+	}
+
+	// single moves are in SAN, e.g. Nf3, or in UCI, e.g. g1f3
+	if len(f) == 1 {
+		if m, err := ip.Board.ParseSAN(f[0]); err == nil {
+			return m, nil
+		}
+
+		if m, err := chess.NewMoveUCI(f[0], ip.Color); err == nil {
+			return m, nil
+		}
+
+		return nil, fmt.Errorf("not a move in SAN or UCI: %v", f[0])
+	}
+
 	if len(f) != 2 && len(f) != 3 {
-		return nil, fmt.Errorf("expected exactly 2 or 3 fields")
+		return nil, fmt.Errorf("expected exactly 1 to 3 fields")
 	}
 
 	var c1, c2 string
@@ -115,47 +154,276 @@ func (ip *InteractivePlayer) parseInput(inp string) (*chess.Move, error) {
 	return chess.NewMoveCoordPromotion(chess.Coordinate(c1), chess.Coordinate(c2), p)
 }
 
+// help is the status shown by the help command
+const help = "Moves: SAN (Nf3), coordinates (g1f3 or g1 f3), drops (N@f7). A square (g1) shows its legal moves, " +
+	"the next square moves there. Commands: flip, draw, undo, resign."
+
 func (ip *InteractivePlayer) Run() {
-	fmt.Printf("Interactive Player [%v] started\n", ip.Color)
+	atomic.AddInt32(&running, 1)
+	defer atomic.AddInt32(&running, -1)
+
+	ticker := time.NewTicker(clockInterval)
+	defer ticker.Stop()
 
-	reader := bufio.NewReader(os.Stdin)
 	for {
-		// Wait for our turn
-		p := <-ip.Prompt
-		if p.OppMove != nil {
-			// fmt.Printf("Interactive Player [%v] Opponent made move: %v\n", ip.Color, p.OppMove.String())
-			ip.Board.UnsafeMove(p.OppMove)
-		}
+		select {
+		case p, ok := <-ip.Prompt:
+			if !ok {
+				ip.catchUp()
+				ip.status = fmt.Sprintf("Game over, Interactive Player [%v] leaves", ip.Color)
+				ip.draw()
+				fmt.Println()
+				return
+			}
 
-		var m *chess.Move
-		var err error
-		for {
-			fmt.Println()
-			fmt.Printf("Interactive Player [%v] to move...\n", ip.Color)
-			fmt.Println()
-			fmt.Println(ip.Board)
-
-			// Get move from CLI arg
-			inp := ip.readInput(reader)
-			if m, err = ip.parseInput(inp); err != nil {
-				fmt.Printf("Could not parse move. Try again: %v\n", err)
-				continue
+			if p.OppMove != nil {
+				ip.record(p.OppMove)
 			}
 
-			// Make move on the board
-			if err := ip.Board.Move(m); err != nil {
-				fmt.Printf("Invalid move: %v\n", err)
+			ip.play(ticker)
+			if alone() {
+				ip.draw()
+			}
+		case <-ticker.C:
+			// while waiting for the opponent, the screen is only kept up to date by a player that doesn't share it
+			if !alone() {
 				continue
 			}
 
-			break
+			if ip.drawn {
+				ip.drawClocks()
+			} else {
+				ip.draw()
+			}
 		}
+	}
+}
 
-		// Send move
-		ip.Move <- m
+// play reads commands until the player moved, or resigned or agreed to a draw, keeping the clocks up to date
+func (ip *InteractivePlayer) play(ticker *time.Ticker) {
+	ip.draw()
+	for {
+		select {
+		case line, ok := <-input():
+			if !ok {
+				// nobody is left to play
+				ip.Move <- nil
+				return
+			}
+
+			if ip.command(line) {
+				return
+			}
+
+			ip.draw()
+		case <-ip.Prompt:
+			// the game ended on the player's turn, e.g. as it ran out of time
+			return
+		case <-ticker.C:
+			ip.drawClocks()
+		}
 	}
 }
 
+// command executes a line typed on the player's turn, a move or another command, and returns true iff its turn is over
+func (ip *InteractivePlayer) command(line string) bool {
+	selected := ip.selected
+	ip.selected = 0
+	ip.status = ""
+	ip.Render.Hints = nil
+
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(f[0]) {
+	case "help":
+		ip.status = help
+		return false
+	case "flip":
+		ip.Render.Perspective = ip.Render.Perspective.Other()
+		return false
+	case "resign":
+		if n, ok := ip.GameClient.(chess.Negotiator); ok {
+			n.Resign(ip.Color)
+		} else {
+			ip.Move <- nil
+		}
+
+		return true
+	case "draw":
+		if ip.offer(chess.DrawOffer) {
+			ip.status = "Draw offer accepted"
+			return true
+		}
+
+		return false
+	case "undo":
+		if ip.offer(chess.UndoOffer) {
+			ip.undo(2)
+			ip.status = "Undo offer accepted"
+		}
+
+		return false
+	}
+
+	var m *chess.Move
+	if len(f) == 1 && validateCoord(f[0]) == nil {
+		if m = ip.selectSquare(chess.Coordinate(f[0]), selected); m == nil {
+			return false
+		}
+	} else {
+		var err error
+		if m, err = ip.parseInput(line); err != nil {
+			ip.status = fmt.Sprintf("Could not parse move. Try again: %v", err)
+			return false
+		}
+	}
+
+	if err := ip.Board.CheckMove(m); err != nil {
+		ip.status = fmt.Sprintf("Invalid move: %v", err)
+		return false
+	}
+
+	ip.record(m)
+	ip.Move <- m
+	return true
+}
+
+// offer makes an offer to the opponent, and returns true iff it accepted it
+func (ip *InteractivePlayer) offer(o chess.Offer) bool {
+	n, ok := ip.GameClient.(chess.Negotiator)
+	if !ok {
+		ip.status = "Offers can't be made in this game"
+		return false
+	}
+
+	ip.status = fmt.Sprintf("Waiting for the opponent to answer the %v offer...", o)
+	ip.draw()
+
+	if !n.Offer(ip.Color, o) {
+		ip.status = fmt.Sprintf("The %v offer was declined", o)
+		return false
+	}
+
+	return true
+}
+
+// AcceptOffer asks the player whether to accept the offer of its opponent, who plays at the same terminal
+func (ip *InteractivePlayer) AcceptOffer(o chess.Offer) bool {
+	ip.ask(fmt.Sprintf("Interactive Player [%v], accept the %v offer of your opponent? [y/N] ", ip.Color, o))
+
+	answer := strings.ToLower(strings.TrimSpace(<-input()))
+	if answer != "y" && answer != "yes" {
+		return false
+	}
+
+	if o == chess.UndoOffer {
+		ip.undo(2)
+	}
+
+	return true
+}
+
+// selectSquare returns the move a typed square stands for: the move of the piece on the square selected before to it,
+// promoting to a queen, or the pawn move it is the SAN of, e.g. e4. Otherwise, it selects the square and shows its legal
+// moves, and returns nil.
+func (ip *InteractivePlayer) selectSquare(c chess.Coordinate, selected chess.Square) *chess.Move {
+	square, _ := c.Square()
+
+	for _, m := range ip.Board.LegalMoves() {
+		queen := m.Promotion == chess.EmptyPiece || m.Promotion == chess.WhiteQueen || m.Promotion == chess.BlackQueen
+		if selected != 0 && m.From == selected && m.To == square && queen {
+			return m
+		}
+	}
+
+	if p := ip.Board.PieceAt(square); p == chess.EmptyPiece || p.Color() != ip.Color {
+		if m, err := ip.Board.ParseSAN(string(c)); err == nil {
+			return m
+		}
+	}
+
+	ip.selected = square
+	ip.hint(c)
+	return nil
+}
+
+// hint shows the legal moves of the piece on the given square, or the legal drops onto it
+func (ip *InteractivePlayer) hint(c chess.Coordinate) {
+	square, _ := c.Square()
+
+	var sans []string
+	for _, m := range ip.Board.LegalMoves() {
+		switch {
+		case m.From == square:
+			ip.Render.Hints = append(ip.Render.Hints, m.To)
+		case m.IsDrop() && m.To == square:
+			ip.Render.Hints = append(ip.Render.Hints, m.To)
+		default:
+			continue
+		}
+
+		sans = append(sans, ip.Board.SAN(m))
+	}
+
+	if len(sans) == 0 {
+		ip.status = fmt.Sprintf("No legal moves from or onto %v", c)
+		return
+	}
+
+	ip.status = fmt.Sprintf("%v: %v", c, strings.Join(sans, " "))
+}
+
+// record plays a legal move on the board of the player
+func (ip *InteractivePlayer) record(m *chess.Move) {
+	ip.sans = append(ip.sans, ip.Board.SAN(m))
+	ip.moves = append(ip.moves, m)
+	ip.Board.UnsafeMove(m)
+	ip.Render.LastMove = m
+}
+
+// undo takes back the last n moves, by playing the moves before them again from the start
+func (ip *InteractivePlayer) undo(n int) {
+	ip.moves = ip.moves[:len(ip.moves)-n]
+	ip.sans = ip.sans[:len(ip.sans)-n]
+
+	ip.Board = ip.start.Copy()
+	ip.Render.LastMove = nil
+	for _, m := range ip.moves {
+		ip.Board.UnsafeMove(m)
+		ip.Render.LastMove = m
+	}
+}
+
+// catchUp records the move that ended the game, which players aren't prompted with
+func (ip *InteractivePlayer) catchUp() {
+	final := ip.GameClient.GetBoard()
+	if final.Hash() == ip.Board.Hash() {
+		return
+	}
+
+	for _, m := range ip.Board.LegalMoves() {
+		b := ip.Board.Copy()
+		b.UnsafeMove(m)
+		if b.Hash() == final.Hash() {
+			ip.record(m)
+			return
+		}
+	}
+
+	ip.Board = final
+}
+
+// Player returns an InteractivePlayer drawing the board with Unicode glyphs, colors and coordinates
 func Player() *InteractivePlayer {
-	return &InteractivePlayer{}
+	return &InteractivePlayer{
+		Render: chess.RenderOptions{
+			Unicode:     true,
+			Color:       true,
+			Coordinates: true,
+			Check:       true,
+		},
+	}
 }
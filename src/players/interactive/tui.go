@@ -0,0 +1,175 @@
+package interactive
+
+import (
+	"Chess2020/src/chess"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Layout of the screen, in rows from the top and columns from the left, starting at 1. The clock of the player at the
+// top of the board is on the first row, and the clock of the other player right below the board.
+const (
+	boardRow    = 2
+	statusRow   = 13
+	promptRow   = 14
+	movesColumn = 32
+
+	// movesShown is the number of full moves shown in the move list, the last ones
+	movesShown = 10
+
+	// clockWidth is the width of a clock, as wide as a board with coordinates
+	clockWidth = 26
+)
+
+// clockInterval is how often the clocks are redrawn
+const clockInterval = 100 * time.Millisecond
+
+// ANSI escape codes to control the terminal
+const (
+	clearScreen  = "\x1b[2J"
+	clearBelow   = "\x1b[J"
+	saveCursor   = "\x1b7"
+	returnCursor = "\x1b8"
+)
+
+var (
+	// running is the number of interactive players running in this process. Players sharing the terminal, e.g. when
+	// both sides play at the same one, only draw on their turn.
+	running int32
+
+	// lines are the lines read from the standard input, shared by all interactive players
+	lines     chan string
+	linesOnce sync.Once
+)
+
+// alone returns true iff this is the only interactive player using the terminal
+func alone() bool {
+	return atomic.LoadInt32(&running) == 1
+}
+
+// input returns the channel of lines read from the standard input, which is closed at its end
+func input() <-chan string {
+	linesOnce.Do(func() {
+		lines = make(chan string)
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				// convert CRLF to LF
+				lines <- strings.TrimSuffix(scanner.Text(), "\r")
+			}
+
+			close(lines)
+		}()
+	})
+
+	return lines
+}
+
+// moveTo returns the ANSI escape code moving the cursor to the given row and column
+func moveTo(row, column int) string {
+	return fmt.Sprintf("\x1b[%d;%dH", row, column)
+}
+
+// draw draws the whole screen: the board between the clocks, the move list, the status and the command line
+func (ip *InteractivePlayer) draw() {
+	var sb strings.Builder
+	sb.WriteString(clearScreen)
+
+	board := strings.Split(strings.TrimSuffix(ip.Board.Render(ip.Render), "\n"), "\n")
+	for i, line := range board {
+		sb.WriteString(moveTo(boardRow+i, 1) + line)
+	}
+	sb.WriteString(ip.clocks())
+
+	moves := ip.moveList()
+	if len(moves) > movesShown {
+		moves = moves[len(moves)-movesShown:]
+	}
+
+	sb.WriteString(moveTo(1, movesColumn) + "Moves")
+	for i, line := range moves {
+		sb.WriteString(moveTo(boardRow+i, movesColumn) + line)
+	}
+
+	sb.WriteString(moveTo(statusRow, 1) + ip.status)
+	sb.WriteString(moveTo(promptRow, 1) + "> ")
+
+	fmt.Print(sb.String())
+	ip.drawn = true
+}
+
+// drawClocks redraws the clocks only, leaving the cursor where it is, e.g. after a partially typed command
+func (ip *InteractivePlayer) drawClocks() {
+	fmt.Print(saveCursor + ip.clocks() + returnCursor)
+}
+
+// ask shows a question below the command line, to be answered on the next line of input
+func (ip *InteractivePlayer) ask(question string) {
+	fmt.Print(moveTo(promptRow+1, 1) + clearBelow + question)
+}
+
+// clocks returns the clocks of both players, drawn above and below the board
+func (ip *InteractivePlayer) clocks() string {
+	height := 8
+	if ip.Render.Coordinates {
+		height++
+	}
+
+	top, bottom := ip.Render.Perspective.Other(), ip.Render.Perspective
+	return moveTo(1, 1) + ip.clock(top) + moveTo(boardRow+height, 1) + ip.clock(bottom)
+}
+
+// clock returns the clock of player c, marked when it is its turn, as wide as the board
+func (ip *InteractivePlayer) clock(c chess.Color) string {
+	marker := " "
+	if ip.Board.Turn == c {
+		marker = "*"
+	}
+
+	return fmt.Sprintf("%v %-*v%v", marker, clockWidth-12, c, formatTimeLeft(ip.GameClient, c))
+}
+
+// formatTimeLeft returns the time player c has left, e.g. 0:04:59, with tenths of seconds under 10 seconds, e.g.
+// 0:00:09.5, padded to 10 characters
+func formatTimeLeft(gc chess.GameClient, c chess.Color) string {
+	if _, ok := gc.GetTimeControl().(chess.InfiniteTime); ok {
+		return fmt.Sprintf("%10v", "-:--:--")
+	}
+
+	d := gc.GetTimeLeft(c)
+	if d < 0 {
+		d = 0
+	}
+
+	h, m, s := int(d/time.Hour), int(d/time.Minute)%60, int(d/time.Second)%60
+	if d >= 10*time.Second {
+		return fmt.Sprintf("%10v", fmt.Sprintf("%d:%02d:%02d", h, m, s))
+	}
+
+	return fmt.Sprintf("%10v", fmt.Sprintf("%d:%02d:%02d.%d", h, m, s, int(d/(100*time.Millisecond))%10))
+}
+
+// moveList returns the moves played in SAN, one full move per line, e.g. "12. Nf3     Nc6"
+func (ip *InteractivePlayer) moveList() []string {
+	sans := ip.sans
+	if ip.start.Turn == chess.Black {
+		sans = append([]string{"..."}, sans...)
+	}
+
+	var moves []string
+	for i := 0; i < len(sans); i += 2 {
+		line := fmt.Sprintf("%3d. %-8v", i/2+1, sans[i])
+		if i+1 < len(sans) {
+			line += sans[i+1]
+		}
+
+		moves = append(moves, line)
+	}
+
+	return moves
+}
@@ -0,0 +1,351 @@
+package lichess
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bot plays games of a bot account through the Bot API of Lichess. It isn't a chess.Player itself: the thinking is
+// left to inner Players, each game getting its own, prompted for a move whenever the bot has to move, with the clocks
+// of the Lichess game.
+type Bot struct {
+	Client *Client
+
+	// NewInner returns the Player thinking for the bot in a new game
+	NewInner func() chess.Player
+
+	// AcceptChallenges makes the bot accept all challenges to games of supported variants, and decline the others
+	AcceptChallenges bool
+
+	// ID of the bot account, looked up on first use
+	accountID string
+}
+
+// Listen plays every game the bot is in, as they start, until the stream of incoming events ends
+func (bot *Bot) Listen() error {
+	if err := bot.lookUpAccount(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	return bot.Client.StreamEvents(func(e *Event) bool {
+		switch {
+		case e.Type == "challenge" && e.Challenge != nil && bot.AcceptChallenges:
+			_, _, err := variant(e.Challenge.Variant.Key)
+			if err == nil {
+				err = bot.Client.AcceptChallenge(e.Challenge.ID)
+			} else {
+				err = bot.Client.DeclineChallenge(e.Challenge.ID)
+			}
+
+			if err != nil {
+				log.Printf("Could not answer challenge %v: %v", e.Challenge.ID, err)
+			}
+		case e.Type == "gameStart" && e.Game != nil:
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+
+				if err := bot.PlayGame(id); err != nil {
+					log.Printf("Game %v failed: %v", id, err)
+				}
+			}(e.Game.ID)
+		}
+
+		return true
+	})
+}
+
+// PlayGame plays the game with the given ID until it is over
+func (bot *Bot) PlayGame(id string) error {
+	if err := bot.lookUpAccount(); err != nil {
+		return err
+	}
+
+	g := &game{
+		id:       id,
+		client:   bot.Client,
+		inner:    bot.NewInner(),
+		prompted: -1,
+	}
+	defer g.close()
+
+	var err error
+	streamErr := bot.Client.StreamGame(id, func(e *GameEvent) bool {
+		switch e.Type {
+		case "gameFull":
+			if e.State == nil {
+				err = fmt.Errorf("gameFull event without a state")
+				return false
+			}
+
+			if err = g.start(e, bot.accountID); err != nil {
+				return false
+			}
+
+			return g.update(e.State)
+		case "gameState":
+			if g.board == nil {
+				err = fmt.Errorf("gameState event before gameFull")
+				return false
+			}
+
+			return g.update(&e.GameState)
+		default:
+			return true
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return streamErr
+}
+
+// lookUpAccount looks up the ID of the bot account, if not known yet
+func (bot *Bot) lookUpAccount() error {
+	if bot.accountID != "" {
+		return nil
+	}
+
+	accountID, err := bot.Client.AccountID()
+	if err != nil {
+		return err
+	}
+
+	bot.accountID = accountID
+	return nil
+}
+
+// game is a Lichess game played by an inner Player. It is the GameClient of the inner Player.
+type game struct {
+	id     string
+	client *Client
+	inner  chess.Player
+
+	color chess.Color
+
+	// prompt and move are the channels of the inner Player, nil until it is started
+	prompt chan chess.Prompt
+	move   chan *chess.Move
+	done   chan struct{}
+
+	mu sync.Mutex
+
+	// board is the current position, reached by playing the moves seen so far from the initial position
+	board *chess.Board
+	moves int
+
+	// number of moves played when the inner Player was last prompted, or -1
+	prompted int
+
+	// clocks as of the last state received, at received
+	whiteTime time.Duration
+	blackTime time.Duration
+	inc       time.Duration
+	received  time.Time
+}
+
+// start sets up the game from its full description
+func (g *game) start(e *GameEvent, accountID string) error {
+	switch {
+	case strings.EqualFold(e.White.ID, accountID):
+		g.color = chess.White
+	case strings.EqualFold(e.Black.ID, accountID):
+		g.color = chess.Black
+	default:
+		return fmt.Errorf("bot %v doesn't play in game %v", accountID, g.id)
+	}
+
+	v, chess960, err := variant(e.Variant.Key)
+	if err != nil {
+		return err
+	}
+
+	b := chess.NewBoard()
+	if v != nil {
+		b = v.NewBoard()
+	}
+
+	if e.InitialFEN != "" && e.InitialFEN != "startpos" {
+		if b, err = chess.NewVariantBoardFromFEN(e.InitialFEN, v); err != nil {
+			return err
+		}
+	}
+	b.Chess960 = b.Chess960 || chess960
+
+	g.mu.Lock()
+	g.board = b
+	g.mu.Unlock()
+
+	return nil
+}
+
+// variant returns the variant of the given Lichess variant key, nil for standard chess, and whether it is Chess960.
+// Returns an error if the variant isn't supported.
+func variant(key string) (chess.Variant, bool, error) {
+	switch key {
+	case "", "standard", "fromPosition":
+		return nil, false, nil
+	case "chess960":
+		return nil, true, nil
+	default:
+		// keys are the names of variants in camel case, e.g. kingOfTheHill
+		v, err := chess.VariantByName(key)
+		return v, false, err
+	}
+}
+
+// update catches up with the given state of the game, prompting the inner Player if the bot has to move. Returns
+// false once the game is over.
+func (g *game) update(s *GameState) bool {
+	moves := strings.Fields(s.Moves)
+
+	g.mu.Lock()
+	if len(moves) < g.moves {
+		g.mu.Unlock()
+		log.Printf("Game %v took back moves, which is not supported", g.id)
+		return false
+	}
+
+	var last *chess.Move
+	for _, uci := range moves[g.moves:] {
+		m, err := chess.NewMoveUCI(uci, g.board.Turn)
+		if err == nil {
+			err = g.board.Move(m)
+		}
+
+		if err != nil {
+			g.mu.Unlock()
+			log.Printf("Game %v has an invalid move %v: %v", g.id, uci, err)
+			return false
+		}
+
+		last = m
+		g.moves++
+	}
+
+	g.whiteTime = time.Duration(s.WhiteTime) * time.Millisecond
+	g.blackTime = time.Duration(s.BlackTime) * time.Millisecond
+	g.inc = time.Duration(s.WhiteInc) * time.Millisecond
+	if g.color == chess.Black {
+		g.inc = time.Duration(s.BlackInc) * time.Millisecond
+	}
+	g.received = time.Now()
+
+	toMove := g.board.Turn == g.color && len(g.board.LegalMoves()) > 0
+	g.mu.Unlock()
+
+	if s.Status != "" && s.Status != "started" && s.Status != "created" {
+		return false
+	}
+
+	// states are also sent for other reasons than moves, e.g. draw offers; prompt only once per position
+	if !toMove || g.prompted == g.moves {
+		return true
+	}
+	g.prompted = g.moves
+
+	if g.prompt == nil {
+		// the inner Player starts from the current position, so there is no opponent move to tell it about
+		g.run()
+		last = nil
+	}
+
+	g.prompt <- chess.Prompt{OppMove: last}
+	return true
+}
+
+// run starts the inner Player, and posts the moves it sends
+func (g *game) run() {
+	g.prompt = make(chan chess.Prompt, 1)
+	g.move = make(chan *chess.Move, 1)
+	g.done = make(chan struct{})
+
+	g.inner.Init(g.color, g, g.prompt, g.move)
+	go g.inner.Run()
+
+	go func() {
+		for {
+			select {
+			case m := <-g.move:
+				if m == nil {
+					continue
+				}
+
+				if err := g.client.Move(g.id, m.UCI()); err != nil {
+					log.Printf("Could not play %v in game %v: %v", m.UCI(), g.id, err)
+				}
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+// close stops the inner Player, if started
+func (g *game) close() {
+	if g.prompt != nil {
+		close(g.prompt)
+		close(g.done)
+	}
+}
+
+func (g *game) GetBoard() *chess.Board {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.board.Copy()
+}
+
+func (g *game) GetTimeLeft(c chess.Color) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	left := g.whiteTime
+	if c == chess.Black {
+		left = g.blackTime
+	}
+
+	if c == g.board.Turn {
+		left -= time.Since(g.received)
+	}
+
+	return left
+}
+
+func (g *game) GetTimeControl() chess.TimeControl {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return timeControl{inc: g.inc}
+}
+
+// timeControl is the time control of a Lichess game, as far as it is known from its state
+type timeControl struct {
+	inc time.Duration
+}
+
+func (tc timeControl) InitialTime() time.Duration {
+	return 0
+}
+
+func (tc timeControl) Increment() time.Duration {
+	return tc.inc
+}
+
+// NewBot returns a Bot playing through the API at baseURL on behalf of the bot with the given token, and
+// thinking with the Players returned by newInner
+func NewBot(baseURL, token string, newInner func() chess.Player) *Bot {
+	return &Bot{
+		Client:   NewClient(baseURL, token),
+		NewInner: newInner,
+	}
+}
@@ -0,0 +1,218 @@
+package lichess
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLichess implements enough of the Bot API to play a single game, in which the opponent of the bot plays the first
+// legal move of every position, until maxMoves moves are played
+type fakeLichess struct {
+	botColor chess.Color
+	maxMoves int
+
+	mu       sync.Mutex
+	board    *chess.Board
+	moves    []string
+	accepted bool
+	illegal  []string
+
+	// receives a value whenever the bot moves
+	botMoved chan struct{}
+	over     chan struct{}
+}
+
+func newFakeLichess(botColor chess.Color, maxMoves int) *fakeLichess {
+	return &fakeLichess{
+		botColor: botColor,
+		maxMoves: maxMoves,
+		board:    chess.NewBoard(),
+		botMoved: make(chan struct{}, 1),
+		over:     make(chan struct{}),
+	}
+}
+
+// opponentMoves plays the moves of the opponent until it's the bot's turn. Must be called with f.mu held.
+func (f *fakeLichess) opponentMoves() {
+	for f.board.Turn != f.botColor && len(f.moves) < f.maxMoves {
+		moves := f.board.LegalMoves()
+		if len(moves) == 0 {
+			return
+		}
+
+		f.board.UnsafeMove(moves[0])
+		f.moves = append(f.moves, moves[0].UCI())
+	}
+}
+
+// state returns the state of the game. Must be called with f.mu held.
+func (f *fakeLichess) state() *GameState {
+	status := "started"
+	if len(f.moves) >= f.maxMoves || len(f.board.LegalMoves()) == 0 {
+		status = "outoftime"
+	}
+
+	return &GameState{
+		Moves:     strings.Join(f.moves, " "),
+		WhiteTime: 60000,
+		BlackTime: 60000,
+		WhiteInc:  1000,
+		BlackInc:  1000,
+		Status:    status,
+	}
+}
+
+func (f *fakeLichess) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher := w.(http.Flusher)
+	send := func(v interface{}) {
+		line, _ := json.Marshal(v)
+		fmt.Fprintf(w, "%s\n\n", line) // with an empty keep-alive line
+		flusher.Flush()
+	}
+
+	white, black := GamePlayer{ID: "opponent"}, GamePlayer{ID: "TestBot"}
+	if f.botColor == chess.White {
+		white, black = black, white
+	}
+
+	switch {
+	case r.URL.Path == "/api/account":
+		send(map[string]string{"id": "testbot"})
+
+	case r.URL.Path == "/api/stream/event":
+		send(&Event{Type: "challenge", Challenge: &Challenge{ID: "c1", Variant: Variant{Key: "standard"}}})
+		send(&Event{Type: "gameStart", Game: &EventGame{ID: "g1"}})
+		<-f.over
+
+	case r.URL.Path == "/api/challenge/c1/accept":
+		f.mu.Lock()
+		f.accepted = true
+		f.mu.Unlock()
+
+	case r.URL.Path == "/api/bot/game/stream/g1":
+		f.mu.Lock()
+		f.opponentMoves()
+		state := f.state()
+		f.mu.Unlock()
+
+		send(&GameEvent{Type: "gameFull", ID: "g1", White: white, Black: black, InitialFEN: "startpos", State: state})
+		send(&GameEvent{Type: "chatLine"})
+		for state.Status == "started" {
+			<-f.botMoved
+
+			f.mu.Lock()
+			f.opponentMoves()
+			state = f.state()
+			f.mu.Unlock()
+
+			send(&GameEvent{Type: "gameState", GameState: *state})
+		}
+		close(f.over)
+
+	case strings.HasPrefix(r.URL.Path, "/api/bot/game/g1/move/"):
+		uci := strings.TrimPrefix(r.URL.Path, "/api/bot/game/g1/move/")
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		m, err := chess.NewMoveUCI(uci, f.board.Turn)
+		if err == nil && f.board.Turn != f.botColor {
+			err = fmt.Errorf("not your turn")
+		}
+		if err == nil {
+			err = f.board.Move(m)
+		}
+
+		if err != nil {
+			f.illegal = append(f.illegal, uci)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.moves = append(f.moves, uci)
+		f.botMoved <- struct{}{}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestListen(t *testing.T) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		f := newFakeLichess(c, 20)
+		ts := httptest.NewServer(f)
+
+		bot := NewBot(ts.URL, "secret", func() chess.Player { return random.Player() })
+		bot.AcceptChallenges = true
+
+		if err := bot.Listen(); err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+		ts.Close()
+
+		if !f.accepted {
+			t.Fatalf("Expected the challenge to be accepted")
+		}
+
+		if len(f.illegal) > 0 {
+			t.Fatalf("Expected only legal moves, but got: %v", f.illegal)
+		}
+
+		if len(f.moves) != 20 && len(f.board.LegalMoves()) > 0 {
+			t.Fatalf("Expected the game to be played to its end, but got: %v moves", len(f.moves))
+		}
+	}
+}
+
+func TestBadToken(t *testing.T) {
+	ts := httptest.NewServer(newFakeLichess(chess.White, 20))
+	defer ts.Close()
+
+	bot := NewBot(ts.URL, "wrong", func() chess.Player { return random.Player() })
+	if err := bot.PlayGame("g1"); err == nil {
+		t.Fatalf("Expected an error, but got none")
+	}
+}
+
+func TestVariant(t *testing.T) {
+	tests := []struct {
+		key      string
+		variant  chess.Variant
+		chess960 bool
+	}{
+		{"standard", nil, false},
+		{"fromPosition", nil, false},
+		{"chess960", nil, true},
+		{"kingOfTheHill", chess.KingOfTheHill, false},
+		{"threeCheck", chess.ThreeCheck, false},
+		{"crazyhouse", chess.Crazyhouse, false},
+		{"atomic", chess.Atomic, false},
+		{"antichess", chess.Antichess, false},
+		{"horde", chess.Horde, false},
+		{"racingKings", chess.RacingKings, false},
+	}
+
+	for _, test := range tests {
+		v, chess960, err := variant(test.key)
+		if err != nil || v != test.variant || chess960 != test.chess960 {
+			t.Errorf("Expected (%v, %v) for %v, but got: (%v, %v, %v)", test.variant, test.chess960, test.key, v,
+				chess960, err)
+		}
+	}
+
+	if _, _, err := variant("bughouse"); err == nil {
+		t.Errorf("Expected bughouse to be unsupported")
+	}
+}
@@ -0,0 +1,215 @@
+package lichess
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is the base URL of the Lichess API
+const DefaultBaseURL = "https://lichess.org"
+
+// Client makes requests to the Bot API of Lichess, or of any server implementing it
+type Client struct {
+	// BaseURL is the URL the API paths are relative to, e.g. DefaultBaseURL
+	BaseURL string
+
+	// Token is the personal API access token of the bot account
+	Token string
+
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client making requests to the API at baseURL on behalf of the bot with the given token
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Event is an event of the stream of incoming events of the bot
+type Event struct {
+	// Type is one of "gameStart", "gameFinish", "challenge", "challengeCanceled" or "challengeDeclined"
+	Type string `json:"type"`
+
+	Game      *EventGame `json:"game"`
+	Challenge *Challenge `json:"challenge"`
+}
+
+// EventGame is the game an event is about
+type EventGame struct {
+	ID string `json:"id"`
+}
+
+// Challenge is a challenge sent to the bot
+type Challenge struct {
+	ID      string  `json:"id"`
+	Variant Variant `json:"variant"`
+}
+
+// Variant is the variant of chess a game or challenge is played with
+type Variant struct {
+	Key string `json:"key"`
+}
+
+// GameEvent is an event of the stream of a game: the full game, when the stream starts, and then its state after
+// every move
+type GameEvent struct {
+	// Type is one of "gameFull", "gameState", "chatLine" or "opponentGone"
+	Type string `json:"type"`
+
+	// set for "gameFull" only
+	ID         string     `json:"id"`
+	Variant    Variant    `json:"variant"`
+	White      GamePlayer `json:"white"`
+	Black      GamePlayer `json:"black"`
+	InitialFEN string     `json:"initialFen"`
+	State      *GameState `json:"state"`
+
+	GameState
+}
+
+// GamePlayer is a player of a game
+type GamePlayer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GameState is the state of a game
+type GameState struct {
+	// Moves are all moves played since the initial position, in UCI notation and separated by spaces
+	Moves string `json:"moves"`
+
+	// time left on the clocks, and increments, in milliseconds
+	WhiteTime int64 `json:"wtime"`
+	BlackTime int64 `json:"btime"`
+	WhiteInc  int64 `json:"winc"`
+	BlackInc  int64 `json:"binc"`
+
+	// Status is "started" while the game is being played, and describes how it ended otherwise, e.g. "mate"
+	Status string `json:"status"`
+}
+
+// do makes a request to the API, and returns the response if successful
+func (c *Client) do(method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v %v: %v: %v", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// post makes a POST request to the API, discarding the response
+func (c *Client) post(path string) error {
+	resp, err := c.do(http.MethodPost, path)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// stream calls handle with every line of the NDJSON stream at path, skipping the empty lines used as keep-alives,
+// until the stream ends or handle returns false
+func (c *Client) stream(path string, handle func(line []byte) (bool, error)) error {
+	resp, err := c.do(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		more, err := handle(line)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// AccountID returns the ID of the bot account
+func (c *Client) AccountID() (string, error) {
+	resp, err := c.do(http.MethodGet, "/api/account")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var account struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
+		return "", err
+	}
+
+	return account.ID, nil
+}
+
+// StreamEvents calls handle with every incoming event of the bot, until the stream ends or handle returns false
+func (c *Client) StreamEvents(handle func(e *Event) bool) error {
+	return c.stream("/api/stream/event", func(line []byte) (bool, error) {
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return false, fmt.Errorf("invalid event: %v", err)
+		}
+
+		return handle(&e), nil
+	})
+}
+
+// StreamGame calls handle with every event of the game with the given ID, until the stream ends or handle returns
+// false
+func (c *Client) StreamGame(id string, handle func(e *GameEvent) bool) error {
+	return c.stream("/api/bot/game/stream/"+id, func(line []byte) (bool, error) {
+		var e GameEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return false, fmt.Errorf("invalid game event: %v", err)
+		}
+
+		return handle(&e), nil
+	})
+}
+
+// Move plays a move, in UCI notation, in the game with the given ID
+func (c *Client) Move(id, move string) error {
+	return c.post("/api/bot/game/" + id + "/move/" + move)
+}
+
+// AcceptChallenge accepts the challenge with the given ID
+func (c *Client) AcceptChallenge(id string) error {
+	return c.post("/api/challenge/" + id + "/accept")
+}
+
+// DeclineChallenge declines the challenge with the given ID
+func (c *Client) DeclineChallenge(id string) error {
+	return c.post("/api/challenge/" + id + "/decline")
+}
@@ -0,0 +1,229 @@
+package neat
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"math/rand"
+	"os"
+
+	goneat "github.com/yaricom/goNEAT/neat"
+	"github.com/yaricom/goNEAT/neat/genetics"
+	"github.com/yaricom/goNEAT/neat/network"
+)
+
+const (
+	// NumInputs is the number of sensors fed to the network: one per square for each of the 12 piece types,
+	// followed by the side to move and the 4 castling flags
+	NumInputs = 12*64 + 1 + 4
+
+	// NumOutputs is the number of outputs of the network: a single score of the position from White's perspective
+	NumOutputs = 1
+)
+
+// NEATPlayer plays the move leading to the position that a NEAT network scores best for its own color
+type NEATPlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	Network *network.Network
+
+	// number of activation steps needed for the input signal to reach the output
+	depth int
+}
+
+func (np *NEATPlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	np.Color = c
+	np.Prompt = prompt
+	np.Move = move
+	np.GameClient = gc
+
+	np.Board = gc.GetBoard()
+}
+
+func (np *NEATPlayer) Run() {
+	for {
+		// Wait for our turn
+		p, ok := <-np.Prompt
+		if !ok {
+			return
+		}
+
+		if p.OppMove != nil {
+			np.Board.UnsafeMove(p.OppMove)
+		}
+
+		if len(np.Board.LegalMoves()) == 0 {
+			continue // game is over, wait for the prompt channel to close
+		}
+
+		m, err := np.ChooseMove(np.Board)
+		if err != nil {
+			// the network is broken, abandon the game rather than leave it waiting forever
+			np.Move <- nil
+			continue
+		}
+
+		np.Board.UnsafeMove(m)
+
+		// Send move
+		np.Move <- m
+	}
+}
+
+// ChooseMove returns the legal move on b whose resulting position scores best for the player to move. Ties are
+// broken randomly. Returns an error if there are no legal moves, or the network could not be activated.
+func (np *NEATPlayer) ChooseMove(b *chess.Board) (*chess.Move, error) {
+	moves := b.LegalMoves()
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("no legal moves")
+	}
+
+	var best []*chess.Move
+	var bestScore float64
+	for _, m := range moves {
+		next := b.Copy()
+		next.UnsafeMove(m)
+
+		score, err := np.Score(next)
+		if err != nil {
+			return nil, err
+		}
+
+		// the network scores from White's perspective
+		if b.Turn == chess.Black {
+			score = -score
+		}
+
+		switch {
+		case len(best) == 0 || score > bestScore:
+			best = []*chess.Move{m}
+			bestScore = score
+		case score == bestScore:
+			best = append(best, m)
+		}
+	}
+
+	return best[rand.Intn(len(best))], nil
+}
+
+// Score activates the network on the encoding of b, returning its output. Higher scores are better for White.
+func (np *NEATPlayer) Score(b *chess.Board) (float64, error) {
+	if err := np.Network.LoadSensors(Encode(b)); err != nil {
+		return 0, err
+	}
+
+	if _, err := np.Network.ForwardSteps(np.depth); err != nil {
+		return 0, fmt.Errorf("could not activate network: %v", err)
+	}
+
+	score := np.Network.Outputs[0].Activation
+
+	if _, err := np.Network.Flush(); err != nil {
+		return 0, fmt.Errorf("could not flush network: %v", err)
+	}
+
+	return score, nil
+}
+
+// Encode translates b into the network's sensor values. Each of the 12 Pieces bitmaps contributes 64 inputs that are
+// 1 where a piece of that type sits, followed by 1 if it is Black's turn, and 1 for each castling right still held.
+func Encode(b *chess.Board) []float64 {
+	in := make([]float64, 0, NumInputs)
+
+	for _, p := range chess.AllPieceTypes {
+		pieces := uint64(b.Pieces[p])
+		for i := 0; i < 64; i++ {
+			in = append(in, float64((pieces>>uint(i))&1))
+		}
+	}
+
+	castling := []bool{
+		b.Turn == chess.Black,
+		b.CanWhiteCastleKingside,
+		b.CanWhiteCastleQueenside,
+		b.CanBlackCastleKingside,
+		b.CanBlackCastleQueenside,
+	}
+
+	for _, flag := range castling {
+		if flag {
+			in = append(in, 1)
+		} else {
+			in = append(in, 0)
+		}
+	}
+
+	return in
+}
+
+// StartGenome returns a genome connecting every input (and a bias) directly to the output with small random
+// weights, to seed a population with.
+func StartGenome() *genetics.Genome {
+	trait := goneat.NewTrait()
+	trait.Id = 1
+
+	var nodes []*network.NNode
+	for id := 1; id <= NumInputs; id++ {
+		n := network.NewNNode(id, network.InputNeuron)
+		n.Trait = trait
+		nodes = append(nodes, n)
+	}
+
+	bias := network.NewNNode(NumInputs+1, network.BiasNeuron)
+	bias.Trait = trait
+	nodes = append(nodes, bias)
+
+	out := network.NewNNode(NumInputs+2, network.OutputNeuron)
+	out.Trait = trait
+	nodes = append(nodes, out)
+
+	var genes []*genetics.Gene
+	for i, in := range nodes[:NumInputs+1] {
+		weight := rand.Float64()*0.2 - 0.1
+		genes = append(genes, genetics.NewGeneWithTrait(trait, weight, in, out, false, int64(i+1), weight))
+	}
+
+	return genetics.NewGenome(1, []*goneat.Trait{trait}, nodes, genes)
+}
+
+// Player returns a NEATPlayer choosing moves with the given network
+func Player(net *network.Network) (*NEATPlayer, error) {
+	depth, err := net.MaxDepth()
+	if err != nil {
+		return nil, fmt.Errorf("could not compute network depth: %v", err)
+	}
+
+	if depth < 1 {
+		depth = 1 // an output with no incoming links still needs to be activated once
+	}
+
+	return &NEATPlayer{
+		Network: net,
+		depth:   depth,
+	}, nil
+}
+
+// LoadPlayer returns a NEATPlayer using the network of the genome stored in plain text format at path
+func LoadPlayer(path string) (*NEATPlayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open genome file: %v", err)
+	}
+	defer f.Close()
+
+	g, err := genetics.ReadGenome(f, 1)
+	if err != nil {
+		return nil, fmt.Errorf("could not read genome: %v", err)
+	}
+
+	net, err := g.Genesis(g.Id)
+	if err != nil {
+		return nil, fmt.Errorf("could not build network from genome: %v", err)
+	}
+
+	return Player(net)
+}
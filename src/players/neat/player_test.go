@@ -0,0 +1,73 @@
+package neat
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"strings"
+	"testing"
+)
+
+func newPlayer(t *testing.T) *NEATPlayer {
+	g := StartGenome()
+
+	net, err := g.Genesis(g.Id)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	np, err := Player(net)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	return np
+}
+
+func TestEncode(t *testing.T) {
+	in := Encode(chess.NewBoard())
+	if len(in) != NumInputs {
+		t.Fatalf("Expected %v inputs, but got: %v", NumInputs, len(in))
+	}
+
+	ones := 0
+	for _, v := range in {
+		if v == 1 {
+			ones++
+		}
+	}
+
+	// 32 pieces and the 4 castling rights, with White to move
+	if ones != 36 {
+		t.Errorf("Expected 36 set inputs, but got: %v", ones)
+	}
+}
+
+func TestChooseMoveNoLegalMoves(t *testing.T) {
+	b, err := chess.NewBoardFromFEN("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if _, err := newPlayer(t).ChooseMove(b); err == nil {
+		t.Errorf("Expected an error choosing a move in stalemate")
+	}
+}
+
+func TestGameAgainstRandom(t *testing.T) {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		var r *chess.GameResult
+		if c == chess.White {
+			r = chess.NewGame(newPlayer(t), random.Player(), chess.InfiniteTime{}).Start()
+		} else {
+			r = chess.NewGame(random.Player(), newPlayer(t), chess.InfiniteTime{}).Start()
+		}
+
+		if strings.Contains(r.Reason, "abandoned") || strings.Contains(r.Reason, "invalid") {
+			t.Errorf("Expected the game to be played to completion as %v, but got: %v", c, r.Reason)
+		}
+
+		if len(r.Moves) == 0 {
+			t.Errorf("Expected moves to be played as %v", c)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package random
+
+import (
+	"Chess2020/src/chess"
+	"math/rand"
+)
+
+// RandomPlayer plays a uniformly random legal move every turn
+type RandomPlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+}
+
+func (rp *RandomPlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	rp.Color = c
+	rp.Prompt = prompt
+	rp.Move = move
+	rp.GameClient = gc
+
+	rp.Board = gc.GetBoard()
+}
+
+func (rp *RandomPlayer) Run() {
+	for {
+		// Wait for our turn
+		p, ok := <-rp.Prompt
+		if !ok {
+			return
+		}
+
+		if p.OppMove != nil {
+			rp.Board.UnsafeMove(p.OppMove)
+		}
+
+		moves := rp.Board.LegalMoves()
+		if len(moves) == 0 {
+			continue // game is over, wait for the prompt channel to close
+		}
+
+		m := moves[rand.Intn(len(moves))]
+		rp.Board.UnsafeMove(m)
+
+		// Send move
+		rp.Move <- m
+	}
+}
+
+func Player() *RandomPlayer {
+	return &RandomPlayer{}
+}
@@ -0,0 +1,294 @@
+package remote
+
+import (
+	"Chess2020/src/chess"
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the default time a disconnected client has to reconnect before its game is abandoned
+const DefaultTimeout = time.Minute
+
+// time after which a client that isn't reading what is sent to it is considered disconnected
+const writeTimeout = 10 * time.Second
+
+// RemotePlayer plays the moves of a client connected over the network. The client speaks a line protocol; the
+// player sends:
+//
+//	color <white|black>        the color the client plays
+//	board <fen>                the current position
+//	clock <white ms> <black ms> the time left on both clocks
+//	opponent <move>            the move just played by the opponent
+//	yourmove                   the client must now move
+//	illegal <reason>           the last move sent was rejected; the client must move again
+//	gameover                   the game is over, and the connection is closed
+//
+// and the client replies to "yourmove" with:
+//
+//	move <move>
+//
+// Moves are in UCI long algebraic notation, e.g. e2e4 or e7e8q. A client that disconnects may hand a new
+// connection to the player with Reconnect; if it doesn't within Timeout, the game is abandoned. Before the game
+// starts, Disconnected reports a client that went away.
+type RemotePlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	// Timeout is how long a disconnected client has to reconnect before the game is abandoned
+	Timeout time.Duration
+
+	conn    *connection
+	reconns chan net.Conn
+
+	// closed when the connection the player was created with drops
+	disconnected chan struct{}
+
+	// over is set once Run has returned, after which Reconnect refuses new connections
+	reconnMu sync.Mutex
+	over     bool
+
+	// toMove is true while the client is expected to send a move
+	toMove bool
+
+	// abandoned is set once the client stayed disconnected for longer than Timeout
+	abandoned bool
+}
+
+// connection reads the lines sent by a client in the background
+type connection struct {
+	conn  net.Conn
+	lines chan string
+	quit  chan struct{}
+
+	// closed once the client disconnected, or the connection was closed
+	closed chan struct{}
+}
+
+func newConnection(conn net.Conn) *connection {
+	c := &connection{
+		conn:   conn,
+		lines:  make(chan string),
+		quit:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.closed)
+		defer close(c.lines)
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			select {
+			case c.lines <- scanner.Text():
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// send writes a line to the client. Write errors are ignored: a client that disconnected is noticed when reading.
+func (c *connection) send(format string, args ...interface{}) {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	fmt.Fprintf(c.conn, format+"\n", args...)
+}
+
+func (c *connection) close() {
+	close(c.quit)
+	c.conn.Close()
+}
+
+func (rp *RemotePlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	rp.Color = c
+	rp.Prompt = prompt
+	rp.Move = move
+	rp.GameClient = gc
+
+	rp.Board = gc.GetBoard()
+	rp.toMove = false
+	rp.abandoned = false
+}
+
+// Reconnect hands the player a new connection from its client, replacing the previous one once Run gets to it. It
+// doesn't block, even if Run hasn't started yet; a connection still waiting to be handed over is closed in favor of
+// conn. Returns false, leaving conn untouched, if the game is already over.
+func (rp *RemotePlayer) Reconnect(conn net.Conn) bool {
+	rp.reconnMu.Lock()
+	defer rp.reconnMu.Unlock()
+
+	if rp.over {
+		return false
+	}
+
+	select {
+	case pending := <-rp.reconns:
+		pending.Close()
+	default:
+	}
+
+	rp.reconns <- conn // Run only ever takes connections out, so there is room
+	return true
+}
+
+// Disconnected returns a channel that is closed once the connection the player was created with drops. It is meant
+// to notice clients leaving before their game starts: once Run has started, the client may reconnect instead. A
+// client that sent lines before its game started is only noticed once Run has read them.
+func (rp *RemotePlayer) Disconnected() <-chan struct{} {
+	return rp.disconnected
+}
+
+func (rp *RemotePlayer) Run() {
+	defer func() {
+		rp.reconnMu.Lock()
+		defer rp.reconnMu.Unlock()
+
+		rp.over = true
+		select {
+		case conn := <-rp.reconns:
+			fmt.Fprintln(conn, "gameover")
+			conn.Close()
+		default:
+		}
+	}()
+	defer func() {
+		rp.conn.close() // the connection may have been replaced since Run started
+	}()
+	rp.sendState()
+
+	// fires when a disconnected client has run out of time to reconnect
+	var abandon <-chan time.Time
+
+	for {
+		select {
+		case p, ok := <-rp.Prompt:
+			if !ok {
+				rp.conn.send("gameover")
+				return
+			}
+
+			if p.OppMove != nil {
+				rp.Board.UnsafeMove(p.OppMove)
+				rp.conn.send("opponent %v", p.OppMove.UCI())
+			}
+
+			if len(rp.Board.LegalMoves()) == 0 {
+				continue // game is over, wait for the prompt channel to close
+			}
+
+			if rp.abandoned {
+				rp.Move <- nil
+				continue
+			}
+
+			rp.toMove = true
+			rp.sendClock()
+			rp.conn.send("yourmove")
+
+		case conn := <-rp.reconns:
+			if rp.abandoned {
+				fmt.Fprintln(conn, "gameover")
+				conn.Close()
+				continue
+			}
+
+			rp.conn.close()
+			rp.conn = newConnection(conn)
+			abandon = nil
+			rp.sendState()
+
+		case line, ok := <-rp.conn.lines:
+			if !ok {
+				rp.conn.lines = nil // disconnected; wait for the client to reconnect
+				abandon = time.After(rp.Timeout)
+				continue
+			}
+
+			rp.handle(line)
+
+		case <-abandon:
+			abandon = nil
+			rp.abandoned = true
+			if rp.toMove {
+				rp.toMove = false
+				rp.Move <- nil
+			}
+		}
+	}
+}
+
+// sendState tells the client everything it needs to know to resume the game
+func (rp *RemotePlayer) sendState() {
+	if rp.Color == chess.White {
+		rp.conn.send("color white")
+	} else {
+		rp.conn.send("color black")
+	}
+
+	rp.conn.send("board %v", rp.Board.FEN())
+	rp.sendClock()
+
+	if rp.toMove {
+		rp.conn.send("yourmove")
+	}
+}
+
+func (rp *RemotePlayer) sendClock() {
+	white := rp.GameClient.GetTimeLeft(chess.White)
+	black := rp.GameClient.GetTimeLeft(chess.Black)
+	rp.conn.send("clock %d %d", white.Milliseconds(), black.Milliseconds())
+}
+
+// handle handles a line sent by the client
+func (rp *RemotePlayer) handle(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	if fields[0] != "move" || len(fields) != 2 {
+		rp.conn.send("illegal expected: move <move>")
+		return
+	}
+
+	if !rp.toMove {
+		rp.conn.send("illegal not your turn")
+		return
+	}
+
+	m, err := chess.NewMoveUCI(fields[1], rp.Board.Turn)
+	if err == nil {
+		err = rp.Board.CheckMove(m)
+	}
+
+	if err != nil {
+		rp.conn.send("illegal %v", err)
+		return
+	}
+
+	rp.Board.UnsafeMove(m)
+	rp.toMove = false
+
+	// Send move
+	rp.Move <- m
+}
+
+// Player returns a RemotePlayer playing the moves of the client connected on conn
+func Player(conn net.Conn) *RemotePlayer {
+	c := newConnection(conn)
+	return &RemotePlayer{
+		Timeout:      DefaultTimeout,
+		conn:         c,
+		reconns:      make(chan net.Conn, 1),
+		disconnected: c.closed,
+	}
+}
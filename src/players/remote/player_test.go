@@ -0,0 +1,152 @@
+package remote
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client plays the first legal move of every position over conn, until it has played maxMoves moves, at which point
+// it disconnects, or the game is over. Its first move is preceded by an illegal one.
+func client(t *testing.T, conn net.Conn, maxMoves int) {
+	defer conn.Close()
+
+	var b *chess.Board
+	moves := 0
+	illegal := false
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch fields[0] {
+		case "board":
+			var err error
+			if b, err = chess.NewBoardFromFEN(strings.Join(fields[1:], " ")); err != nil {
+				t.Errorf("Expected no errors, but got: %v", err)
+				return
+			}
+		case "opponent":
+			m, err := chess.NewMoveUCI(fields[1], b.Turn)
+			if err == nil {
+				err = b.Move(m)
+			}
+
+			if err != nil {
+				t.Errorf("Expected no errors, but got: %v", err)
+				return
+			}
+		case "yourmove":
+			if moves == maxMoves {
+				return
+			}
+
+			if !illegal {
+				illegal = true
+				fmt.Fprintln(conn, "move e2e5")
+				continue
+			}
+
+			m := b.LegalMoves()[0]
+			b.UnsafeMove(m)
+			moves++
+			fmt.Fprintf(conn, "move %v\n", m.UCI())
+		case "illegal":
+			if !illegal || moves > 0 {
+				t.Errorf("Expected only the first move to be illegal, but got: %v", scanner.Text())
+				return
+			}
+
+			fmt.Fprintf(conn, "move %v\n", b.LegalMoves()[0].UCI())
+			b.UnsafeMove(b.LegalMoves()[0])
+			moves++
+		case "gameover":
+			return
+		}
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	server, conn := net.Pipe()
+	go client(t, conn, -1)
+
+	result := chess.NewGame(Player(server), random.Player(), chess.ThreeMinute{}).Start()
+	if strings.Contains(result.Reason, "White lost") {
+		t.Fatalf("Expected the client to play legal moves in time, but got: %v", result.Reason)
+	}
+}
+
+func TestAbandon(t *testing.T) {
+	server, conn := net.Pipe()
+	go client(t, conn, 3)
+
+	p := Player(server)
+	p.Timeout = 50 * time.Millisecond
+
+	result := chess.NewGame(random.Player(), p, chess.ThreeMinute{}).Start()
+	if result.Outcome != chess.WhiteWon || !strings.Contains(result.Reason, "abandoned") {
+		t.Fatalf("Expected black to abandon the game, but got: %v", result.Reason)
+	}
+
+	if len(result.Moves) != 7 {
+		t.Fatalf("Expected 7 moves, but got: %v", len(result.Moves))
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	server, conn := net.Pipe()
+	disconnected := make(chan struct{})
+	go func() {
+		client(t, conn, 3)
+		close(disconnected)
+	}()
+
+	p := Player(server)
+	p.Timeout = time.Minute
+
+	go func() {
+		<-disconnected
+
+		server, conn := net.Pipe()
+		go client(t, conn, -1)
+		p.Reconnect(server)
+	}()
+
+	result := chess.NewGame(p, random.Player(), chess.ThreeMinute{}).Start()
+	if strings.Contains(result.Reason, "White lost") {
+		t.Fatalf("Expected the client to resume the game, but got: %v", result.Reason)
+	}
+
+	if len(result.Moves) <= 6 {
+		t.Fatalf("Expected the game to go on after the reconnection, but got: %v moves", len(result.Moves))
+	}
+}
+
+func TestReconnectBeforeRun(t *testing.T) {
+	server, conn := net.Pipe()
+	conn.Close()
+
+	p := Player(server)
+
+	select {
+	case <-p.Disconnected():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the client to be reported disconnected")
+	}
+
+	// the game hasn't started yet, so the connection waits for Run
+	server, conn = net.Pipe()
+	go client(t, conn, -1)
+	if !p.Reconnect(server) {
+		t.Fatalf("Expected the connection to be handed over")
+	}
+
+	result := chess.NewGame(p, random.Player(), chess.ThreeMinute{}).Start()
+	if strings.Contains(result.Reason, "White lost") {
+		t.Fatalf("Expected the client to play on its new connection, but got: %v", result.Reason)
+	}
+}
@@ -0,0 +1,187 @@
+package search
+
+import (
+	"Chess2020/src/chess"
+	"math/bits"
+)
+
+// pieceValues [p] is the material value of piece p, in centipawns
+var pieceValues = [12]int{
+	chess.WhiteKing:   0,
+	chess.WhiteQueen:  900,
+	chess.WhiteKnight: 320,
+	chess.WhiteBishop: 330,
+	chess.WhiteRook:   500,
+	chess.WhitePawn:   100,
+	chess.BlackKing:   0,
+	chess.BlackQueen:  900,
+	chess.BlackKnight: 320,
+	chess.BlackBishop: 330,
+	chess.BlackRook:   500,
+	chess.BlackPawn:   100,
+}
+
+// racingKingsRankValue is the value of each rank a king is ahead of the other in Racing Kings, in centipawns
+const racingKingsRankValue = 100
+
+// Piece-square tables from White's point of view, listed rank 8 first and file a first, in centipawns
+var (
+	pawnTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	knightTable = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+
+	bishopTable = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+
+	rookTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+
+	queenTable = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+
+	kingTable = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+
+	// kingEndgameTable replaces kingTable once queens are off the board, drawing the king to the center
+	kingEndgameTable = [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+)
+
+// tableIndex returns the index into a piece-square table of the square represented by 2^i, for a piece of color c
+func tableIndex(i int, c chess.Color) int {
+	rank := i / 8
+	file := 7 - i%8
+
+	if c == chess.White {
+		rank = 7 - rank
+	}
+
+	return rank*8 + file
+}
+
+// Evaluate returns a static evaluation of b in centipawns, from the point of view of the player to move
+func Evaluate(b *chess.Board) int {
+	endgame := b.Pieces[chess.WhiteQueen]|b.Pieces[chess.BlackQueen] == 0
+
+	score := 0
+	for _, p := range chess.AllPieceTypes {
+		var table *[64]int
+		switch p {
+		case chess.WhitePawn, chess.BlackPawn:
+			table = &pawnTable
+		case chess.WhiteKnight, chess.BlackKnight:
+			table = &knightTable
+		case chess.WhiteBishop, chess.BlackBishop:
+			table = &bishopTable
+		case chess.WhiteRook, chess.BlackRook:
+			table = &rookTable
+		case chess.WhiteQueen, chess.BlackQueen:
+			table = &queenTable
+		case chess.WhiteKing, chess.BlackKing:
+			table = &kingTable
+			if endgame {
+				table = &kingEndgameTable
+			}
+		}
+
+		c := p.Color()
+		pieces := uint64(b.Pieces[p])
+		for pieces != 0 {
+			i := bits.TrailingZeros64(pieces)
+			v := pieceValues[p] + table[tableIndex(i, c)]
+
+			if c == chess.White {
+				score += v
+			} else {
+				score -= v
+			}
+
+			pieces &= pieces - 1
+		}
+	}
+
+	// pieces in hand, in variants with pockets, are worth as much as pieces on the board
+	for p, n := range b.Pockets {
+		if chess.Piece(p).Color() == chess.White {
+			score += n * pieceValues[p]
+		} else {
+			score -= n * pieceValues[p]
+		}
+	}
+
+	// losing pieces is the goal of Antichess
+	if b.Variant == chess.Antichess {
+		score = -score
+	}
+
+	// kings race to the 8th rank in Racing Kings
+	if b.Variant == chess.RacingKings {
+		whiteRank := bits.TrailingZeros64(uint64(b.Pieces[chess.WhiteKing])) / 8
+		blackRank := bits.TrailingZeros64(uint64(b.Pieces[chess.BlackKing])) / 8
+		score += racingKingsRankValue * (whiteRank - blackRank)
+	}
+
+	if b.Turn == chess.Black {
+		return -score
+	}
+
+	return score
+}
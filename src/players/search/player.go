@@ -0,0 +1,115 @@
+package search
+
+import (
+	"Chess2020/src/chess"
+	"time"
+)
+
+const (
+	// DefaultHashMB is the default size of the transposition table, in megabytes
+	DefaultHashMB = 64
+
+	// time to search each move for when the clocks are untimed
+	untimedMoveTime = 10 * time.Second
+)
+
+// SearchPlayer plays the best move found by an alpha-beta search
+type SearchPlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	Engine *Engine
+
+	// Limits, if set, are used for every search instead of budgeting time from the Game clock. Setting only Depth
+	// or Nodes gives reproducible searches regardless of the time control.
+	Limits *Limits
+
+	// hashes of all positions played so far, oldest first
+	history []uint64
+
+	// score of the last search, from the point of view of the player
+	score int
+}
+
+func (sp *SearchPlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	sp.Color = c
+	sp.Prompt = prompt
+	sp.Move = move
+	sp.GameClient = gc
+
+	sp.Board = gc.GetBoard()
+	sp.history = nil
+	sp.Engine.NewGame()
+}
+
+// limits returns the limits to search the current move with
+func (sp *SearchPlayer) limits() Limits {
+	if sp.Limits != nil {
+		return *sp.Limits
+	}
+
+	white := sp.GameClient.GetTimeLeft(chess.White)
+	black := sp.GameClient.GetTimeLeft(chess.Black)
+	if white > chess.UntimedClock || black > chess.UntimedClock {
+		return Limits{MoveTime: untimedMoveTime}
+	}
+
+	inc := sp.GameClient.GetTimeControl().Increment()
+	return Limits{
+		WhiteTime: white,
+		BlackTime: black,
+		WhiteInc:  inc,
+		BlackInc:  inc,
+	}
+}
+
+func (sp *SearchPlayer) Run() {
+	for {
+		// Wait for our turn
+		p, ok := <-sp.Prompt
+		if !ok {
+			return
+		}
+
+		if p.OppMove != nil {
+			sp.history = append(sp.history, sp.Board.Hash())
+			sp.Board.UnsafeMove(p.OppMove)
+		}
+
+		r := sp.Engine.Search(sp.Board, sp.history, sp.limits())
+		if r.Move == nil {
+			continue // game is over, wait for the prompt channel to close
+		}
+
+		sp.score = r.Score
+		sp.history = append(sp.history, sp.Board.Hash())
+		sp.Board.UnsafeMove(r.Move)
+
+		// Send move
+		sp.Move <- r.Move
+	}
+}
+
+// Score returns the score of the search of the last move, implementing chess.Evaluator
+func (sp *SearchPlayer) Score() (int, bool) {
+	return sp.score, true
+}
+
+// Player returns a SearchPlayer searching with the given number of threads, budgeting time from the Game clock
+func Player(threads int) *SearchPlayer {
+	return &SearchPlayer{
+		Engine: NewEngine(threads, DefaultHashMB),
+	}
+}
+
+// LimitedPlayer returns a SearchPlayer searching with the given number of threads, and the given limits on every move
+func LimitedPlayer(threads int, l Limits) *SearchPlayer {
+	return &SearchPlayer{
+		Engine: NewEngine(threads, DefaultHashMB),
+		Limits: &l,
+	}
+}
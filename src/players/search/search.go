@@ -0,0 +1,634 @@
+package search
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/syzygy"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MateScore is the score of delivering checkmate on the current move. Being mated in n plies scores -MateScore + n.
+	MateScore = 31000
+
+	infinity = 32000
+
+	// TablebaseWinScore is the score of a position the tablebase reports as won. It is below all mate scores, and
+	// decreases by one every ply, like them.
+	TablebaseWinScore = MateScore - 2*maxPly
+
+	// maxPly is the deepest a search will ever go, including extensions
+	maxPly = 100
+)
+
+// Result is the outcome of a search
+type Result struct {
+	Move  *chess.Move
+	Score int
+	Depth int
+	Nodes uint64
+
+	// PV is the principal variation: the line of play the search expects, starting with Move
+	PV []*chess.Move
+}
+
+// Limits restricts how long a search runs, mirroring the parameters of the UCI "go" command. A search stops as
+// soon as any of the set limits is reached, or when Engine.Stop is called. A search with no limits set runs until
+// stopped.
+type Limits struct {
+	// Clock state, used to budget time when MoveTime is not set. Zero times mean no clock.
+	WhiteTime time.Duration
+	BlackTime time.Duration
+	WhiteInc  time.Duration
+	BlackInc  time.Duration
+
+	// MovesToGo is the number of moves until the next time control, or 0 if the clock is sudden death
+	MovesToGo int
+
+	// Depth is the maximum number of plies to search
+	Depth int
+
+	// Nodes is the maximum number of nodes to search, over all threads
+	Nodes uint64
+
+	// MoveTime is the exact amount of time to search for
+	MoveTime time.Duration
+
+	// Infinite makes the search ignore all other limits and run until stopped
+	Infinite bool
+}
+
+// timeBudget returns how long to search a position with the given player to move, or 0 for no time limit
+func (l Limits) timeBudget(c chess.Color) time.Duration {
+	if l.Infinite {
+		return 0
+	}
+
+	if l.MoveTime > 0 {
+		return l.MoveTime
+	}
+
+	left, inc := l.WhiteTime, l.WhiteInc
+	if c == chess.Black {
+		left, inc = l.BlackTime, l.BlackInc
+	}
+
+	if left <= 0 {
+		return 0
+	}
+
+	movesToGo := l.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+
+	t := left/time.Duration(movesToGo) + inc*3/4
+	if t > left/2 {
+		t = left / 2
+	}
+
+	return t
+}
+
+// Engine searches positions for the best move using one or more threads. Threads share a single lock-free
+// transposition table, and each searches its own copy of the board (Lazy SMP): the threads cooperate only through
+// the table entries they leave for each other.
+type Engine struct {
+	// Threads is the number of threads to search with
+	Threads int
+
+	// Tablebase, if set, is probed for positions with few enough pieces
+	Tablebase *syzygy.Tablebase
+
+	// OnIteration, if set, is called with the result of every completed iteration of the main thread, e.g. to report
+	// progress to a user interface
+	OnIteration func(r *Result)
+
+	tt *TranspositionTable
+
+	// moves searched at the root, restricted to the best moves according to the tablebase if the root is in it
+	rootMoves []*chess.Move
+
+	stopped int32
+	nodes   uint64
+
+	// limits of the running search, with the time budget resolved into a deadline
+	limits   Limits
+	deadline time.Time
+}
+
+// NewEngine returns an Engine searching with the given number of threads, and a transposition table of roughly
+// hashMB megabytes.
+func NewEngine(threads, hashMB int) *Engine {
+	if threads < 1 {
+		threads = 1
+	}
+
+	return &Engine{
+		Threads: threads,
+		tt:      NewTranspositionTable(hashMB),
+	}
+}
+
+// Stop makes a running search return as soon as possible
+func (e *Engine) Stop() {
+	atomic.StoreInt32(&e.stopped, 1)
+}
+
+func (e *Engine) isStopped() bool {
+	return atomic.LoadInt32(&e.stopped) != 0
+}
+
+// NewGame clears all state remembered from previous searches
+func (e *Engine) NewGame() {
+	e.tt.Clear()
+}
+
+// Search returns the best move found on b within the given limits, or a Result with a nil Move if there are no
+// legal moves. history holds the hashes of all positions that occurred in the game before b, oldest first, and is
+// used to detect repetitions. An Engine can run only one search at a time.
+func (e *Engine) Search(b *chess.Board, history []uint64, limits Limits) *Result {
+	atomic.StoreInt32(&e.stopped, 0)
+	return e.search(b, history, limits)
+}
+
+// Go starts a search like Search in the background, and calls done with its result when it ends. Unlike with
+// Search, a call to Stop made any time after Go returns is guaranteed to end the search.
+func (e *Engine) Go(b *chess.Board, history []uint64, limits Limits, done func(r *Result)) {
+	atomic.StoreInt32(&e.stopped, 0)
+
+	b = b.Copy()
+	history = append([]uint64(nil), history...)
+	go func() {
+		done(e.search(b, history, limits))
+	}()
+}
+
+func (e *Engine) search(b *chess.Board, history []uint64, limits Limits) *Result {
+	moves := b.LegalMoves()
+	if len(moves) == 0 {
+		return &Result{}
+	}
+
+	atomic.StoreUint64(&e.nodes, 0)
+
+	e.rootMoves = e.tablebaseRootMoves(b, moves)
+
+	e.limits = limits
+	e.deadline = time.Time{}
+	if budget := limits.timeBudget(b.Turn); budget > 0 {
+		e.deadline = time.Now().Add(budget)
+	}
+
+	threads := make([]*thread, e.Threads)
+	results := make([]*Result, e.Threads)
+
+	var wg sync.WaitGroup
+	for i := range threads {
+		threads[i] = &thread{
+			e:       e,
+			id:      i,
+			history: append([]uint64(nil), history...),
+		}
+
+		wg.Add(1)
+		go func(t *thread) {
+			defer wg.Done()
+			results[t.id] = t.iterate(b.Copy())
+
+			// helper threads never run out of work, so the main thread finishing ends the search
+			if t.id == 0 {
+				for limits.Infinite && !e.isStopped() {
+					time.Sleep(time.Millisecond) // infinite searches only end when stopped
+				}
+
+				e.Stop()
+			}
+		}(threads[i])
+	}
+
+	wg.Wait()
+
+	// use the deepest completed search, preferring the main thread
+	var best *Result
+	for _, r := range results {
+		if r != nil && (best == nil || r.Depth > best.Depth) {
+			best = r
+		}
+	}
+
+	if best == nil {
+		best = &Result{Move: e.rootMoves[0], PV: e.rootMoves[:1]}
+	}
+
+	best.Nodes = atomic.LoadUint64(&e.nodes)
+	return best
+}
+
+// tablebaseRootMoves returns the moves on b that the tablebase ranks best, or all moves if b is not in the tablebase
+func (e *Engine) tablebaseRootMoves(b *chess.Board, moves []*chess.Move) []*chess.Move {
+	if e.Tablebase == nil || b.PieceCount() > e.Tablebase.MaxPieces() {
+		return moves
+	}
+
+	ranked, err := e.Tablebase.RankRootMoves(b)
+	if err != nil {
+		return moves
+	}
+
+	var best []*chess.Move
+	bestRank := 0
+	for _, m := range ranked {
+		switch {
+		case len(best) == 0 || m.Rank > bestRank:
+			best = []*chess.Move{m.Move}
+			bestRank = m.Rank
+		case m.Rank == bestRank:
+			best = append(best, m.Move)
+		}
+	}
+
+	return best
+}
+
+// probe returns the score of b according to the tablebase. Positions are only probed right after a capture or pawn
+// move, as the tablebase doesn't know how far the 50-move counter has already advanced.
+func (t *thread) probe(b *chess.Board, ply int) (int, bool) {
+	tb := t.e.Tablebase
+	if tb == nil || b.HalfMoveClock != 0 || b.PieceCount() > tb.MaxPieces() {
+		return 0, false
+	}
+
+	wdl, err := tb.ProbeWDL(b)
+	if err != nil {
+		return 0, false
+	}
+
+	switch wdl {
+	case syzygy.Win:
+		return TablebaseWinScore - ply, true
+	case syzygy.Loss:
+		return -TablebaseWinScore + ply, true
+	default:
+		return 0, true // cursed wins and blessed losses are draws under the 50-move rule
+	}
+}
+
+// thread is one of the parallel searchers of an Engine
+type thread struct {
+	e  *Engine
+	id int
+
+	// hashes of all positions before the current node, in the game and in the search
+	history []uint64
+
+	completedDepth int
+	rootBest       *chess.Move
+}
+
+// visit counts a newly searched node, stopping the search if it hit its node limit
+func (t *thread) visit() {
+	nodes := atomic.AddUint64(&t.e.nodes, 1)
+	if !t.e.limits.Infinite && t.e.limits.Nodes > 0 && nodes >= t.e.limits.Nodes {
+		t.e.Stop()
+	}
+
+	if t.id == 0 && nodes&1023 == 0 && !t.e.deadline.IsZero() && time.Now().After(t.e.deadline) {
+		t.e.Stop()
+	}
+}
+
+// stopped returns true iff the search should end. Every thread finishes at least the first iteration, so there is
+// always a move to play.
+func (t *thread) stopped() bool {
+	return (t.id > 0 || t.completedDepth > 0) && t.e.isStopped()
+}
+
+// iterate runs iterative deepening on b until stopped, returning the result of the deepest completed iteration
+func (t *thread) iterate(b *chess.Board) *Result {
+	var best *Result
+
+	maxDepth := maxPly - 1
+	if t.e.limits.Depth > 0 && !t.e.limits.Infinite {
+		maxDepth = t.e.limits.Depth
+	}
+
+	// half of the helper threads start a ply deeper, so that threads spread out over different depths
+	for depth := 1 + t.id%2; depth <= maxDepth; depth++ {
+		score := t.negamax(b, depth, 0, -infinity, infinity)
+		if t.stopped() {
+			break
+		}
+
+		t.completedDepth = depth
+		best = &Result{
+			Move:  t.rootBest,
+			Score: score,
+			Depth: depth,
+			PV:    t.e.pv(b, t.rootBest, depth),
+		}
+
+		if t.id == 0 && t.e.OnIteration != nil {
+			r := *best
+			r.Nodes = atomic.LoadUint64(&t.e.nodes)
+			t.e.OnIteration(&r)
+		}
+
+		// no point searching deeper once a forced mate was found
+		if !t.e.limits.Infinite && (score > MateScore-maxPly || score < -MateScore+maxPly) {
+			break
+		}
+	}
+
+	return best
+}
+
+// repeated returns true iff the position with the given hash already occurred since the last irreversible move
+func (t *thread) repeated(hash uint64, halfMoveClock int) bool {
+	// only positions with the same player to move can be repetitions
+	for i := len(t.history) - 2; i >= 0 && i >= len(t.history)-halfMoveClock; i -= 2 {
+		if t.history[i] == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negamax returns the score of b from the point of view of the player to move, searching depth plies ahead
+func (t *thread) negamax(b *chess.Board, depth, ply, alpha, beta int) int {
+	t.visit()
+	if t.stopped() {
+		return 0
+	}
+
+	hash := b.Hash()
+	if ply > 0 && (b.HalfMoveClock >= 100 || t.repeated(hash, b.HalfMoveClock)) {
+		return 0
+	}
+
+	if ply > 0 {
+		if score, ok := variantScore(b, ply); ok {
+			return score
+		}
+	}
+
+	if ply >= maxPly {
+		return Evaluate(b)
+	}
+
+	if ply > 0 {
+		if score, ok := t.probe(b, ply); ok {
+			return score
+		}
+	}
+
+	inCheck := b.InCheck(b.Turn)
+	if inCheck {
+		depth++ // don't stop searching while in check
+	}
+
+	if depth <= 0 {
+		return t.quiesce(b, ply, alpha, beta)
+	}
+
+	ttMove, ttScore, ttDepth, ttBound, ok := t.e.tt.Probe(hash)
+	if ok && ply > 0 && ttDepth >= depth {
+		score := scoreFromTT(ttScore, ply)
+		switch {
+		case ttBound == Exact,
+			ttBound == Lower && score >= beta,
+			ttBound == Upper && score <= alpha:
+			return score
+		}
+	}
+
+	moves := b.LegalMoves()
+	if ply == 0 {
+		moves = append([]*chess.Move(nil), t.e.rootMoves...)
+	}
+
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+
+		return 0 // stalemate
+	}
+
+	orderMoves(b, moves, ttMove)
+
+	t.history = append(t.history, hash)
+	defer func() { t.history = t.history[:len(t.history)-1] }()
+
+	origAlpha := alpha
+	bestScore := -infinity
+	var bestMove *chess.Move
+	for _, m := range moves {
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		score := -t.negamax(child, depth-1, ply+1, -beta, -alpha)
+		if t.stopped() {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = m
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+
+		if alpha >= beta {
+			break
+		}
+	}
+
+	if ply == 0 {
+		t.rootBest = bestMove
+	}
+
+	bound := Bound(Exact)
+	switch {
+	case bestScore <= origAlpha:
+		bound = Upper
+	case bestScore >= beta:
+		bound = Lower
+	}
+
+	t.e.tt.Store(hash, packMove(bestMove), scoreToTT(bestScore, ply), depth, bound)
+	return bestScore
+}
+
+// variantScore returns the score of b from the point of view of the player to move if its variant ended the game, a
+// win scoring like checkmate
+func variantScore(b *chess.Board, ply int) (int, bool) {
+	o, _, over := b.VariantOutcome()
+	switch {
+	case !over:
+		return 0, false
+	case o == chess.Draw:
+		return 0, true
+	case (o == chess.WhiteWon) == (b.Turn == chess.White):
+		return MateScore - ply, true
+	default:
+		return -MateScore + ply, true
+	}
+}
+
+// quiesce searches captures until the position is quiet, so that positions in the middle of an exchange aren't
+// statically evaluated
+func (t *thread) quiesce(b *chess.Board, ply, alpha, beta int) int {
+	t.visit()
+	if t.stopped() {
+		return 0
+	}
+
+	if score, ok := variantScore(b, ply); ok {
+		return score
+	}
+
+	standPat := Evaluate(b)
+	if standPat >= beta || ply >= maxPly {
+		return standPat
+	}
+
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	var captures []*chess.Move
+	for _, m := range b.LegalMoves() {
+		if b.IsCapture(m) || m.Promotion != chess.EmptyPiece {
+			captures = append(captures, m)
+		}
+	}
+
+	orderMoves(b, captures, 0)
+
+	for _, m := range captures {
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		score := -t.quiesce(child, ply+1, -beta, -alpha)
+		if t.stopped() {
+			return 0
+		}
+
+		if score >= beta {
+			return score
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	return alpha
+}
+
+// orderMoves sorts moves so the most promising are searched first: the transposition table move, then captures of
+// the most valuable pieces by the least valuable attackers, then promotions, then everything else
+func orderMoves(b *chess.Board, moves []*chess.Move, ttMove uint16) {
+	keys := make(map[*chess.Move]int, len(moves))
+	for _, m := range moves {
+		k := 0
+		switch {
+		case ttMove != 0 && packMove(m) == ttMove:
+			k = 1000000
+		case b.IsCapture(m):
+			victim := b.PieceAt(m.To)
+			value := pieceValues[chess.WhitePawn] // en-passent
+			if victim != chess.EmptyPiece {
+				value = pieceValues[victim]
+			}
+
+			k = 100000 + 10*value - pieceValues[b.PieceAt(m.From)]
+		}
+
+		if m.Promotion != chess.EmptyPiece {
+			k += pieceValues[m.Promotion]
+		}
+
+		keys[m] = k
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return keys[moves[i]] > keys[moves[j]]
+	})
+}
+
+// MateIn returns the number of moves until mate for a score: positive if the player to move mates, negative if it gets
+// mated, and 0 if the score is not a mate score
+func MateIn(score int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return (MateScore - score + 1) / 2
+	case score < -MateScore+maxPly:
+		return -(MateScore + score) / 2
+	default:
+		return 0
+	}
+}
+
+// scoreToTT adjusts mate scores to be relative to the current node instead of the root before storing them
+func scoreToTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score + ply
+	case score < -MateScore+maxPly:
+		return score - ply
+	default:
+		return score
+	}
+}
+
+// scoreFromTT undoes scoreToTT
+func scoreFromTT(score, ply int) int {
+	switch {
+	case score > MateScore-maxPly:
+		return score - ply
+	case score < -MateScore+maxPly:
+		return score + ply
+	default:
+		return score
+	}
+}
+
+// pv returns the principal variation starting with the move best on b, following best moves stored in the
+// transposition table for at most depth plies
+func (e *Engine) pv(b *chess.Board, best *chess.Move, depth int) []*chess.Move {
+	pv := []*chess.Move{best}
+	seen := map[uint64]bool{b.Hash(): true}
+
+	b = b.Copy()
+	b.UnsafeMove(best)
+
+	for len(pv) < depth {
+		hash := b.Hash()
+		if seen[hash] {
+			break
+		}
+		seen[hash] = true
+
+		pm, _, _, _, ok := e.tt.Probe(hash)
+		if !ok || pm == 0 {
+			break
+		}
+
+		m := unpackMove(pm)
+		if b.CheckMove(m) != nil {
+			break
+		}
+
+		pv = append(pv, m)
+		b.UnsafeMove(m)
+	}
+
+	return pv
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"Chess2020/src/chess"
+	"testing"
+	"time"
+)
+
+func newMove(c1, c2 chess.Coordinate) *chess.Move {
+	m, _ := chess.NewMoveCoord(c1, c2)
+	return m
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		b := chess.NewBoard()
+
+		// 1. f3 e5 2. g4
+		for _, m := range []*chess.Move{newMove("f2", "f3"), newMove("e7", "e5"), newMove("g2", "g4")} {
+			if err := b.Move(m); err != nil {
+				t.Fatalf("Expected no errors, but got: %v", err)
+			}
+		}
+
+		e := NewEngine(threads, 1)
+		r := e.Search(b, nil, Limits{MoveTime: time.Second})
+
+		if *r.Move != *newMove("d8", "h4") {
+			t.Errorf("Expected %v threads to find Qh4#, but got: %v", threads, r.Move)
+		}
+
+		if r.Score != MateScore-1 || MateIn(r.Score) != 1 {
+			t.Errorf("Expected %v threads to score mate in 1, but got: %v", threads, r.Score)
+		}
+	}
+}
+
+func TestSearchNoLegalMoves(t *testing.T) {
+	b := chess.NewBoard()
+
+	// 1. f3 e5 2. g4 Qh4#
+	for _, m := range []*chess.Move{newMove("f2", "f3"), newMove("e7", "e5"), newMove("g2", "g4"), newMove("d8", "h4")} {
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+	}
+
+	if r := NewEngine(2, 1).Search(b, nil, Limits{MoveTime: time.Second}); r.Move != nil {
+		t.Errorf("Expected no move when checkmated, but got: %v", r.Move)
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	e := NewEngine(1, 1)
+
+	r := e.Search(chess.NewBoard(), nil, Limits{Depth: 3})
+	if r.Depth != 3 {
+		t.Errorf("Expected a depth 3 search, but got depth: %v", r.Depth)
+	}
+
+	// fixed depth searches are reproducible
+	e.NewGame()
+	if r2 := e.Search(chess.NewBoard(), nil, Limits{Depth: 3}); *r2.Move != *r.Move || r2.Score != r.Score || r2.Nodes != r.Nodes {
+		t.Errorf("Expected identical depth 3 searches, but got: %+v and %+v", r, r2)
+	}
+
+	if r := e.Search(chess.NewBoard(), nil, Limits{Nodes: 500}); r.Nodes != 500 {
+		t.Errorf("Expected a 500 node search, but got %v nodes", r.Nodes)
+	}
+
+	// infinite searches run until stopped
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		e.Stop()
+	}()
+
+	start := time.Now()
+	if r := e.Search(chess.NewBoard(), nil, Limits{Infinite: true, Depth: 1}); r.Move == nil || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Expected infinite search to run until stopped, but it returned %v after %v", r.Move, time.Since(start))
+	}
+}
+
+func TestTranspositionTable(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	m := newMove("e7", "e8")
+	m.Promotion = chess.WhiteQueen
+
+	tt.Store(42, packMove(m), -123, 7, Lower)
+
+	pm, score, depth, bound, ok := tt.Probe(42)
+	if !ok {
+		t.Fatalf("Expected stored entry to be found")
+	}
+
+	if *unpackMove(pm) != *m || score != -123 || depth != 7 || bound != Lower {
+		t.Errorf("Expected (%v, -123, 7, %v), but got: (%v, %v, %v, %v)", m, Lower, unpackMove(pm), score, depth, bound)
+	}
+
+	drop, _ := chess.NewMoveUCI("N@f7", chess.Black)
+	if pm := packMove(drop); *unpackMove(pm) != *drop {
+		t.Errorf("Expected %v, but got: %v", drop, unpackMove(pm))
+	}
+
+	if _, _, _, _, ok := tt.Probe(43); ok {
+		t.Errorf("Expected no entry for a different hash")
+	}
+}
+
+func TestGoStop(t *testing.T) {
+	e := NewEngine(2, 1)
+
+	var iterations int
+	e.OnIteration = func(r *Result) {
+		iterations++
+	}
+
+	results := make(chan *Result)
+	e.Go(chess.NewBoard(), nil, Limits{Infinite: true}, func(r *Result) {
+		results <- r
+	})
+
+	// stopping right away must not be lost, even if the search hasn't started yet
+	e.Stop()
+
+	select {
+	case r := <-results:
+		if r.Move == nil || iterations == 0 {
+			t.Errorf("Expected a move after at least one iteration, but got: %v after %v", r.Move, iterations)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the search to stop")
+	}
+}
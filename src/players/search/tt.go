@@ -0,0 +1,118 @@
+package search
+
+import (
+	"Chess2020/src/chess"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Bound describes how a score stored in the transposition table relates to the true score of the position
+type Bound uint8
+
+// Bound representations
+const (
+	Exact = iota // the score is exact
+	Lower = iota // the true score is at least the stored score (fail-high)
+	Upper = iota // the true score is at most the stored score (fail-low)
+)
+
+// ttEntry is a single slot of the transposition table. The key is stored XORed with the data, so a slot torn by
+// concurrent writes from different threads fails verification instead of returning another position's data.
+type ttEntry struct {
+	key  uint64
+	data uint64
+}
+
+// TranspositionTable is a lock-free hash table of search results, safe for concurrent use by many threads.
+type TranspositionTable struct {
+	entries []ttEntry
+	mask    uint64
+}
+
+// NewTranspositionTable returns a table using roughly sizeMB megabytes of memory
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	n := uint64(sizeMB) * 1024 * 1024 / 16
+	if n < 1 {
+		n = 1
+	}
+
+	// round down to a power of two, so indexing is a mask
+	n = 1 << (63 - bits.LeadingZeros64(n))
+
+	return &TranspositionTable{
+		entries: make([]ttEntry, n),
+		mask:    n - 1,
+	}
+}
+
+// Clear removes all entries from the table. Must not be called concurrently with a search.
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.entries {
+		tt.entries[i] = ttEntry{}
+	}
+}
+
+// Probe returns the packed move, score, depth and bound stored for the position with the given hash, and whether
+// an entry was found.
+func (tt *TranspositionTable) Probe(hash uint64) (uint16, int, int, Bound, bool) {
+	e := &tt.entries[hash&tt.mask]
+
+	data := atomic.LoadUint64(&e.data)
+	key := atomic.LoadUint64(&e.key)
+	if key^data != hash || data == 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	move := uint16(data)
+	score := int(int16(uint16(data >> 16)))
+	depth := int(uint8(data >> 32))
+	bound := Bound(data >> 40 & 3)
+
+	return move, score, depth, bound, true
+}
+
+// Store saves the result of searching the position with the given hash. Entries of other positions are always
+// replaced, while an entry of the same position is only replaced by a search at least as deep.
+func (tt *TranspositionTable) Store(hash uint64, move uint16, score, depth int, bound Bound) {
+	e := &tt.entries[hash&tt.mask]
+
+	old := atomic.LoadUint64(&e.data)
+	if atomic.LoadUint64(&e.key)^old == hash {
+		if int(uint8(old>>32)) > depth {
+			return
+		}
+
+		if move == 0 {
+			move = uint16(old) // keep the best move found by the shallower search
+		}
+	}
+
+	data := uint64(move) | uint64(uint16(int16(score)))<<16 | uint64(uint8(depth))<<32 | uint64(bound)<<40 | 1<<42
+
+	atomic.StoreUint64(&e.key, hash^data)
+	atomic.StoreUint64(&e.data, data)
+}
+
+// packMove encodes m into 16 bits: 6 bits each for the from and to square indices, and 4 bits for the promotion.
+// Drops, which have no from square, use the to square as from square, and the dropped piece as promotion. The zero
+// value represents no move.
+func packMove(m *chess.Move) uint16 {
+	to := bits.TrailingZeros64(uint64(m.To))
+	if m.IsDrop() {
+		return uint16(to) | uint16(to)<<6 | uint16(m.Drop)<<12
+	}
+
+	from := bits.TrailingZeros64(uint64(m.From))
+	return uint16(from) | uint16(to)<<6 | uint16(m.Promotion)<<12
+}
+
+// unpackMove decodes a move encoded by packMove
+func unpackMove(pm uint16) *chess.Move {
+	from := chess.Square(1) << (pm & 63)
+	to := chess.Square(1) << (pm >> 6 & 63)
+	if from == to {
+		return chess.NewDrop(chess.Piece(pm>>12), to)
+	}
+
+	return chess.NewMove(from, to, chess.Piece(pm>>12))
+}
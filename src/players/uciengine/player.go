@@ -0,0 +1,350 @@
+package uciengine
+
+import (
+	"Chess2020/src/chess"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// time to search each move for when the clocks are untimed
+	untimedMoveTime = 10 * time.Second
+
+	// time given to the engine to answer "uci" and "isready" when starting
+	handshakeTimeout = 10 * time.Second
+
+	// time given to the engine to exit after "quit", before it is killed
+	quitTimeout = 5 * time.Second
+
+	// mateScore is the score of mating on the current move, beyond any centipawn score
+	mateScore = 100000
+)
+
+// errGameOver is returned while waiting for the engine once the game is over, e.g. because it ran out of time
+var errGameOver = errors.New("game is over")
+
+// UCIEnginePlayer plays the moves of an external engine speaking the Universal Chess Interface, run as a subprocess
+type UCIEnginePlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	// Path is the engine executable, run with Args
+	Path string
+	Args []string
+
+	// Options are sent to the engine with "setoption" after the handshake, e.g. {"Hash": "128"}. UCI_Chess960 is set
+	// for games of Chess960.
+	Options map[string]string
+
+	// MoveTime, if set, is the time the engine searches every move for, instead of budgeting time from the Game clock
+	MoveTime time.Duration
+
+	// position the game started from, in the form sent by "position", and the moves played since in UCI notation
+	startpos string
+	moves    []string
+
+	// score of the last info line of the last search, if any
+	score  int
+	scored bool
+
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	lines chan string
+}
+
+func (up *UCIEnginePlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	up.Color = c
+	up.Prompt = prompt
+	up.Move = move
+	up.GameClient = gc
+
+	up.Board = gc.GetBoard()
+
+	up.startpos = "startpos"
+	if fen := up.Board.FEN(); fen != chess.StartFEN {
+		up.startpos = "fen " + fen
+	}
+	up.moves = nil
+	up.lines = nil
+
+	if err := up.start(); err != nil {
+		log.Printf("Could not start UCI engine %v: %v", up.Path, err)
+		if up.lines != nil {
+			up.quit() // the engine is running, but unusable
+		}
+		up.cmd = nil
+	}
+}
+
+func (up *UCIEnginePlayer) Run() {
+	defer up.quit()
+
+	for {
+		// Wait for our turn
+		p, ok := <-up.Prompt
+		if !ok {
+			return
+		}
+
+		if p.OppMove != nil {
+			up.moves = append(up.moves, p.OppMove.UCI())
+			up.Board.UnsafeMove(p.OppMove)
+		}
+
+		if len(up.Board.LegalMoves()) == 0 {
+			continue // game is over, wait for the prompt channel to close
+		}
+
+		if up.cmd == nil {
+			// the engine failed to start, abandon the game rather than leave it waiting forever
+			up.Move <- nil
+			continue
+		}
+
+		m, err := up.bestMove()
+		if err == errGameOver {
+			return
+		}
+
+		if err != nil {
+			log.Printf("UCI engine %v failed to move: %v", up.Path, err)
+			up.Move <- nil
+			continue
+		}
+
+		up.moves = append(up.moves, m.UCI())
+		up.Board.UnsafeMove(m)
+
+		// Send move
+		up.Move <- m
+	}
+}
+
+// start launches the engine and completes the UCI handshake
+func (up *UCIEnginePlayer) start() error {
+	up.cmd = exec.Command(up.Path, up.Args...)
+
+	in, err := up.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	out, err := up.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := up.cmd.Start(); err != nil {
+		return err
+	}
+
+	up.in = in
+	up.lines = make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			up.lines <- scanner.Text()
+		}
+		close(up.lines)
+	}()
+
+	if err := up.send("uci"); err != nil {
+		return err
+	}
+
+	if _, err := up.readUntil("uciok", time.After(handshakeTimeout)); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(up.Options))
+	for name := range up.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := up.send("setoption name %v value %v", name, up.Options[name]); err != nil {
+			return err
+		}
+	}
+
+	if up.Board.Chess960 {
+		if err := up.send("setoption name UCI_Chess960 value true"); err != nil {
+			return err
+		}
+	}
+
+	if err := up.send("ucinewgame"); err != nil {
+		return err
+	}
+
+	if err := up.send("isready"); err != nil {
+		return err
+	}
+
+	_, err = up.readUntil("readyok", time.After(handshakeTimeout))
+	return err
+}
+
+// quit asks the engine to exit, and kills it if it doesn't do so in time
+func (up *UCIEnginePlayer) quit() {
+	if up.cmd == nil {
+		return
+	}
+
+	up.send("quit")
+	up.in.Close()
+
+	timer := time.AfterFunc(quitTimeout, func() {
+		up.cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	// drain the output so the engine never blocks writing it
+	for range up.lines {
+	}
+	up.cmd.Wait()
+}
+
+// send writes a command to the engine
+func (up *UCIEnginePlayer) send(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(up.in, format+"\n", args...)
+	return err
+}
+
+// readUntil reads lines from the engine until one starting with the given command, and returns its fields. The
+// scores of info lines read on the way are recorded. Gives up once timeout fires, if not nil, or with errGameOver
+// once the game is over, as an engine that stopped answering would otherwise be waited for forever.
+func (up *UCIEnginePlayer) readUntil(command string, timeout <-chan time.Time) ([]string, error) {
+	for {
+		select {
+		case line, ok := <-up.lines:
+			if !ok {
+				return nil, fmt.Errorf("engine exited before sending %v", command)
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) > 0 && fields[0] == command {
+				return fields, nil
+			}
+
+			if len(fields) > 0 && fields[0] == "info" {
+				if score, ok := parseScore(fields); ok {
+					up.score, up.scored = score, true
+				}
+			}
+		case _, ok := <-up.Prompt:
+			// the game never prompts a player while waiting for its move, so this is the end of the game
+			if !ok {
+				return nil, errGameOver
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("engine didn't send %v in time", command)
+		}
+	}
+}
+
+// goCommand returns the "go" command searching the current move
+func (up *UCIEnginePlayer) goCommand() string {
+	if up.MoveTime > 0 {
+		return fmt.Sprintf("go movetime %d", up.MoveTime.Milliseconds())
+	}
+
+	white := up.GameClient.GetTimeLeft(chess.White)
+	black := up.GameClient.GetTimeLeft(chess.Black)
+	if white > chess.UntimedClock || black > chess.UntimedClock {
+		return fmt.Sprintf("go movetime %d", untimedMoveTime.Milliseconds())
+	}
+
+	inc := up.GameClient.GetTimeControl().Increment().Milliseconds()
+	return fmt.Sprintf("go wtime %d btime %d winc %d binc %d", white.Milliseconds(), black.Milliseconds(), inc, inc)
+}
+
+// bestMove asks the engine for its move in the current position
+func (up *UCIEnginePlayer) bestMove() (*chess.Move, error) {
+	position := "position " + up.startpos
+	if len(up.moves) > 0 {
+		position += " moves " + strings.Join(up.moves, " ")
+	}
+
+	if err := up.send(position); err != nil {
+		return nil, err
+	}
+
+	up.scored = false
+	if err := up.send(up.goCommand()); err != nil {
+		return nil, err
+	}
+
+	fields, err := up.readUntil("bestmove", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("bestmove is missing its move")
+	}
+
+	m, err := chess.NewMoveUCI(fields[1], up.Board.Turn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := up.Board.CheckMove(m); err != nil {
+		return nil, fmt.Errorf("illegal bestmove %v: %v", fields[1], err)
+	}
+
+	return m, nil
+}
+
+// parseScore returns the score of an info line, in centipawns, and false if it has none
+func parseScore(fields []string) (int, bool) {
+	for i := 1; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+
+		n, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return 0, false
+		}
+
+		switch fields[i+1] {
+		case "cp":
+			return n, true
+		case "mate":
+			// mate in n moves, or getting mated in -n moves
+			if n < 0 {
+				return -mateScore - n, true
+			}
+			return mateScore - n, true
+		}
+	}
+
+	return 0, false
+}
+
+// Score returns the score the engine gave its last move, implementing chess.Evaluator
+func (up *UCIEnginePlayer) Score() (int, bool) {
+	return up.score, up.scored
+}
+
+// Player returns a UCIEnginePlayer running the engine at path with the given arguments
+func Player(path string, args ...string) *UCIEnginePlayer {
+	return &UCIEnginePlayer{
+		Path: path,
+		Args: args,
+	}
+}
@@ -0,0 +1,198 @@
+package uciengine
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEngineEnv makes the test binary act as a fake UCI engine instead of running the tests
+const fakeEngineEnv = "UCIENGINE_FAKE_ENGINE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEngineEnv) != "" {
+		fakeEngine()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeEngine speaks just enough UCI to play the first legal move, in UCI order, of every position it's given. It
+// refuses to move unless told the time left on both clocks, and never answers "go" when run as a hung engine.
+func fakeEngine() {
+	hung := os.Getenv(fakeEngineEnv) == "hung"
+
+	b := chess.NewBoard()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name fake")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			b = chess.NewBoard()
+			for i := 3; i < len(fields); i++ {
+				m, err := chess.NewMoveUCI(fields[i], b.Turn)
+				if err != nil || b.Move(m) != nil {
+					fmt.Printf("info string illegal move %v\n", fields[i])
+					break
+				}
+			}
+		case "go":
+			if hung {
+				continue
+			}
+
+			args := strings.Join(fields, " ")
+			if !strings.Contains(args, "wtime") || !strings.Contains(args, "btime") {
+				fmt.Println("bestmove 0000")
+				continue
+			}
+
+			best := ""
+			for _, m := range b.LegalMoves() {
+				if best == "" || m.UCI() < best {
+					best = m.UCI()
+				}
+			}
+
+			fmt.Println("info depth 1 score cp 0")
+			fmt.Println("info depth 2 score cp 25 pv " + best)
+			fmt.Printf("bestmove %v\n", best)
+		case "quit":
+			return
+		}
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	tests := map[string]int{
+		"info depth 12 seldepth 15 score cp -31 nodes 1000 pv e2e4": -31,
+		"info depth 20 score mate 3 pv d1h5":                        mateScore - 3,
+		"info depth 20 score mate -2":                               -mateScore + 2,
+	}
+
+	for line, expected := range tests {
+		if score, ok := parseScore(strings.Fields(line)); !ok || score != expected {
+			t.Errorf("Expected %v to score %v, but got: %v, %v", line, expected, score, ok)
+		}
+	}
+
+	if score, ok := parseScore(strings.Fields("info string hello")); ok {
+		t.Errorf("Expected no score, but got: %v", score)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "1")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	white := Player(os.Args[0])
+	g := chess.NewGame(white, random.Player(), chess.ThreeMinute{})
+
+	// the engine is idle right after its move, but may be searching again once the game is over
+	var score int
+	var scored bool
+	g.OnMove = func(c chess.Color, m *chess.Move) {
+		if c == chess.White {
+			score, scored = white.Score()
+		}
+	}
+
+	result := g.Start()
+
+	if strings.Contains(result.Reason, "White lost") {
+		t.Fatalf("Expected the engine to play legal moves in time, but got: %v", result.Reason)
+	}
+
+	if len(result.Moves) == 0 {
+		t.Fatalf("Expected moves to be played, but got none")
+	}
+
+	if !scored || score != 25 {
+		t.Errorf("Expected the engine to score its last move 25, but got: %v, %v", score, scored)
+	}
+
+	// every move of the engine must be the first legal move in UCI order
+	b := chess.NewBoard()
+	for i, m := range result.Moves {
+		if i%2 == 0 {
+			best := ""
+			for _, legal := range b.LegalMoves() {
+				if best == "" || legal.UCI() < best {
+					best = legal.UCI()
+				}
+			}
+
+			if m.UCI() != best {
+				t.Fatalf("Expected move %v to be %v, but got: %v", i+1, best, m.UCI())
+			}
+		}
+
+		if err := b.Move(m); err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+	}
+}
+
+func TestPlayerAbandons(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "1")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	// the fake engine refuses to move without clocks, and the other one can't even start
+	for _, white := range []*UCIEnginePlayer{Player(os.Args[0]), Player(os.Args[0] + ".missing")} {
+		result := chess.NewGame(white, random.Player(), chess.InfiniteTime{}).Start()
+		if result.Outcome != chess.BlackWon || !strings.Contains(result.Reason, "abandoned") {
+			t.Errorf("Expected %v to abandon the game, but got: %v %v", white.Path, result.Outcome, result.Reason)
+		}
+	}
+}
+
+func TestPlayerHungEngine(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "hung")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	white := Player(os.Args[0])
+	prompt := make(chan chess.Prompt, 1)
+	move := make(chan *chess.Move, 1)
+	white.Init(chess.White, chess.NewGame(white, random.Player(), chess.ThreeMinute{}), prompt, move)
+
+	done := make(chan struct{})
+	go func() {
+		white.Run()
+		close(done)
+	}()
+
+	// the game ends while the engine is thinking, e.g. on time
+	prompt <- chess.Prompt{}
+	time.Sleep(100 * time.Millisecond)
+	close(prompt)
+
+	select {
+	case <-done:
+	case <-time.After(quitTimeout):
+		t.Fatalf("Expected the player to stop waiting for the engine once the game is over")
+	}
+
+	if white.cmd.ProcessState == nil {
+		t.Errorf("Expected the engine to have exited")
+	}
+
+	if len(move) != 0 {
+		t.Errorf("Expected no move once the game is over, but got: %v", <-move)
+	}
+}
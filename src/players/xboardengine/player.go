@@ -0,0 +1,407 @@
+package xboardengine
+
+import (
+	"Chess2020/src/chess"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// time to search each move for when the clocks are untimed
+	untimedMoveTime = 10 * time.Second
+
+	// time given to the engine to list its features after "protover"; engines not done by then are treated as
+	// protocol version 1 engines
+	featureTimeout = 2 * time.Second
+
+	// time given to the engine to exit after "quit", before it is killed
+	quitTimeout = 5 * time.Second
+)
+
+// errGameOver is returned while waiting for the engine once the game is over, e.g. because it ran out of time
+var errGameOver = errors.New("game is over")
+
+// XBoardEnginePlayer plays the moves of an external engine speaking the Chess Engine Communication Protocol (CECP,
+// also known as the xboard or WinBoard protocol), run as a subprocess
+type XBoardEnginePlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	// Path is the engine executable, run with Args
+	Path string
+	Args []string
+
+	// MoveTime, if set, is the time the engine searches every move for, instead of budgeting time from the Game clock
+	MoveTime time.Duration
+
+	// features announced by the engine
+	usermove bool
+	setboard bool
+	variants []string
+
+	// thinking is true once the engine has been told to play its side with "go"
+	thinking bool
+
+	// score of the last thinking output of the last search, if any
+	score  int
+	scored bool
+
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	lines chan string
+}
+
+func (xp *XBoardEnginePlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	xp.Color = c
+	xp.Prompt = prompt
+	xp.Move = move
+	xp.GameClient = gc
+
+	xp.Board = gc.GetBoard()
+	xp.thinking = false
+	xp.lines = nil
+
+	if err := xp.start(); err != nil {
+		log.Printf("Could not start xboard engine %v: %v", xp.Path, err)
+		if xp.lines != nil {
+			xp.quit() // the engine is running, but unusable
+		}
+		xp.cmd = nil
+	}
+}
+
+func (xp *XBoardEnginePlayer) Run() {
+	defer xp.quit()
+
+	for {
+		// Wait for our turn
+		p, ok := <-xp.Prompt
+		if !ok {
+			return
+		}
+
+		var oppMove string
+		if p.OppMove != nil {
+			oppMove = xp.Board.XBoardMove(p.OppMove)
+			xp.Board.UnsafeMove(p.OppMove)
+		}
+
+		if len(xp.Board.LegalMoves()) == 0 {
+			continue // game is over, wait for the prompt channel to close
+		}
+
+		if xp.cmd == nil {
+			// the engine failed to start, abandon the game rather than leave it waiting forever
+			xp.Move <- nil
+			continue
+		}
+
+		m, err := xp.bestMove(oppMove)
+		if err == errGameOver {
+			return
+		}
+
+		if err != nil {
+			// the engine resigned, claimed an illegal move, or stopped answering
+			log.Printf("xboard engine %v failed to move: %v", xp.Path, err)
+			xp.Move <- nil
+			continue
+		}
+
+		xp.Board.UnsafeMove(m)
+
+		// Send move
+		xp.Move <- m
+	}
+}
+
+// start launches the engine, negotiates the protocol features, and sets up the game
+func (xp *XBoardEnginePlayer) start() error {
+	xp.cmd = exec.Command(xp.Path, xp.Args...)
+
+	in, err := xp.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	out, err := xp.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := xp.cmd.Start(); err != nil {
+		return err
+	}
+
+	xp.in = in
+	xp.lines = make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			xp.lines <- scanner.Text()
+		}
+		close(xp.lines)
+	}()
+
+	xp.send("xboard")
+	xp.send("protover 2")
+	if err := xp.features(); err != nil {
+		return err
+	}
+
+	xp.send("new")
+	if xp.Board.Chess960 {
+		if !hasVariant(xp.variants, "fischerandom") {
+			return fmt.Errorf("engine doesn't play Chess960")
+		}
+
+		xp.send("variant fischerandom")
+	}
+	xp.send("force")
+	xp.send("easy")
+	xp.send("post")
+
+	if fen := xp.Board.FEN(); fen != chess.StartFEN || xp.Board.Chess960 {
+		if !xp.setboard {
+			return fmt.Errorf("engine can't start from a custom position")
+		}
+
+		xp.send("setboard %v", fen)
+	}
+
+	if xp.MoveTime > 0 {
+		xp.send("st %d", int((xp.MoveTime+time.Second-1)/time.Second))
+		return nil
+	}
+
+	initial := xp.GameClient.GetTimeControl().InitialTime()
+	if initial > chess.UntimedClock {
+		xp.send("st %d", int(untimedMoveTime/time.Second))
+		return nil
+	}
+
+	seconds := int(initial / time.Second)
+	inc := xp.GameClient.GetTimeControl().Increment().Seconds()
+	xp.send("level 0 %d:%02d %v", seconds/60, seconds%60, inc)
+	return nil
+}
+
+// features reads the features announced by the engine after "protover", until it is done or the time to do so
+// runs out
+func (xp *XBoardEnginePlayer) features() error {
+	timeout := time.After(featureTimeout)
+	for {
+		select {
+		case line, ok := <-xp.lines:
+			if !ok {
+				return fmt.Errorf("engine exited during the feature negotiation")
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) == 0 || fields[0] != "feature" {
+				continue
+			}
+
+			for _, f := range parseFeatures(strings.TrimPrefix(line, "feature")) {
+				switch f.name {
+				case "usermove":
+					xp.usermove = f.value == "1"
+				case "setboard":
+					xp.setboard = f.value == "1"
+				case "variants":
+					xp.variants = strings.Split(f.value, ",")
+				case "done":
+					if f.value == "0" {
+						timeout = nil // the engine needs more time to start, and will say when it's done
+						continue
+					}
+
+					return nil
+				}
+
+				xp.send("accepted %v", f.name)
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+// feature is a single name=value pair of a "feature" command
+type feature struct {
+	name  string
+	value string
+}
+
+// parseFeatures parses the name=value pairs of a "feature" command, where values may be quoted
+func parseFeatures(s string) []feature {
+	var features []feature
+	for {
+		s = strings.TrimSpace(s)
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return features
+		}
+
+		f := feature{name: s[:eq]}
+		s = s[eq+1:]
+
+		end := strings.IndexByte(s, ' ')
+		if strings.HasPrefix(s, "\"") {
+			end = strings.IndexByte(s[1:], '"') + 2
+		}
+		if end < 0 || end > len(s) {
+			end = len(s)
+		}
+
+		f.value = strings.Trim(s[:end], "\"")
+		s = s[end:]
+
+		features = append(features, f)
+	}
+}
+
+// quit asks the engine to exit, and kills it if it doesn't do so in time
+func (xp *XBoardEnginePlayer) quit() {
+	if xp.cmd == nil {
+		return
+	}
+
+	xp.send("quit")
+	xp.in.Close()
+
+	timer := time.AfterFunc(quitTimeout, func() {
+		xp.cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	// drain the output so the engine never blocks writing it
+	for range xp.lines {
+	}
+	xp.cmd.Wait()
+}
+
+// send writes a command to the engine. Write errors are ignored: an engine that exited is noticed when reading its
+// output.
+func (xp *XBoardEnginePlayer) send(format string, args ...interface{}) {
+	fmt.Fprintf(xp.in, format+"\n", args...)
+}
+
+// sendMove tells the engine about a move, in coordinate notation
+func (xp *XBoardEnginePlayer) sendMove(m string) {
+	if xp.usermove {
+		xp.send("usermove %v", m)
+	} else {
+		xp.send("%v", m)
+	}
+}
+
+// bestMove tells the engine about the opponent's move, if any, and returns the engine's reply
+func (xp *XBoardEnginePlayer) bestMove(oppMove string) (*chess.Move, error) {
+	if xp.MoveTime == 0 {
+		engineTime := xp.GameClient.GetTimeLeft(xp.Color)
+		oppTime := xp.GameClient.GetTimeLeft(xp.Color.Other())
+		if engineTime <= chess.UntimedClock && oppTime <= chess.UntimedClock {
+			xp.send("time %d", engineTime.Milliseconds()/10)
+			xp.send("otim %d", oppTime.Milliseconds()/10)
+		}
+	}
+
+	if oppMove != "" {
+		xp.sendMove(oppMove)
+	}
+
+	if !xp.thinking {
+		xp.send("go")
+		xp.thinking = true
+	}
+
+	xp.scored = false
+	for {
+		var line string
+		select {
+		case l, ok := <-xp.lines:
+			if !ok {
+				return nil, fmt.Errorf("engine exited before moving")
+			}
+
+			line = l
+		case _, ok := <-xp.Prompt:
+			// the game never prompts a player while waiting for its move, so this is the end of the game, and an
+			// engine that stopped answering would otherwise be waited for forever
+			if !ok {
+				return nil, errGameOver
+			}
+
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case len(fields) >= 4 && isInt(fields[0]) && isInt(fields[1]):
+			// thinking output: ply score time nodes pv
+			xp.score, _ = strconv.Atoi(fields[1])
+			xp.scored = true
+		case fields[0] == "move" && len(fields) > 1:
+			m, err := xp.Board.ParseXBoardMove(fields[1])
+			if err != nil {
+				return nil, err
+			}
+
+			if err := xp.Board.CheckMove(m); err != nil {
+				return nil, fmt.Errorf("illegal move %v: %v", fields[1], err)
+			}
+
+			return m, nil
+		case strings.HasPrefix(line, "Illegal move"), strings.HasPrefix(line, "Error"):
+			return nil, fmt.Errorf("engine rejected a command: %v", line)
+		case fields[0] == "resign":
+			return nil, fmt.Errorf("engine resigned")
+		}
+	}
+}
+
+// hasVariant returns true iff variant is one of variants
+func hasVariant(variants []string, variant string) bool {
+	for _, v := range variants {
+		if strings.TrimSpace(v) == variant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInt returns true iff s is an integer
+func isInt(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// Score returns the score the engine gave its last move, implementing chess.Evaluator
+func (xp *XBoardEnginePlayer) Score() (int, bool) {
+	return xp.score, xp.scored
+}
+
+// Player returns an XBoardEnginePlayer running the engine at path with the given arguments
+func Player(path string, args ...string) *XBoardEnginePlayer {
+	return &XBoardEnginePlayer{
+		Path: path,
+		Args: args,
+	}
+}
@@ -0,0 +1,217 @@
+package xboardengine
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEngineEnv makes the test binary act as a fake xboard engine instead of running the tests
+const fakeEngineEnv = "XBOARDENGINE_FAKE_ENGINE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEngineEnv) != "" {
+		fakeEngine()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// firstMove returns the first legal move on b, in UCI order
+func firstMove(b *chess.Board) string {
+	best := ""
+	for _, m := range b.LegalMoves() {
+		if best == "" || m.UCI() < best {
+			best = m.UCI()
+		}
+	}
+
+	return best
+}
+
+// fakeEngine speaks just enough CECP to play the first legal move, in UCI order, whenever it's its turn. It refuses
+// moves not sent with "usermove", won't move before being given a time control, and resigns when given a fixed time
+// per move. When run as a hung engine, it never moves.
+func fakeEngine() {
+	hung := os.Getenv(fakeEngineEnv) == "hung"
+	b := chess.NewBoard()
+	engineColor := chess.Color(chess.Black)
+	force := false
+	level := false
+	fixed := false
+
+	move := func() {
+		if force || b.Turn != engineColor || !level || hung {
+			return
+		}
+
+		if fixed {
+			fmt.Println("resign")
+			return
+		}
+
+		s := firstMove(b)
+		m, _ := chess.NewMoveUCI(s, b.Turn)
+		b.UnsafeMove(m)
+		fmt.Printf("1 -40 0 20 %v\n", s)
+		fmt.Printf("move %v\n", s)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "protover":
+			fmt.Println("feature myname=\"fake engine\" usermove=1 setboard=1 done=1")
+		case "new":
+			b = chess.NewBoard()
+			engineColor = chess.Black
+			force = false
+		case "force":
+			force = true
+		case "level", "st":
+			level = true
+			fixed = fields[0] == "st"
+		case "go":
+			force = false
+			engineColor = b.Turn
+			move()
+		case "usermove":
+			m, err := chess.NewMoveUCI(fields[1], b.Turn)
+			if err != nil || b.Move(m) != nil {
+				fmt.Printf("Illegal move: %v\n", fields[1])
+				continue
+			}
+			move()
+		case "quit":
+			return
+		case "xboard", "accepted", "easy", "post", "time", "otim", "setboard":
+		default:
+			fmt.Printf("Error (unknown command): %v\n", fields[0])
+		}
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	features := parseFeatures(` myname="Some Engine 1.0" usermove=1  setboard=0 done=1`)
+
+	expected := []feature{
+		{"myname", "Some Engine 1.0"},
+		{"usermove", "1"},
+		{"setboard", "0"},
+		{"done", "1"},
+	}
+
+	if len(features) != len(expected) {
+		t.Fatalf("Expected %v features, but got: %v", len(expected), features)
+	}
+
+	for i, f := range features {
+		if f != expected[i] {
+			t.Fatalf("Expected feature %v, but got: %v", expected[i], f)
+		}
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "1")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		engine := Player(os.Args[0])
+		var g *chess.Game
+		if c == chess.White {
+			g = chess.NewGame(engine, random.Player(), chess.ThreeMinute{})
+		} else {
+			g = chess.NewGame(random.Player(), engine, chess.ThreeMinute{})
+		}
+
+		// the engine is idle right after its move, but may be thinking again once the game is over
+		var score int
+		var scored bool
+		g.OnMove = func(moved chess.Color, m *chess.Move) {
+			if moved == c {
+				score, scored = engine.Score()
+			}
+		}
+
+		result := g.Start()
+		if strings.Contains(result.Reason, fmt.Sprintf("%v lost", c)) {
+			t.Fatalf("Expected the engine to play legal moves in time, but got: %v", result.Reason)
+		}
+
+		if !scored || score != -40 {
+			t.Errorf("Expected the engine to score its last move -40, but got: %v, %v", score, scored)
+		}
+
+		// every move of the engine must be the first legal move in UCI order
+		b := chess.NewBoard()
+		for _, m := range result.Moves {
+			if b.Turn == c && m.UCI() != firstMove(b) {
+				t.Fatalf("Expected %v to play %v, but got: %v", c, firstMove(b), m.UCI())
+			}
+
+			if err := b.Move(m); err != nil {
+				t.Fatalf("Expected no errors, but got: %v", err)
+			}
+		}
+	}
+}
+
+func TestPlayerAbandons(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "1")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	// the fake engine resigns on untimed games, and the other one can't even start
+	for _, white := range []*XBoardEnginePlayer{Player(os.Args[0]), Player(os.Args[0] + ".missing")} {
+		result := chess.NewGame(white, random.Player(), chess.InfiniteTime{}).Start()
+		if result.Outcome != chess.BlackWon || !strings.Contains(result.Reason, "abandoned") {
+			t.Errorf("Expected %v to abandon the game, but got: %v %v", white.Path, result.Outcome, result.Reason)
+		}
+	}
+}
+
+func TestPlayerHungEngine(t *testing.T) {
+	os.Setenv(fakeEngineEnv, "hung")
+	defer os.Unsetenv(fakeEngineEnv)
+
+	white := Player(os.Args[0])
+	prompt := make(chan chess.Prompt, 1)
+	move := make(chan *chess.Move, 1)
+	white.Init(chess.White, chess.NewGame(white, random.Player(), chess.ThreeMinute{}), prompt, move)
+
+	done := make(chan struct{})
+	go func() {
+		white.Run()
+		close(done)
+	}()
+
+	// the game ends while the engine is thinking, e.g. on time
+	prompt <- chess.Prompt{}
+	time.Sleep(100 * time.Millisecond)
+	close(prompt)
+
+	select {
+	case <-done:
+	case <-time.After(quitTimeout):
+		t.Fatalf("Expected the player to stop waiting for the engine once the game is over")
+	}
+
+	if white.cmd.ProcessState == nil {
+		t.Errorf("Expected the engine to have exited")
+	}
+
+	if len(move) != 0 {
+		t.Errorf("Expected no move once the game is over, but got: %v", <-move)
+	}
+}
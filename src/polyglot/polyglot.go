@@ -0,0 +1,265 @@
+package polyglot
+
+import (
+	"Chess2020/src/chess"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+)
+
+// entrySize is the size in bytes of a book entry: a 64-bit key, 16-bit move, 16-bit weight and 32-bit learn value
+const entrySize = 16
+
+// pieceKinds [p] is the Polyglot index of piece p
+var pieceKinds = [12]int{
+	chess.BlackPawn:   0,
+	chess.WhitePawn:   1,
+	chess.BlackKnight: 2,
+	chess.WhiteKnight: 3,
+	chess.BlackBishop: 4,
+	chess.WhiteBishop: 5,
+	chess.BlackRook:   6,
+	chess.WhiteRook:   7,
+	chess.BlackQueen:  8,
+	chess.WhiteQueen:  9,
+	chess.BlackKing:   10,
+	chess.WhiteKing:   11,
+}
+
+// Key returns the Polyglot key of b, used to look up positions in a book
+func Key(b *chess.Board) uint64 {
+	var key uint64
+
+	for p, pieces := range b.Pieces {
+		pieces := uint64(pieces)
+		for pieces != 0 {
+			i := bits.TrailingZeros64(pieces)
+			rank, file := i/8, 7-i%8
+			key ^= randomKeys[64*pieceKinds[p]+8*rank+file]
+			pieces &= pieces - 1
+		}
+	}
+
+	castling := [4]bool{b.CanWhiteCastleKingside, b.CanWhiteCastleQueenside, b.CanBlackCastleKingside, b.CanBlackCastleQueenside}
+	for i, ok := range castling {
+		if ok {
+			key ^= randomKeys[768+i]
+		}
+	}
+
+	if b.EnPassent != 0 && canCaptureEnPassent(b) {
+		i := bits.TrailingZeros64(uint64(b.EnPassent))
+		key ^= randomKeys[772+7-i%8]
+	}
+
+	if b.Turn == chess.White {
+		key ^= randomKeys[780]
+	}
+
+	return key
+}
+
+// canCaptureEnPassent returns true iff a pawn of the player to move stands next to the pawn that just moved up two
+// squares. Polyglot only hashes the EnPassent square in that case, even if the capture would be illegal.
+func canCaptureEnPassent(b *chess.Board) bool {
+	ep := uint64(b.EnPassent)
+
+	pawned, pawns := ep>>8, uint64(b.Pieces[chess.WhitePawn])
+	if b.Turn == chess.Black {
+		pawned, pawns = ep<<8, uint64(b.Pieces[chess.BlackPawn])
+	}
+
+	var neighbors uint64
+	file := 7 - bits.TrailingZeros64(pawned)%8
+	if file > 0 {
+		neighbors |= pawned << 1
+	}
+
+	if file < 7 {
+		neighbors |= pawned >> 1
+	}
+
+	return neighbors&pawns != 0
+}
+
+// Entry is a book move for a position
+type Entry struct {
+	Move   *chess.Move
+	Weight uint16
+	Learn  uint32
+}
+
+// Book is an opening book in the Polyglot .bin format: a list of entries sorted by key. Entries are read on demand,
+// so books of any size can be used.
+type Book struct {
+	r io.ReaderAt
+	n int64
+
+	closer io.Closer
+}
+
+// NewBook returns a Book reading size bytes of entries from r
+func NewBook(r io.ReaderAt, size int64) (*Book, error) {
+	if size%entrySize != 0 {
+		return nil, fmt.Errorf("book size must be a multiple of %v bytes, is: %v", entrySize, size)
+	}
+
+	return &Book{
+		r: r,
+		n: size / entrySize,
+	}, nil
+}
+
+// Open opens the Polyglot book file at path
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open book: %v", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not stat book: %v", err)
+	}
+
+	book, err := NewBook(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	book.closer = f
+	return book, nil
+}
+
+// Close closes the underlying book file, if the Book was opened with Open
+func (bk *Book) Close() error {
+	if bk.closer == nil {
+		return nil
+	}
+
+	return bk.closer.Close()
+}
+
+// entry reads the raw i-th entry of the book
+func (bk *Book) entry(i int64) (key uint64, move, weight uint16, learn uint32, err error) {
+	var buf [entrySize]byte
+	if _, err = bk.r.ReadAt(buf[:], i*entrySize); err != nil {
+		return
+	}
+
+	key = binary.BigEndian.Uint64(buf[0:8])
+	move = binary.BigEndian.Uint16(buf[8:10])
+	weight = binary.BigEndian.Uint16(buf[10:12])
+	learn = binary.BigEndian.Uint32(buf[12:16])
+	return
+}
+
+// Entries returns the legal book moves for b, in the order they appear in the book
+func (bk *Book) Entries(b *chess.Board) ([]Entry, error) {
+	key := Key(b)
+
+	// binary search for the first entry with the key
+	lo, hi := int64(0), bk.n
+	for lo < hi {
+		mid := (lo + hi) / 2
+
+		k, _, _, _, err := bk.entry(mid)
+		if err != nil {
+			return nil, fmt.Errorf("could not read book entry: %v", err)
+		}
+
+		if k < key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	var entries []Entry
+	for i := lo; i < bk.n; i++ {
+		k, move, weight, learn, err := bk.entry(i)
+		if err != nil {
+			return nil, fmt.Errorf("could not read book entry: %v", err)
+		}
+
+		if k != key {
+			break
+		}
+
+		m := decodeMove(b, move)
+		if b.CheckMove(m) != nil {
+			continue // key collision with another position
+		}
+
+		entries = append(entries, Entry{Move: m, Weight: weight, Learn: learn})
+	}
+
+	return entries, nil
+}
+
+// Pick returns a book move for b chosen randomly in proportion to the entry weights, or nil if b is not in the book
+func (bk *Book) Pick(b *chess.Board) (*chess.Move, error) {
+	entries, err := bk.Entries(b)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+
+	if total == 0 {
+		return nil, nil
+	}
+
+	r := rand.Intn(total)
+	for _, e := range entries {
+		if r < int(e.Weight) {
+			return e.Move, nil
+		}
+
+		r -= int(e.Weight)
+	}
+
+	panic("Unreachable")
+}
+
+// square returns the Square on the given file and rank, where file 0 is the a-file and rank 0 is the 1st rank
+func square(file, rank uint16) chess.Square {
+	return chess.Square(1) << (8*rank + 7 - file)
+}
+
+// decodeMove translates a Polyglot move into a Move on b. Polyglot encodes castling as the king capturing its own
+// rook, which is translated into the king moving two squares.
+func decodeMove(b *chess.Board, move uint16) *chess.Move {
+	from := square(move>>6&7, move>>9&7)
+	to := square(move&7, move>>3&7)
+
+	promotion := chess.Piece(chess.EmptyPiece)
+	promotions := [5]chess.Piece{chess.EmptyPiece, chess.WhiteKnight, chess.WhiteBishop, chess.WhiteRook, chess.WhiteQueen}
+	if b.Turn == chess.Black {
+		promotions = [5]chess.Piece{chess.EmptyPiece, chess.BlackKnight, chess.BlackBishop, chess.BlackRook, chess.BlackQueen}
+	}
+
+	if p := move >> 12 & 7; p < 5 {
+		promotion = promotions[p]
+	}
+
+	king := b.PieceAt(from)
+	if (king == chess.WhiteKing || king == chess.BlackKing) && move>>6&7 == 4 {
+		switch move & 7 {
+		case 7: // h-file rook
+			to = from >> 2
+		case 0: // a-file rook
+			to = from << 2
+		}
+	}
+
+	return chess.NewMove(from, to, promotion)
+}
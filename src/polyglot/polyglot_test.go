@@ -0,0 +1,104 @@
+package polyglot
+
+import (
+	"Chess2020/src/chess"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func newMove(c1, c2 chess.Coordinate) *chess.Move {
+	m, _ := chess.NewMoveCoord(c1, c2)
+	return m
+}
+
+// Test vectors from the Polyglot book format specification
+func TestKey(t *testing.T) {
+	tests := []struct {
+		moves [][2]chess.Coordinate
+		key   uint64
+	}{
+		{nil, 0x463b96181691fc9c},
+		{[][2]chess.Coordinate{{"e2", "e4"}}, 0x823c9b50fd114196},
+		{[][2]chess.Coordinate{{"e2", "e4"}, {"d7", "d5"}}, 0x0756b94461c50fb0},
+		{[][2]chess.Coordinate{{"e2", "e4"}, {"d7", "d5"}, {"e4", "e5"}}, 0x662fafb965db29d4},
+		{[][2]chess.Coordinate{{"e2", "e4"}, {"d7", "d5"}, {"e4", "e5"}, {"f7", "f5"}}, 0x22a48b5a8e47ff78},
+		{[][2]chess.Coordinate{{"e2", "e4"}, {"d7", "d5"}, {"e4", "e5"}, {"f7", "f5"}, {"e1", "e2"}}, 0x652a607ca3f242c1},
+		{[][2]chess.Coordinate{{"e2", "e4"}, {"d7", "d5"}, {"e4", "e5"}, {"f7", "f5"}, {"e1", "e2"}, {"e8", "f7"}}, 0x00fdd303c946bdd9},
+		{[][2]chess.Coordinate{{"a2", "a4"}, {"b7", "b5"}, {"h2", "h4"}, {"b5", "b4"}, {"c2", "c4"}}, 0x3c8123ea7b067637},
+		{[][2]chess.Coordinate{{"a2", "a4"}, {"b7", "b5"}, {"h2", "h4"}, {"b5", "b4"}, {"c2", "c4"}, {"b4", "c3"}, {"a1", "a3"}}, 0x5c3f9b829b279560},
+	}
+
+	for i, test := range tests {
+		b := chess.NewBoard()
+		for _, m := range test.moves {
+			if err := b.Move(newMove(m[0], m[1])); err != nil {
+				t.Fatalf("Test %v: expected no errors, but got: %v", i, err)
+			}
+		}
+
+		if key := Key(b); key != test.key {
+			t.Errorf("Test %v: expected key %016x, but got: %016x", i, test.key, key)
+		}
+	}
+}
+
+func TestBook(t *testing.T) {
+	start := Key(chess.NewBoard())
+
+	// e2e4 with weight 3, and castling encoded as e1h1 under an unrelated key
+	var buf bytes.Buffer
+	for _, e := range []struct {
+		key          uint64
+		move, weight uint16
+	}{
+		{start - 1, 0x0107, 1},
+		{start, 4<<6 | 1<<9 | 4 | 3<<3, 3},
+		{start, 6<<6 | 0<<9 | 5 | 2<<3, 0},
+		{start + 1, 4<<6 | 7, 1},
+	} {
+		binary.Write(&buf, binary.BigEndian, e.key)
+		binary.Write(&buf, binary.BigEndian, e.move)
+		binary.Write(&buf, binary.BigEndian, e.weight)
+		binary.Write(&buf, binary.BigEndian, uint32(0))
+	}
+
+	bk, err := NewBook(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	entries, err := bk.Entries(chess.NewBoard())
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if len(entries) != 2 || *entries[0].Move != *newMove("e2", "e4") || *entries[1].Move != *newMove("g1", "f3") {
+		t.Fatalf("Expected e2e4 and g1f3, but got: %v", entries)
+	}
+
+	// zero-weight entries are never picked
+	for i := 0; i < 10; i++ {
+		if m, err := bk.Pick(chess.NewBoard()); err != nil || *m != *newMove("e2", "e4") {
+			t.Fatalf("Expected to pick e2e4, but got: %v (%v)", m, err)
+		}
+	}
+
+	// positions missing from the book have no move to pick
+	b := chess.NewBoard()
+	b.UnsafeMove(newMove("d2", "d4"))
+	if m, err := bk.Pick(b); m != nil || err != nil {
+		t.Fatalf("Expected no book move, but got: %v (%v)", m, err)
+	}
+}
+
+func TestDecodeCastling(t *testing.T) {
+	b := chess.NewBoard()
+	if m := decodeMove(b, 4<<6|7); *m != *newMove("e1", "g1") {
+		t.Errorf("Expected e1h1 to decode to e1g1, but got: %v", m)
+	}
+
+	if m := decodeMove(b, 4<<6|0); *m != *newMove("e1", "c1") {
+		t.Errorf("Expected e1a1 to decode to e1c1, but got: %v", m)
+	}
+}
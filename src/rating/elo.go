@@ -0,0 +1,72 @@
+// Package rating rates players from the results of their games, with the Elo and Glicko-2 systems, and computes
+// statistics of matches between two players.
+package rating
+
+import "math"
+
+const (
+	// DefaultElo is the Elo rating of players without any games
+	DefaultElo = 1500
+
+	// DefaultK is the K-factor of Elo updates, i.e. the most a rating can change in a single game
+	DefaultK = 32
+
+	// maximum difference between a performance rating and the average rating of the opponents, reached with a perfect
+	// or null score
+	maxPerformanceGap = 800
+)
+
+// Expected returns the expected score of a player rated a against a player rated b
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// EloChange returns the change of the Elo rating a of a player scoring the given points in a game against a player
+// rated b, with the K-factor k
+func EloChange(a, b, score, k float64) float64 {
+	return k * (score - Expected(a, b))
+}
+
+// Performance returns the performance rating of a player scoring the given points against opponents of the given
+// ratings, i.e. the rating for which the expected score is the actual score. A perfect or null score, whose
+// performance would be infinite, is capped to 800 points from the average rating of the opponents.
+func Performance(opponents []float64, score float64) float64 {
+	if len(opponents) == 0 {
+		return 0
+	}
+
+	average := 0.0
+	for _, r := range opponents {
+		average += r
+	}
+	average /= float64(len(opponents))
+
+	expected := func(r float64) float64 {
+		e := 0.0
+		for _, opp := range opponents {
+			e += Expected(r, opp)
+		}
+
+		return e
+	}
+
+	// the expected score increases with the rating, so the performance can be found by bisection
+	low, high := average-maxPerformanceGap, average+maxPerformanceGap
+	switch {
+	case score <= expected(low):
+		return low
+	case score >= expected(high):
+		return high
+	}
+
+	for high-low > 0.01 {
+		mid := (low + high) / 2
+		if expected(mid) < score {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2
+}
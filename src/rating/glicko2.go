@@ -0,0 +1,110 @@
+package rating
+
+import "math"
+
+const (
+	// Tau is the system constant of Glicko-2, constraining the change of volatility over time
+	Tau = 0.5
+
+	// glicko2Scale is the ratio between the Glicko and Glicko-2 scales
+	glicko2Scale = 173.7178
+
+	// convergence tolerance of the computation of volatility
+	epsilon = 0.000001
+)
+
+// Glicko2 is a Glicko-2 rating: a rating, its deviation, i.e. how uncertain it is, and the volatility of the player,
+// i.e. how much its strength fluctuates
+type Glicko2 struct {
+	Rating     float64 `json:"rating"`
+	RD         float64 `json:"rd"`
+	Volatility float64 `json:"volatility"`
+}
+
+// NewGlicko2 returns the rating of a player without any games
+func NewGlicko2() Glicko2 {
+	return Glicko2{
+		Rating:     DefaultElo,
+		RD:         350,
+		Volatility: 0.06,
+	}
+}
+
+// g reduces the impact of games against an opponent with the given deviation, on the Glicko-2 scale
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// Update returns the rating after a rating period, in which the player scored the given points against opponents of
+// the given ratings, as of the start of the period. Without any games, only the deviation grows.
+func (r Glicko2) Update(opponents []Glicko2, scores []float64) Glicko2 {
+	mu := (r.Rating - DefaultElo) / glicko2Scale
+	phi := r.RD / glicko2Scale
+	sigma := r.Volatility
+
+	if len(opponents) == 0 {
+		r.RD = math.Sqrt(phi*phi+sigma*sigma) * glicko2Scale
+		return r
+	}
+
+	// estimated variance of the rating from the games alone, and estimated improvement
+	vInv, sum := 0.0, 0.0
+	for i, opp := range opponents {
+		muJ := (opp.Rating - DefaultElo) / glicko2Scale
+		gJ := g(opp.RD / glicko2Scale)
+		e := 1 / (1 + math.Exp(-gJ*(mu-muJ)))
+
+		vInv += gJ * gJ * e * (1 - e)
+		sum += gJ * (scores[i] - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma = volatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+
+	return Glicko2{
+		Rating:     mu*glicko2Scale + DefaultElo,
+		RD:         phi * glicko2Scale,
+		Volatility: sigma,
+	}
+}
+
+// volatility returns the new volatility of a player, found with the Illinois algorithm
+func volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(Tau*Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*Tau) < 0 {
+			k++
+		}
+		B = a - k*Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,214 @@
+package rating
+
+import (
+	"Chess2020/src/chess"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Result is the result of a rated game between named players
+type Result struct {
+	White   string
+	Black   string
+	Outcome chess.Outcome
+}
+
+// Player holds the ratings and record of a player of a Ladder
+type Player struct {
+	Elo     float64 `json:"elo"`
+	Glicko2 Glicko2 `json:"glicko2"`
+
+	// Performance is the performance rating of the player in the last rating period it played in
+	Performance float64 `json:"performance"`
+
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+}
+
+// Games returns the number of games played by the player
+func (p *Player) Games() int {
+	return p.Wins + p.Draws + p.Losses
+}
+
+// Ladder rates a set of named players over successive rating periods, e.g. tournaments
+type Ladder struct {
+	// K is the K-factor of Elo updates
+	K float64 `json:"k"`
+
+	Players map[string]*Player `json:"players"`
+}
+
+// NewLadder returns a Ladder without any players
+func NewLadder() *Ladder {
+	return &Ladder{
+		K:       DefaultK,
+		Players: make(map[string]*Player),
+	}
+}
+
+// Load reads a Ladder saved at path, or returns a new Ladder if there is no file at path
+func Load(path string) (*Ladder, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewLadder(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	l := NewLadder()
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("could not read ladder %v: %v", path, err)
+	}
+
+	if l.Players == nil {
+		l.Players = make(map[string]*Player)
+	}
+
+	return l, nil
+}
+
+// Save writes the Ladder to path, replacing any previous version of the file only once it is fully written
+func (l *Ladder) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// player returns the player of the given name, adding it to the ladder if new
+func (l *Ladder) player(name string) *Player {
+	p, ok := l.Players[name]
+	if !ok {
+		p = &Player{Elo: DefaultElo, Glicko2: NewGlicko2()}
+		l.Players[name] = p
+	}
+
+	return p
+}
+
+// Rate updates the ratings of all players from the results of a rating period. All games of the period are rated
+// from the ratings as of its start, so their order doesn't matter.
+func (l *Ladder) Rate(results []Result) {
+	type period struct {
+		eloChange float64
+
+		opponents []string
+		scores    []float64
+	}
+
+	periods := make(map[string]*period)
+	record := func(name, opp string, score float64) {
+		p := l.player(name)
+		switch score {
+		case 1:
+			p.Wins++
+		case 0:
+			p.Losses++
+		default:
+			p.Draws++
+		}
+
+		if periods[name] == nil {
+			periods[name] = &period{}
+		}
+		periods[name].opponents = append(periods[name].opponents, opp)
+		periods[name].scores = append(periods[name].scores, score)
+	}
+
+	for _, r := range results {
+		score := 0.5
+		switch r.Outcome {
+		case chess.WhiteWon:
+			score = 1
+		case chess.BlackWon:
+			score = 0
+		}
+
+		record(r.White, r.Black, score)
+		record(r.Black, r.White, 1-score)
+	}
+
+	// snapshot of the ratings as of the start of the period
+	elo := make(map[string]float64)
+	glicko := make(map[string]Glicko2)
+	for name, p := range l.Players {
+		elo[name] = p.Elo
+		glicko[name] = p.Glicko2
+	}
+
+	for name, p := range l.Players {
+		period := periods[name]
+		if period == nil {
+			p.Glicko2 = p.Glicko2.Update(nil, nil)
+			continue
+		}
+
+		opponents := make([]Glicko2, len(period.opponents))
+		opponentElos := make([]float64, len(period.opponents))
+		score := 0.0
+		for i, opp := range period.opponents {
+			p.Elo += EloChange(elo[name], elo[opp], period.scores[i], l.K)
+			opponents[i] = glicko[opp]
+			opponentElos[i] = elo[opp]
+			score += period.scores[i]
+		}
+
+		p.Glicko2 = p.Glicko2.Update(opponents, period.scores)
+		p.Performance = Performance(opponentElos, score)
+	}
+}
+
+func (l *Ladder) String() string {
+	names := make([]string, 0, len(l.Players))
+	width := len("Name")
+	for name := range l.Players {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, b := l.Players[names[i]], l.Players[names[j]]
+		if a.Elo != b.Elo {
+			return a.Elo > b.Elo
+		}
+
+		return names[i] < names[j]
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%3v  %-*v  %6v  %11v  %7v  %5v  %4v  %4v  %4v\n", "#", width, "Name", "Elo", "Performance",
+		"Glicko2", "RD", "W", "D", "L")
+	for i, name := range names {
+		p := l.Players[name]
+		fmt.Fprintf(&sb, "%3v  %-*v  %6.0f  %11.0f  %7.0f  %5.0f  %4v  %4v  %4v\n", i+1, width, name, p.Elo,
+			p.Performance, p.Glicko2.Rating, p.Glicko2.RD, p.Wins, p.Draws, p.Losses)
+	}
+
+	return sb.String()
+}
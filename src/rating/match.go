@@ -0,0 +1,96 @@
+package rating
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"math"
+)
+
+// z-score of a two-sided 95% confidence interval
+const z95 = 1.959964
+
+// Match holds the results of the games between two players, from the point of view of the first one
+type Match struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Add adds the result of a game in which the first player played with the given color
+func (m *Match) Add(r *chess.GameResult, c chess.Color) {
+	switch {
+	case r.Outcome == chess.Draw:
+		m.Draws++
+	case (r.Outcome == chess.WhiteWon) == (c == chess.White):
+		m.Wins++
+	default:
+		m.Losses++
+	}
+}
+
+// Games returns the number of games played
+func (m *Match) Games() int {
+	return m.Wins + m.Draws + m.Losses
+}
+
+// Score returns the average points scored per game, between 0 and 1
+func (m *Match) Score() float64 {
+	if m.Games() == 0 {
+		return 0.5
+	}
+
+	return (float64(m.Wins) + float64(m.Draws)/2) / float64(m.Games())
+}
+
+// DrawRatio returns the ratio of games drawn
+func (m *Match) DrawRatio() float64 {
+	if m.Games() == 0 {
+		return 0
+	}
+
+	return float64(m.Draws) / float64(m.Games())
+}
+
+// eloDiff returns the Elo difference for which the expected score is the given score
+func eloDiff(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+// EloDiff returns the Elo difference between the players measured by the match, and the margin of error of a 95%
+// confidence interval around it. The difference is infinite if either player scored all points, and so is the margin
+// if the interval reaches such a score.
+func (m *Match) EloDiff() (float64, float64) {
+	n := float64(m.Games())
+	if n == 0 {
+		return 0, math.Inf(1)
+	}
+
+	score := m.Score()
+	if score == 0 || score == 1 {
+		return eloDiff(score), math.Inf(1)
+	}
+
+	deviation := (float64(m.Wins)*math.Pow(1-score, 2) + float64(m.Draws)*math.Pow(0.5-score, 2) +
+		float64(m.Losses)*math.Pow(0-score, 2)) / n
+	stddev := math.Sqrt(deviation / n)
+
+	low := math.Max(score-z95*stddev, 0)
+	high := math.Min(score+z95*stddev, 1)
+
+	return eloDiff(score), (eloDiff(high) - eloDiff(low)) / 2
+}
+
+// LOS returns the likelihood of superiority of the first player, i.e. the probability that it is the stronger one
+func (m *Match) LOS() float64 {
+	if m.Wins+m.Losses == 0 {
+		return 0.5
+	}
+
+	return 0.5 * (1 + math.Erf(float64(m.Wins-m.Losses)/math.Sqrt(2*float64(m.Wins+m.Losses))))
+}
+
+func (m *Match) String() string {
+	diff, margin := m.EloDiff()
+	return fmt.Sprintf("Score: %v - %v - %v [%.3f] %v\nElo difference: %.1f +/- %.1f, LOS: %.1f %%, draw ratio: %.1f %%",
+		m.Wins, m.Losses, m.Draws, m.Score(), m.Games(), diff, margin, 100*m.LOS(), 100*m.DrawRatio())
+}
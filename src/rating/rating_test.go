@@ -0,0 +1,157 @@
+package rating
+
+import (
+	"Chess2020/src/chess"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestElo(t *testing.T) {
+	if e := Expected(1600, 1400); !near(e, 0.7597, 0.0001) {
+		t.Fatalf("Expected a score of 0.7597, but got: %v", e)
+	}
+
+	if c := EloChange(1500, 1500, 1, DefaultK); c != 16 {
+		t.Fatalf("Expected a change of 16, but got: %v", c)
+	}
+
+	opponents := []float64{1400, 1500, 1600}
+	if p := Performance(opponents, 1.5); !near(p, 1500, 0.1) {
+		t.Fatalf("Expected a performance of 1500, but got: %v", p)
+	}
+
+	if p := Performance(opponents, 2); !near(Expected(p, 1400)+Expected(p, 1500)+Expected(p, 1600), 2, 0.001) {
+		t.Fatalf("Expected the performance to have an expected score of 2, but got: %v", p)
+	}
+
+	if p := Performance(opponents, 3); p != 2300 {
+		t.Fatalf("Expected a capped performance of 2300, but got: %v", p)
+	}
+}
+
+func TestGlicko2(t *testing.T) {
+	// the example of "Example of the Glicko-2 system" by Mark Glickman
+	r := Glicko2{Rating: 1500, RD: 200, Volatility: 0.06}.Update(
+		[]Glicko2{{1400, 30, 0.06}, {1550, 100, 0.06}, {1700, 300, 0.06}},
+		[]float64{1, 0, 0},
+	)
+
+	if !near(r.Rating, 1464.06, 0.01) || !near(r.RD, 151.52, 0.01) || !near(r.Volatility, 0.05999, 0.00001) {
+		t.Fatalf("Expected 1464.06, 151.52 and 0.05999, but got: %+v", r)
+	}
+
+	if r := NewGlicko2().Update(nil, nil); r.Rating != DefaultElo || r.RD <= 350 {
+		t.Fatalf("Expected the deviation to grow without games, but got: %+v", r)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var m Match
+	for i := 0; i < 40; i++ {
+		m.Add(&chess.GameResult{Outcome: chess.WhiteWon}, chess.Color(i%2)) // wins with white, losses with black
+		m.Add(&chess.GameResult{Outcome: chess.Draw}, chess.White)
+	}
+	for i := 0; i < 20; i++ {
+		m.Add(&chess.GameResult{Outcome: chess.BlackWon}, chess.Black)
+	}
+
+	if m.Wins != 40 || m.Draws != 40 || m.Losses != 20 || m.Score() != 0.6 || m.DrawRatio() != 0.4 {
+		t.Fatalf("Expected 40 wins, 40 draws and 20 losses, but got: %+v", m)
+	}
+
+	diff, margin := m.EloDiff()
+	if !near(diff, 70.4, 0.05) || !near(margin, 53.55, 0.01) {
+		t.Fatalf("Expected an Elo difference of 70.4 +/- 53.55, but got: %v +/- %v", diff, margin)
+	}
+
+	if los := m.LOS(); !near(los, 0.9951, 0.0001) {
+		t.Fatalf("Expected a LOS of 0.9951, but got: %v", los)
+	}
+}
+
+func TestLadder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ladder.json")
+
+	l, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no errors loading a missing ladder, but got: %v", err)
+	}
+
+	l.Rate([]Result{
+		{White: "a", Black: "b", Outcome: chess.WhiteWon},
+		{White: "b", Black: "a", Outcome: chess.Draw},
+	})
+
+	if err := l.Save(path); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	l, err = Load(path)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	a, b := l.Players["a"], l.Players["b"]
+	if a == nil || b == nil || a.Elo != 1516 || b.Elo != 1484 || a.Wins != 1 || a.Draws != 1 || b.Losses != 1 {
+		t.Fatalf("Expected a to gain 16 points, but got: %+v, %+v", a, b)
+	}
+
+	if a.Glicko2.Rating <= DefaultElo || a.Glicko2.RD >= 350 || a.Performance <= DefaultElo {
+		t.Fatalf("Expected a to be rated higher, with less deviation, but got: %+v", a)
+	}
+
+	// c joins, while b doesn't play
+	rd := b.Glicko2.RD
+	l.Rate([]Result{{White: "c", Black: "a", Outcome: chess.BlackWon}})
+	if l.Players["c"].Elo >= DefaultElo || l.Players["b"].Glicko2.RD <= rd {
+		t.Fatalf("Expected c to lose points, and b to be less certain, but got:\n%v", l)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	s := NewSPRT(0, 5, 0.05, 0.05)
+	if lower, upper := s.Bounds(); !near(lower, -2.944, 0.001) || !near(upper, 2.944, 0.001) {
+		t.Fatalf("Expected bounds of -2.944 and 2.944, but got: %v, %v", lower, upper)
+	}
+
+	if s.LLR() != 0 || s.Decision() != Undecided {
+		t.Fatalf("Expected an undecided test, but got: %v", s)
+	}
+
+	// equal players: the LLR drifts towards H0
+	s.Pairs = [5]int{10, 20, 40, 20, 10}
+	if llr := s.LLR(); !near(llr, -0.0346, 0.0001) {
+		t.Fatalf("Expected a LLR of -0.0346, but got: %v", llr)
+	}
+
+	s.Pairs = [5]int{1000, 2000, 4000, 2000, 1000}
+	if s.Decision() != AcceptH0 {
+		t.Fatalf("Expected H0 to be accepted, but got: %v", s)
+	}
+
+	// the first player is a lot stronger
+	s = NewSPRT(0, 5, 0.05, 0.05)
+	for s.Decision() == Undecided {
+		s.AddPair(1, 0.5)
+		s.AddPair(0.5, 0.5)
+	}
+
+	if s.Decision() != AcceptH1 || s.Pairs[3] == 0 || s.Pairs[2] == 0 {
+		t.Fatalf("Expected H1 to be accepted, but got: %v", s)
+	}
+
+	// all pairs ending the same still decide the test
+	s = NewSPRT(0, 5, 0.05, 0.05)
+	for i := 0; i < 100 && s.Decision() == Undecided; i++ {
+		s.AddPair(1, 1)
+	}
+
+	if s.Decision() != AcceptH1 {
+		t.Fatalf("Expected H1 to be accepted, but got: %v", s)
+	}
+}
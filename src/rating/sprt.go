@@ -0,0 +1,117 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+)
+
+// Decision is the outcome of a sequential probability ratio test
+type Decision uint8
+
+// Decisions of an SPRT
+const (
+	Undecided = iota
+	AcceptH0  = iota
+	AcceptH1  = iota
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Undecided:
+		return "undecided"
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	default:
+		panic("Unhandled decision type")
+	}
+}
+
+// SPRT is a sequential probability ratio test of the hypothesis H1, that the first of two players is Elo1 points
+// stronger than the second one, against H0, that it is Elo0 points stronger. It is fed with pairs of games played
+// from the same position with colors reversed, whose results are much less noisy than those of single games.
+type SPRT struct {
+	Elo0 float64
+	Elo1 float64
+
+	// Alpha and Beta are the probabilities of accepting H1 when H0 holds, and H0 when H1 holds
+	Alpha float64
+	Beta  float64
+
+	// Pairs counts the pairs of games played, by the points scored by the first player: 0, 1/2, 1, 3/2 and 2
+	Pairs [5]int
+}
+
+// NewSPRT returns an SPRT of H1 (elo1) against H0 (elo0), with the given error probabilities
+func NewSPRT(elo0, elo1, alpha, beta float64) *SPRT {
+	return &SPRT{
+		Elo0:  elo0,
+		Elo1:  elo1,
+		Alpha: alpha,
+		Beta:  beta,
+	}
+}
+
+// AddPair adds a pair of games, in which the first player scored the given points
+func (s *SPRT) AddPair(first, second float64) {
+	s.Pairs[int(math.Round(2*(first+second)))]++
+}
+
+// Bounds returns the log-likelihood ratios below which H0 is accepted, and above which H1 is accepted
+func (s *SPRT) Bounds() (float64, float64) {
+	return math.Log(s.Beta / (1 - s.Alpha)), math.Log((1 - s.Beta) / s.Alpha)
+}
+
+// LLR returns the log-likelihood ratio of H1 against H0, approximated from the mean and variance of the scores of
+// the pairs played
+func (s *SPRT) LLR() float64 {
+	if s.Pairs == [5]int{} {
+		return 0
+	}
+
+	// a prior of a single pair, spread evenly over all kinds of pairs, keeps the variance from being 0, or close to it,
+	// while all pairs end the same, which would make the approximation of the LLR meaningless
+	var counts [5]float64
+	n := 0.0
+	for i, count := range s.Pairs {
+		counts[i] = float64(count) + 0.2
+		n += counts[i]
+	}
+
+	mean := 0.0
+	for i, count := range counts {
+		mean += float64(i) / 4 * count
+	}
+	mean /= n
+
+	variance := 0.0
+	for i, count := range counts {
+		variance += math.Pow(float64(i)/4-mean, 2) * count
+	}
+	variance /= n
+
+	s0, s1 := Expected(s.Elo0, 0), Expected(s.Elo1, 0)
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// Decision returns whether the test has accepted either hypothesis
+func (s *SPRT) Decision() Decision {
+	llr := s.LLR()
+	lower, upper := s.Bounds()
+
+	switch {
+	case llr <= lower:
+		return AcceptH0
+	case llr >= upper:
+		return AcceptH1
+	default:
+		return Undecided
+	}
+}
+
+func (s *SPRT) String() string {
+	lower, upper := s.Bounds()
+	return fmt.Sprintf("LLR: %.2f (%.2f, %.2f) [%v, %v], pairs: %v-%v-%v-%v-%v, %v", s.LLR(), lower, upper, s.Elo0,
+		s.Elo1, s.Pairs[0], s.Pairs[1], s.Pairs[2], s.Pairs[3], s.Pairs[4], s.Decision())
+}
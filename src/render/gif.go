@@ -0,0 +1,134 @@
+package render
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// blendSteps is the number of colors between each pair of colors of a diagram in the palettes of GIF frames, for the
+// anti-aliased edges between them
+const blendSteps = 6
+
+// GIF writes an animated GIF of the moves played from start, one frame per position, with the squares of the last move
+// highlighted. Each frame shows for the given delay, and the last one for 3 times as long, before looping.
+func GIF(w io.Writer, start *chess.Board, moves []*chess.Move, o Options, delay time.Duration) error {
+	o = o.withDefaults()
+	b := start.Copy()
+
+	frames := []*image.RGBA{Image(b, o)}
+	for i, m := range moves {
+		if err := b.Move(m); err != nil {
+			return fmt.Errorf("invalid move %v (%v): %v", i+1, m, err)
+		}
+
+		frame := o
+		frame.Highlights = append([]Highlight{{Square: m.To}}, o.Highlights...)
+		if !m.IsDrop() {
+			frame.Highlights = append([]Highlight{{Square: m.From}}, frame.Highlights...)
+		}
+
+		frames = append(frames, Image(b, frame))
+	}
+
+	p := diagramPalette(o)
+	indexes := make(map[color.RGBA]uint8)
+
+	anim := &gif.GIF{}
+	for i, frame := range frames {
+		paletted := image.NewPaletted(frame.Bounds(), p)
+		for j := 0; j < len(frame.Pix); j += 4 {
+			c := color.RGBA{frame.Pix[j], frame.Pix[j+1], frame.Pix[j+2], frame.Pix[j+3]}
+			index, ok := indexes[c]
+			if !ok {
+				index = uint8(p.Index(c))
+				indexes[c] = index
+			}
+
+			paletted.Pix[j/4] = index
+		}
+
+		centiseconds := int(delay / (10 * time.Millisecond))
+		if i == len(frames)-1 {
+			centiseconds *= 3
+		}
+
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, centiseconds)
+	}
+
+	return gif.EncodeAll(w, anim)
+}
+
+// diagramPalette returns a palette of the colors of the diagrams drawn with the given options, with the colors between
+// each pair of them, or the Plan 9 palette if they don't fit in 256 colors
+func diagramPalette(o Options) color.Palette {
+	light, dark := o.Light.(color.NRGBA), o.Dark.(color.NRGBA)
+	base := []color.RGBA{
+		opaque(light), opaque(dark),
+		opaque(whitePieces.body), opaque(whitePieces.outline), opaque(blackPieces.body), opaque(blackPieces.detail),
+	}
+
+	highlights := []color.NRGBA{DefaultHighlight}
+	for _, h := range o.Highlights {
+		highlights = append(highlights, nrgba(h.Color, DefaultHighlight))
+	}
+	for _, a := range o.Arrows {
+		highlights = append(highlights, nrgba(a.Color, DefaultArrow))
+	}
+
+	for _, h := range highlights {
+		base = append(base, over(h, light), over(h, dark))
+	}
+
+	seen := make(map[color.RGBA]bool)
+	var p color.Palette
+	add := func(c color.RGBA) {
+		if !seen[c] {
+			seen[c] = true
+			p = append(p, c)
+		}
+	}
+
+	for _, c := range base {
+		add(c)
+	}
+
+	for i, c1 := range base {
+		for _, c2 := range base[i+1:] {
+			for step := 1; step < blendSteps; step++ {
+				add(blend(c1, c2, float64(step)/blendSteps))
+			}
+		}
+	}
+
+	if len(p) > 256 {
+		return palette.Plan9
+	}
+
+	return p
+}
+
+// opaque returns c as an opaque color
+func opaque(c color.NRGBA) color.RGBA {
+	return color.RGBA{c.R, c.G, c.B, 0xff}
+}
+
+// over returns the color of c drawn over the opaque color under
+func over(c, under color.NRGBA) color.RGBA {
+	return blend(opaque(under), opaque(c), float64(c.A)/0xff)
+}
+
+// blend returns the color a fraction t of the way from c1 to c2
+func blend(c1, c2 color.RGBA, t float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-t) + float64(b)*t + 0.5)
+	}
+
+	return color.RGBA{mix(c1.R, c2.R), mix(c1.G, c2.G), mix(c1.B, c2.B), 0xff}
+}
@@ -0,0 +1,210 @@
+package render
+
+import (
+	"Chess2020/src/chess"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// subsamples is the number of rows sampled in each row of pixels when filling polygons, for anti-aliasing
+const subsamples = 4
+
+// Image returns the diagram of b as an image, Options.Size pixels wide and high
+func Image(b *chess.Board, o Options) *image.RGBA {
+	o = o.withDefaults()
+	img := image.NewRGBA(image.Rect(0, 0, o.Size, o.Size))
+	for _, e := range elements(b, o) {
+		e.draw(img)
+	}
+
+	return img
+}
+
+// PNG writes the diagram of b as a PNG image
+func PNG(w io.Writer, b *chess.Board, o Options) error {
+	return png.Encode(w, Image(b, o))
+}
+
+// draw draws the element on img
+func (e element) draw(img draw.Image) {
+	if e.text != "" {
+		var cells [][]point
+		for i := 0; i < len(e.text); i++ {
+			// characters are 5 cells wide, with a cell of space between them
+			at := point{e.at.x + float64(6*i)*e.size/7, e.at.y}
+			cells = append(cells, glyphCells(e.text[i], at, e.size)...)
+		}
+
+		fill(img, cells, e.fill)
+		return
+	}
+
+	if len(e.polygons) == 0 {
+		return
+	}
+
+	if !e.line {
+		fill(img, e.polygons, e.fill)
+	}
+
+	if e.stroke.A > 0 && e.width > 0 {
+		fill(img, strokePolygons(e.polygons[0], e.width, !e.line), e.stroke)
+	}
+}
+
+// fill fills polygons on img with color c, by the nonzero winding rule
+func fill(img draw.Image, polygons [][]point, c color.NRGBA) {
+	if c.A == 0 {
+		return
+	}
+
+	r := bounds(polygons).Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	draw.DrawMask(img, r, image.NewUniform(c), image.Point{}, rasterize(polygons, r), r.Min, draw.Over)
+}
+
+// bounds returns the smallest rectangle of pixels containing the polygons
+func bounds(polygons [][]point) image.Rectangle {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+
+	if minX > maxX {
+		return image.Rectangle{}
+	}
+
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// crossing is where an edge of a polygon crosses a row sampled, with the direction of the edge: 1 down, -1 up
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// rasterize returns the coverage of the pixels of r by the polygons, filled by the nonzero winding rule. Rows are
+// sampled subsamples times per pixel, and spans covered partially horizontally by their exact fraction.
+func rasterize(polygons [][]point, r image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(r)
+	coverage := make([]float64, r.Dx()+1)
+
+	var crossings []crossing
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+
+		for k := 0; k < subsamples; k++ {
+			sy := float64(y) + (float64(k)+0.5)/subsamples
+
+			crossings = crossings[:0]
+			for _, polygon := range polygons {
+				for i := range polygon {
+					p, q, dir := polygon[i], polygon[(i+1)%len(polygon)], 1
+					if p.y > q.y {
+						p, q, dir = q, p, -1
+					}
+
+					if sy < p.y || sy >= q.y {
+						continue
+					}
+
+					crossings = append(crossings, crossing{p.x + (sy-p.y)*(q.x-p.x)/(q.y-p.y) - float64(r.Min.X), dir})
+				}
+			}
+
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+			for i, c := range crossings {
+				if winding != 0 {
+					cover(coverage, crossings[i-1].x, c.x, 1.0/subsamples)
+				}
+
+				winding += c.dir
+			}
+		}
+
+		for x := 0; x < r.Dx(); x++ {
+			mask.Pix[(y-r.Min.Y)*mask.Stride+x] = uint8(math.Min(coverage[x], 1)*0xff + 0.5)
+		}
+	}
+
+	return mask
+}
+
+// cover adds weight w to the coverage of the pixels in [x0, x1), in proportion to how much of each is in it
+func cover(coverage []float64, x0, x1, w float64) {
+	x0, x1 = math.Max(x0, 0), math.Min(x1, float64(len(coverage)-1))
+	if x1 <= x0 {
+		return
+	}
+
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		coverage[i0] += (x1 - x0) * w
+		return
+	}
+
+	coverage[i0] += (float64(i0+1) - x0) * w
+	for i := i0 + 1; i < i1; i++ {
+		coverage[i] += w
+	}
+	coverage[i1] += (x1 - float64(i1)) * w
+}
+
+// strokePolygons returns polygons covering a line of the given width through the points, closed or not, with round
+// joins and caps. All polygons wind the same way, so that filling them by the nonzero rule fills their union.
+func strokePolygons(points []point, width float64, closed bool) [][]point {
+	segments := len(points) - 1
+	if closed {
+		segments = len(points)
+	}
+
+	var polygons [][]point
+	for i := 0; i < segments; i++ {
+		p, q := points[i], points[(i+1)%len(points)]
+		length := math.Hypot(q.x-p.x, q.y-p.y)
+		if length == 0 {
+			continue
+		}
+
+		nx, ny := -(q.y-p.y)/length*width/2, (q.x-p.x)/length*width/2
+		polygons = append(polygons, clockwise([]point{{p.x + nx, p.y + ny}, {q.x + nx, q.y + ny}, {q.x - nx, q.y - ny}, {p.x - nx, p.y - ny}}))
+	}
+
+	for _, p := range points {
+		polygons = append(polygons, clockwise(circle(p.x, p.y, width/2)))
+	}
+
+	return polygons
+}
+
+// clockwise returns the polygon, reversed if needed to wind clockwise on the board, where y is down
+func clockwise(polygon []point) []point {
+	area := 0.0
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		area += p.x*q.y - q.x*p.y
+	}
+
+	if area < 0 {
+		for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+			polygon[i], polygon[j] = polygon[j], polygon[i]
+		}
+	}
+
+	return polygon
+}
@@ -0,0 +1,185 @@
+package render
+
+import (
+	"Chess2020/src/chess"
+	"image/color"
+	"math"
+)
+
+// pieceBox is the width and height of the box pieces are designed in, scaled to the size of a square when drawn
+const pieceBox = 45
+
+// Colors of the pieces of each player: the fill and outline of their bodies, and their details, e.g. eyes
+var (
+	whitePieces = pieceColors{body: color.NRGBA{0xff, 0xff, 0xff, 0xff}, outline: color.NRGBA{0, 0, 0, 0xff}, detail: color.NRGBA{0, 0, 0, 0xff}}
+	blackPieces = pieceColors{body: color.NRGBA{0x20, 0x20, 0x20, 0xff}, outline: color.NRGBA{0, 0, 0, 0xff}, detail: color.NRGBA{0xf0, 0xf0, 0xf0, 0xff}}
+)
+
+type pieceColors struct {
+	body, outline, detail color.NRGBA
+}
+
+// shapeKind is how a shape of a piece is drawn
+type shapeKind uint8
+
+const (
+	// body is filled with the color of the piece, and outlined
+	body shapeKind = iota
+
+	// line is a line in the detail color
+	line
+
+	// dot is filled with the detail color
+	dot
+)
+
+// shape is a part of a piece, a polygon or line in the box of pieces, with y down
+type shape struct {
+	kind   shapeKind
+	points []point
+}
+
+// PieceSet is a set of drawings of the pieces, the same for both players but for their colors
+type PieceSet struct {
+	Name string
+
+	// shapes [p] are the shapes of the piece type p, by white piece, in the order they are drawn
+	shapes [6][]shape
+
+	// outline is the width of outlines and lines, in the box of pieces
+	outline float64
+}
+
+// elements returns the elements of piece p standing on the square with the given top left corner and size
+func (ps *PieceSet) elements(p chess.Piece, corner point, size float64) []element {
+	colors := whitePieces
+	if p.Color() == chess.Black {
+		colors = blackPieces
+	}
+
+	scale := size / pieceBox
+	var elems []element
+	for _, s := range ps.shapes[int(p)%6] {
+		points := make([]point, len(s.points))
+		for i, q := range s.points {
+			points[i] = point{corner.x + q.x*scale, corner.y + q.y*scale}
+		}
+
+		e := element{polygons: [][]point{points}, width: ps.outline * scale}
+		switch s.kind {
+		case body:
+			e.fill, e.stroke = colors.body, colors.outline
+		case line:
+			e.stroke, e.line = colors.detail, true
+		case dot:
+			e.fill, e.width = colors.detail, 0
+		}
+
+		elems = append(elems, e)
+	}
+
+	return elems
+}
+
+// circle returns a polygon approximating a circle
+func circle(cx, cy, r float64) []point {
+	const n = 24
+	points := make([]point, n)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / n
+		points[i] = point{cx + r*math.Cos(a), cy + r*math.Sin(a)}
+	}
+
+	return points
+}
+
+// polygon returns the polygon through the given coordinates, x and y in turn
+func polygon(xy ...float64) []point {
+	points := make([]point, len(xy)/2)
+	for i := range points {
+		points[i] = point{xy[2*i], xy[2*i+1]}
+	}
+
+	return points
+}
+
+// Standard is the default piece set: outlined Staunton figurines, white with black details for White, and black with
+// white details for Black
+var Standard = &PieceSet{
+	Name:    "standard",
+	outline: 1.5,
+	shapes: [6][]shape{
+		chess.WhiteKing: {
+			{body, polygon(21.5, 4, 23.5, 4, 23.5, 7, 26, 7, 26, 9, 23.5, 9, 23.5, 12.5, 21.5, 12.5, 21.5, 9, 19, 9,
+				19, 7, 21.5, 7)},
+			{body, polygon(22.5, 12.5, 25, 15, 25.5, 20, 29, 17, 34, 16.5, 37.5, 20, 37, 25, 32.5, 30, 32.5, 34, 30, 38,
+				15, 38, 12.5, 34, 12.5, 30, 8, 25, 7.5, 20, 11, 16.5, 16, 17, 19.5, 20, 20, 15)},
+			{line, polygon(12.5, 30, 32.5, 30)},
+			{line, polygon(12.5, 34, 32.5, 34)},
+			{line, polygon(22.5, 21, 22.5, 28)},
+		},
+		chess.WhiteQueen: {
+			{body, polygon(9, 13, 14, 25, 15.5, 9.5, 19.5, 24, 22.5, 8, 25.5, 24, 29.5, 9.5, 31, 25, 36, 13, 32, 30,
+				33, 34, 30, 38, 15, 38, 12, 34, 13, 30)},
+			{body, circle(9, 13, 2.2)},
+			{body, circle(15.5, 9.5, 2.2)},
+			{body, circle(22.5, 8, 2.2)},
+			{body, circle(29.5, 9.5, 2.2)},
+			{body, circle(36, 13, 2.2)},
+			{line, polygon(13, 30, 32, 30)},
+			{line, polygon(12, 34, 33, 34)},
+		},
+		chess.WhiteKnight: {
+			{body, polygon(12, 39, 13, 33, 16, 29, 20, 26, 21, 23, 17, 24, 13, 26, 10, 27, 8, 25, 8, 22, 12, 17, 16, 12,
+				18, 9, 19, 6, 21, 9, 23, 8, 29, 10, 33, 15, 35, 22, 35, 30, 34, 39)},
+			{dot, circle(15.5, 15.5, 1.3)},
+			{line, polygon(9.5, 23.5, 10.5, 22.5)},
+			{line, polygon(24, 11, 29, 14, 31.5, 20, 32, 27)},
+		},
+		chess.WhiteBishop: {
+			{body, polygon(18, 31, 27, 31, 28, 35, 34, 36, 35, 39, 10, 39, 11, 36, 17, 35)},
+			{body, rect(17, 28, 11, 3)},
+			{body, polygon(22.5, 10.5, 26, 13, 29, 17, 30, 21, 29, 25, 27, 28, 18, 28, 16, 25, 15, 21, 16, 17, 19, 13)},
+			{body, circle(22.5, 8, 2.5)},
+			{line, polygon(22.5, 15, 22.5, 23)},
+			{line, polygon(19, 19, 26, 19)},
+		},
+		chess.WhiteRook: {
+			{body, polygon(12, 9, 16, 9, 16, 12, 20, 12, 20, 9, 25, 9, 25, 12, 29, 12, 29, 9, 33, 9, 33, 15, 30, 18, 30,
+				31, 33, 34, 33, 36, 36, 36, 36, 40, 9, 40, 9, 36, 12, 36, 12, 34, 15, 31, 15, 18, 12, 15)},
+			{line, polygon(12, 15, 33, 15)},
+			{line, polygon(15, 31, 30, 31)},
+			{line, polygon(12, 36, 33, 36)},
+		},
+		chess.WhitePawn: {
+			{body, polygon(19, 22, 26, 22, 29.5, 33, 33, 37, 33, 40, 12, 40, 12, 37, 15.5, 33)},
+			{body, circle(22.5, 14, 5.5)},
+		},
+	},
+}
+
+// Letters is a piece set for plain diagrams: discs marked with the letter of the piece, as in FEN, e.g. N for
+// knights
+var Letters = &PieceSet{
+	Name:    "letters",
+	outline: 1.5,
+	shapes: [6][]shape{
+		chess.WhiteKing:   letter('K'),
+		chess.WhiteQueen:  letter('Q'),
+		chess.WhiteKnight: letter('N'),
+		chess.WhiteBishop: letter('B'),
+		chess.WhiteRook:   letter('R'),
+		chess.WhitePawn:   letter('P'),
+	},
+}
+
+// letter returns the shapes of a disc marked with the given letter
+func letter(c byte) []shape {
+	const height = 21
+	shapes := []shape{{body, circle(22.5, 22.5, 17)}}
+	for _, cell := range glyphCells(c, point{22.5 - textWidth(height, 1)/2, 22.5 - height/2}, height) {
+		shapes = append(shapes, shape{dot, cell})
+	}
+
+	return shapes
+}
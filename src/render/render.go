@@ -0,0 +1,246 @@
+// Package render draws diagrams of boards, e.g. for reports and puzzle sheets, as SVG, as PNG, or as animated GIFs of
+// whole games. Pieces are drawn from piece sets embedded in the package, so no fonts or external services are needed.
+package render
+
+import (
+	"Chess2020/src/chess"
+	"image/color"
+	"math"
+	"math/bits"
+)
+
+// DefaultSize is the width and height of a board, in pixels, when Options don't give it
+const DefaultSize = 400
+
+// Default colors of squares, highlights and arrows
+var (
+	DefaultLight     = color.NRGBA{0xf0, 0xd9, 0xb5, 0xff}
+	DefaultDark      = color.NRGBA{0xb5, 0x88, 0x63, 0xff}
+	DefaultHighlight = color.NRGBA{0xcd, 0xd2, 0x6a, 0xc0}
+	DefaultArrow     = color.NRGBA{0x15, 0x78, 0x1b, 0xa0}
+)
+
+// Options are the options of drawing a board. The zero value draws a board of DefaultSize from the side of White,
+// with the Standard pieces.
+type Options struct {
+	// Size is the width and height of the board, in pixels
+	Size int
+
+	// Perspective is the player seeing the board from the bottom
+	Perspective chess.Color
+
+	// Coordinates labels the ranks and files, in the corners of the squares along the edges of the board
+	Coordinates bool
+
+	// Highlights are squares colored over their own color, e.g. those of the last move
+	Highlights []Highlight
+
+	// Arrows are drawn over the pieces, e.g. to show a plan
+	Arrows []Arrow
+
+	// Pieces is the piece set the pieces are drawn with
+	Pieces *PieceSet
+
+	// Light and Dark are the colors of the squares
+	Light, Dark color.Color
+}
+
+// Highlight colors a square over its own color, with DefaultHighlight if Color is nil
+type Highlight struct {
+	Square chess.Square
+	Color  color.Color
+}
+
+// Arrow is an arrow from the center of a square to the center of another, of DefaultArrow if Color is nil
+type Arrow struct {
+	From, To chess.Square
+	Color    color.Color
+}
+
+// point is a point on the board, in pixels from its top left corner
+type point struct {
+	x, y float64
+}
+
+// element is a part of a diagram: polygons filled and outlined, a line through the points of the first polygon, or a
+// text. Colors are transparent where nothing is drawn.
+type element struct {
+	polygons [][]point
+
+	fill   color.NRGBA
+	stroke color.NRGBA
+	width  float64
+
+	// line strokes the points of the first polygon without closing it, instead of filling the polygons
+	line bool
+
+	// text is drawn with its top left corner at at, size pixels high, in the fill color
+	text string
+	at   point
+	size float64
+}
+
+// nrgba returns c as a non-premultiplied color, or def if c is nil
+func nrgba(c color.Color, def color.NRGBA) color.NRGBA {
+	if c == nil {
+		return def
+	}
+
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
+
+// withDefaults returns the options with the defaults of unset ones
+func (o Options) withDefaults() Options {
+	if o.Size <= 0 {
+		o.Size = DefaultSize
+	}
+
+	if o.Pieces == nil {
+		o.Pieces = Standard
+	}
+
+	o.Light = nrgba(o.Light, DefaultLight)
+	o.Dark = nrgba(o.Dark, DefaultDark)
+	return o
+}
+
+// squareSize returns the width and height of a square, in pixels
+func (o Options) squareSize() float64 {
+	return float64(o.Size) / 8
+}
+
+// fileRank returns the file and rank of a square, from 0 for the a-file and 1st rank
+func fileRank(s chess.Square) (int, int) {
+	i := bits.TrailingZeros64(uint64(s))
+	return 7 - i%8, i / 8
+}
+
+// corner returns the top left corner of the square on the given file and rank, as seen from the perspective
+func (o Options) corner(file, rank int) point {
+	if o.Perspective == chess.Black {
+		file, rank = 7-file, 7-rank
+	}
+
+	size := o.squareSize()
+	return point{float64(file) * size, float64(7-rank) * size}
+}
+
+// center returns the center of a square
+func (o Options) center(s chess.Square) point {
+	c := o.corner(fileRank(s))
+	size := o.squareSize()
+	return point{c.x + size/2, c.y + size/2}
+}
+
+// rect returns the polygon of a rectangle
+func rect(x, y, w, h float64) []point {
+	return []point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+}
+
+// elements returns the elements of the diagram of b, in the order they are drawn
+func elements(b *chess.Board, o Options) []element {
+	size := o.squareSize()
+	light, dark := o.Light.(color.NRGBA), o.Dark.(color.NRGBA)
+
+	var elems []element
+	for rank := 0; rank < 8; rank++ {
+		for file := 0; file < 8; file++ {
+			c := o.corner(file, rank)
+			fill := dark
+			if (file+rank)%2 == 1 {
+				fill = light
+			}
+
+			elems = append(elems, element{polygons: [][]point{rect(c.x, c.y, size, size)}, fill: fill})
+		}
+	}
+
+	for _, h := range o.Highlights {
+		c := o.corner(fileRank(h.Square))
+		elems = append(elems, element{polygons: [][]point{rect(c.x, c.y, size, size)}, fill: nrgba(h.Color, DefaultHighlight)})
+	}
+
+	if o.Coordinates {
+		elems = append(elems, coordinates(o, light, dark)...)
+	}
+
+	for s := chess.Square(1); s != 0; s <<= 1 {
+		if p := b.PieceAt(s); p != chess.EmptyPiece {
+			elems = append(elems, o.Pieces.elements(p, o.corner(fileRank(s)), size)...)
+		}
+	}
+
+	for _, a := range o.Arrows {
+		elems = append(elems, arrow(o.center(a.From), o.center(a.To), size, nrgba(a.Color, DefaultArrow)))
+	}
+
+	return elems
+}
+
+// coordinates returns the labels of the ranks, in the top left corner of the squares on the left edge, and of the
+// files, in the bottom right corner of the squares on the bottom edge, each in the color of the other squares
+func coordinates(o Options, light, dark color.NRGBA) []element {
+	size := o.squareSize()
+	height := size / 5
+	margin := size / 20
+
+	var elems []element
+	for i := 0; i < 8; i++ {
+		// the rank on the left edge, and the file on the bottom edge, i squares from the bottom left corner
+		rank, file := i, i
+		if o.Perspective == chess.Black {
+			rank, file = 7-i, 7-i
+		}
+
+		label := light
+		if i%2 == 1 {
+			label = dark
+		}
+
+		c := o.corner(0, rank)
+		if o.Perspective == chess.Black {
+			c = o.corner(7, rank)
+		}
+
+		elems = append(elems, element{text: string(rune('1' + rank)), at: point{c.x + margin, c.y + margin}, size: height, fill: label})
+
+		c = o.corner(file, 0)
+		if o.Perspective == chess.Black {
+			c = o.corner(file, 7)
+		}
+
+		x := c.x + size - margin - textWidth(height, 1)
+		elems = append(elems, element{text: string(rune('a' + file)), at: point{x, c.y + size - margin - height}, size: height, fill: label})
+	}
+
+	return elems
+}
+
+// arrow returns an arrow from one point to another, whose head ends at the latter
+func arrow(from, to point, size float64, c color.NRGBA) element {
+	dx, dy := to.x-from.x, to.y-from.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return element{}
+	}
+
+	// unit vectors along the arrow and across it
+	ux, uy := dx/length, dy/length
+	vx, vy := -uy, ux
+
+	shaft, headWidth, headLength := size*0.09, size*0.25, math.Min(size*0.45, length)
+	base := point{to.x - ux*headLength, to.y - uy*headLength}
+
+	return element{
+		polygons: [][]point{{
+			{from.x + vx*shaft, from.y + vy*shaft},
+			{base.x + vx*shaft, base.y + vy*shaft},
+			{base.x + vx*headWidth, base.y + vy*headWidth},
+			to,
+			{base.x - vx*headWidth, base.y - vy*headWidth},
+			{base.x - vx*shaft, base.y - vy*shaft},
+			{from.x - vx*shaft, from.y - vy*shaft},
+		}},
+		fill: c,
+	}
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"Chess2020/src/chess"
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMove(c1, c2 chess.Coordinate) *chess.Move {
+	m, _ := chess.NewMoveCoord(c1, c2)
+	return m
+}
+
+// square returns the square of the given coordinate
+func square(c chess.Coordinate) chess.Square {
+	s, _ := c.Square()
+	return s
+}
+
+// near returns true iff the colors differ by at most 1 in each channel, e.g. from rounding
+func near(c color.Color, rgba color.RGBA) bool {
+	c1 := color.RGBAModel.Convert(c).(color.RGBA)
+	for _, d := range []int{int(c1.R) - int(rgba.R), int(c1.G) - int(rgba.G), int(c1.B) - int(rgba.B), int(c1.A) - int(rgba.A)} {
+		if d < -1 || d > 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestImage(t *testing.T) {
+	b := chess.NewBoard()
+	e4 := square("e4")
+
+	tests := []struct {
+		options Options
+		x, y    int
+		color   color.RGBA
+	}{
+		// a1 is dark, b1 light, from either side
+		{Options{}, 1, 398, opaque(DefaultDark)},
+		{Options{}, 51, 398, opaque(DefaultLight)},
+		{Options{Perspective: chess.Black}, 1, 398, opaque(DefaultDark)},
+		{Options{Size: 80}, 11, 79, opaque(DefaultLight)},
+
+		// the pawns on e2 and e7, seen from either side
+		{Options{}, 225, 333, opaque(whitePieces.body)},
+		{Options{}, 225, 83, opaque(blackPieces.body)},
+		{Options{Perspective: chess.Black}, 175, 83, opaque(whitePieces.body)},
+		{Options{Pieces: Letters}, 213, 375, opaque(whitePieces.body)},
+
+		{Options{Highlights: []Highlight{{Square: e4}}}, 201, 201, over(DefaultHighlight, DefaultLight)},
+		{Options{Highlights: []Highlight{{Square: e4, Color: color.RGBA{0, 0, 0xff, 0xff}}}}, 201, 201, color.RGBA{0, 0, 0xff, 0xff}},
+		{Options{Arrows: []Arrow{{From: square("a3"), To: square("h3")}}}, 175, 275, over(DefaultArrow, DefaultLight)},
+	}
+
+	for i, test := range tests {
+		img := Image(b, test.options)
+		if size := test.options.Size; size != 0 && img.Bounds().Dx() != size {
+			t.Errorf("Test %v: expected an image of %v pixels, but got: %v", i, size, img.Bounds())
+		}
+
+		if c := img.RGBAAt(test.x, test.y); !near(c, test.color) {
+			t.Errorf("Test %v: expected %v at (%v, %v), but got: %v", i, test.color, test.x, test.y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, b, Options{Size: 200, Coordinates: true}); err != nil {
+		t.Fatalf("Expected no error writing a PNG, but got: %v", err)
+	}
+
+	if img, err := png.Decode(&buf); err != nil || img.Bounds().Dx() != 200 || img.Bounds().Dy() != 200 {
+		t.Errorf("Expected a 200x200 PNG, but got: %v (%v)", img, err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	b := chess.NewBoard()
+	o := Options{Size: 320, Coordinates: true, Highlights: []Highlight{{Square: square("e4")}},
+		Arrows: []Arrow{{From: square("g1"), To: square("f3")}}}
+
+	svg := SVG(b, o)
+
+	elements := make(map[string]int)
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("Expected well-formed SVG, but got: %v\n%v", err, svg)
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+
+	// the squares, highlight and arrow, the shapes of the kings, queens, knights, bishops, rooks and pawns, and the
+	// labels of the ranks and files
+	if elements["svg"] != 1 || elements["polygon"] != 64+1+1+2*2+2*6+4*2+4*4+4*1+16*2 || elements["text"] != 16 {
+		t.Errorf("Expected all squares, pieces, labels and the arrow, but got: %v", elements)
+	}
+
+	if !strings.Contains(svg, `width="320" height="320"`) || !strings.Contains(svg, `fill-opacity="0.75"`) {
+		t.Errorf("Expected a 320 pixels wide SVG with a translucent highlight, but got:\n%v", svg)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, b, o); err != nil || buf.String() != svg {
+		t.Errorf("Expected WriteSVG to write the SVG, but got: %v", err)
+	}
+}
+
+func TestGIF(t *testing.T) {
+	b := chess.NewBoard()
+	moves := []*chess.Move{newMove("e2", "e4"), newMove("e7", "e5"), newMove("g1", "f3")}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, b, moves, Options{Size: 160}, 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected no error writing a GIF, but got: %v", err)
+	}
+
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Expected a GIF, but got: %v", err)
+	}
+
+	if len(g.Image) != 4 || g.Delay[0] != 50 || g.Delay[3] != 150 || g.Image[0].Bounds().Dx() != 160 {
+		t.Errorf("Expected 4 frames of 160 pixels, shown 0.5s and the last 1.5s, but got: %v frames, delays %v",
+			len(g.Image), g.Delay)
+	}
+
+	// the squares of the last move are highlighted: e2 and e4 in the 2nd frame, but not the 1st
+	if c := g.Image[1].At(81, 121); !near(c, over(DefaultHighlight, DefaultLight)) || !near(g.Image[0].At(81, 121), opaque(DefaultLight)) {
+		t.Errorf("Expected the last move to be highlighted, but got: %v", c)
+	}
+
+	if err := GIF(&buf, b, []*chess.Move{newMove("e2", "e5")}, Options{}, time.Second); err == nil {
+		t.Errorf("Expected an error for an invalid move")
+	}
+
+	if p := diagramPalette(Options{}.withDefaults()); len(p) > 256 || len(p) < 100 {
+		t.Errorf("Expected a palette of the colors of diagrams, but got %v colors", len(p))
+	}
+}
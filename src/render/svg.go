@@ -0,0 +1,101 @@
+package render
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVG returns the diagram of b as an SVG image, Options.Size pixels wide and high
+func SVG(b *chess.Board, o Options) string {
+	o = o.withDefaults()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		o.Size, o.Size, o.Size, o.Size)
+
+	for _, e := range elements(b, o) {
+		e.writeSVG(&sb)
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+// WriteSVG writes the diagram of b as an SVG image
+func WriteSVG(w io.Writer, b *chess.Board, o Options) error {
+	_, err := io.WriteString(w, SVG(b, o))
+	return err
+}
+
+// writeSVG writes the element as an SVG element, on a line of its own
+func (e element) writeSVG(sb *strings.Builder) {
+	switch {
+	case e.text != "":
+		// the glyphs of diagram fonts are about 70% of the font size high, from the baseline
+		fmt.Fprintf(sb, `<text x="%v" y="%v" font-family="sans-serif" font-size="%v" %v>%v</text>`+"\n",
+			number(e.at.x), number(e.at.y+e.size), number(e.size/0.7), paint("fill", e.fill), e.text)
+		return
+	case len(e.polygons) == 0:
+		return
+	}
+
+	var attributes []string
+	if e.line {
+		attributes = append(attributes, `fill="none"`)
+	} else {
+		attributes = append(attributes, paint("fill", e.fill))
+	}
+
+	if e.stroke.A > 0 && e.width > 0 {
+		attributes = append(attributes, paint("stroke", e.stroke), fmt.Sprintf(`stroke-width="%v"`, number(e.width)),
+			`stroke-linejoin="round" stroke-linecap="round"`)
+	}
+
+	switch {
+	case e.line:
+		fmt.Fprintf(sb, `<polyline points="%v" %v/>`+"\n", points(e.polygons[0]), strings.Join(attributes, " "))
+	case len(e.polygons) == 1:
+		fmt.Fprintf(sb, `<polygon points="%v" %v/>`+"\n", points(e.polygons[0]), strings.Join(attributes, " "))
+	default:
+		var d []string
+		for _, polygon := range e.polygons {
+			d = append(d, "M"+points(polygon)+"Z")
+		}
+
+		fmt.Fprintf(sb, `<path d="%v" %v/>`+"\n", strings.Join(d, " "), strings.Join(attributes, " "))
+	}
+}
+
+// paint returns the SVG attributes painting the fill or the stroke with color c, with its opacity if not opaque
+func paint(attribute string, c color.NRGBA) string {
+	if c.A == 0 {
+		return attribute + `="none"`
+	}
+
+	s := fmt.Sprintf(`%v="#%02x%02x%02x"`, attribute, c.R, c.G, c.B)
+	if c.A < 0xff {
+		s += fmt.Sprintf(` %v-opacity="%v"`, attribute, number(float64(c.A)/0xff))
+	}
+
+	return s
+}
+
+// points returns the points of an SVG polygon or polyline, e.g. "0,0 10,0 10,10"
+func points(polygon []point) string {
+	s := make([]string, len(polygon))
+	for i, p := range polygon {
+		s[i] = number(p.x) + "," + number(p.y)
+	}
+
+	return strings.Join(s, " ")
+}
+
+// number returns x with at most 2 decimals, as short as possible
+func number(x float64) string {
+	return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"Chess2020/src/chess"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status of a game
+const (
+	// StatusWaiting is the status of a game waiting for players to join
+	StatusWaiting = "waiting"
+
+	// StatusPlaying is the status of a game in progress
+	StatusPlaying = "playing"
+
+	// StatusOver is the status of a finished game
+	StatusOver = "over"
+)
+
+// State is a snapshot of a game, as sent to clients
+type State struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+
+	// FEN is the current position, and Moves all moves played since the initial position StartFEN, in UCI notation
+	FEN      string   `json:"fen"`
+	StartFEN string   `json:"start_fen"`
+	Turn     string   `json:"turn"`
+	Moves    []string `json:"moves"`
+
+	// time left on both clocks, in milliseconds
+	WhiteTime int64 `json:"white_ms"`
+	BlackTime int64 `json:"black_ms"`
+
+	White Seat `json:"white"`
+	Black Seat `json:"black"`
+
+	// Result and Reason describe how the game ended, once it's over
+	Result string `json:"result,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Seat describes the player of one color
+type Seat struct {
+	Player string `json:"player"`
+
+	// Joined is true once a human player has joined the seat; computer players are always joined
+	Joined bool `json:"joined"`
+}
+
+// Event is a message sent to clients over a game's WebSocket stream
+type Event struct {
+	// Type is "state" for a change in the game, with State set, or "error" for a rejected request, with Error set
+	Type  string `json:"type"`
+	State *State `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// game is a game hosted by the server, along with its players and the clients following it
+type game struct {
+	id string
+
+	mu sync.Mutex
+
+	g     *chess.Game
+	start *chess.Board
+	board *chess.Board
+	moves []string
+
+	status string
+	result *chess.GameResult
+
+	// clocks, kept up to date while the game is not being played
+	whiteTime time.Duration
+	blackTime time.Duration
+
+	seats [2]Seat
+
+	// humans[c] is the player of color c if a human plays it, and tokens[c] the token authorizing its moves once
+	// joined
+	humans [2]*humanPlayer
+	tokens [2]string
+
+	// channels of the clients subscribed to the events of the game
+	subscribers map[chan []byte]bool
+
+	// onOver, if set, is called once the game is over
+	onOver func()
+}
+
+// newGame returns a game between white and black, which are human players if nil
+func newGame(id string, tc chess.TimeControl, b *chess.Board, white, black chess.Player, whiteType, blackType string) *game {
+	g := &game{
+		id:          id,
+		start:       b.Copy(),
+		board:       b.Copy(),
+		status:      StatusWaiting,
+		whiteTime:   tc.InitialTime(),
+		blackTime:   tc.InitialTime(),
+		subscribers: make(map[chan []byte]bool),
+	}
+
+	players := [2]chess.Player{white, black}
+	types := [2]string{whiteType, blackType}
+	for c := range players {
+		g.seats[c] = Seat{Player: types[c], Joined: players[c] != nil}
+		if players[c] == nil {
+			g.humans[c] = newHumanPlayer()
+			players[c] = g.humans[c]
+		}
+	}
+
+	g.g = chess.NewGameFromBoard(players[chess.White], players[chess.Black], tc, b)
+	g.g.OnMove = g.onMove
+	return g
+}
+
+// join seats a human at color c, returning the token authorizing its moves
+func (g *game) join(c chess.Color) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.humans[c] == nil {
+		return "", fmt.Errorf("%v is not played by a human", c)
+	}
+
+	if g.seats[c].Joined {
+		return "", fmt.Errorf("%v has already been joined", c)
+	}
+
+	g.seats[c].Joined = true
+	g.tokens[c] = newID()
+
+	g.startIfReady()
+	g.broadcast()
+	return g.tokens[c], nil
+}
+
+// startIfReady starts the game once both seats are joined. Must be called with g.mu held.
+func (g *game) startIfReady() {
+	if g.status == StatusWaiting && g.seats[chess.White].Joined && g.seats[chess.Black].Joined {
+		g.status = StatusPlaying
+		go g.play()
+	}
+}
+
+// play plays the game to completion
+func (g *game) play() {
+	result := g.g.Start()
+	if g.onOver != nil {
+		defer g.onOver()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.whiteTime = g.g.GetTimeLeft(chess.White)
+	g.blackTime = g.g.GetTimeLeft(chess.Black)
+	g.status = StatusOver
+	g.result = result
+
+	g.broadcast()
+	for ch := range g.subscribers {
+		close(ch)
+		delete(g.subscribers, ch)
+	}
+}
+
+func (g *game) onMove(c chess.Color, m *chess.Move) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.board.UnsafeMove(m)
+	g.moves = append(g.moves, m.UCI())
+	g.broadcast()
+}
+
+// submit plays a move given in UCI notation for the player holding token
+func (g *game) submit(token, move string) error {
+	g.mu.Lock()
+	var human *humanPlayer
+	for c := range g.tokens {
+		if token != "" && g.tokens[c] == token {
+			human = g.humans[c]
+		}
+	}
+	status := g.status
+	g.mu.Unlock()
+
+	switch {
+	case human == nil:
+		return fmt.Errorf("invalid token")
+	case status != StatusPlaying:
+		return fmt.Errorf("game is %v", status)
+	}
+
+	return human.submit(move)
+}
+
+// state returns a snapshot of the game. Must be called with g.mu held.
+func (g *game) state() *State {
+	s := &State{
+		ID:       g.id,
+		Status:   g.status,
+		FEN:      g.board.FEN(),
+		StartFEN: g.start.FEN(),
+		Turn:     g.board.Turn.String(),
+		Moves:    append([]string{}, g.moves...),
+		White:    g.seats[chess.White],
+		Black:    g.seats[chess.Black],
+	}
+
+	white, black := g.whiteTime, g.blackTime
+	if g.status == StatusPlaying {
+		white, black = g.g.GetTimeLeft(chess.White), g.g.GetTimeLeft(chess.Black)
+	}
+	s.WhiteTime, s.BlackTime = white.Milliseconds(), black.Milliseconds()
+
+	if g.result != nil {
+		s.Result = g.result.Outcome.String()
+		s.Reason = g.result.Reason
+	}
+
+	return s
+}
+
+// snapshot returns a snapshot of the game
+func (g *game) snapshot() *State {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.state()
+}
+
+// subscribe returns a channel receiving the events of the game, starting with its current state. The channel is
+// closed when the game is over, or the subscriber too slow to keep up.
+func (g *game) subscribe() chan []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ch := make(chan []byte, subscriberBuffer)
+	ch <- g.stateEvent()
+
+	if g.status == StatusOver {
+		close(ch)
+		return ch
+	}
+
+	g.subscribers[ch] = true
+	return ch
+}
+
+// unsubscribe stops sending events to ch
+func (g *game) unsubscribe(ch chan []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.subscribers[ch] {
+		close(ch)
+		delete(g.subscribers, ch)
+	}
+}
+
+// stateEvent returns the encoded state event of the game. Must be called with g.mu held.
+func (g *game) stateEvent() []byte {
+	msg, _ := json.Marshal(&Event{Type: "state", State: g.state()})
+	return msg
+}
+
+// broadcast sends the state of the game to all subscribers, dropping those too slow to keep up. Must be called with
+// g.mu held.
+func (g *game) broadcast() {
+	msg := g.stateEvent()
+	for ch := range g.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			close(ch)
+			delete(g.subscribers, ch)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+)
+
+// humanPlayer plays the moves submitted by a human through the server
+type humanPlayer struct {
+	Color      chess.Color
+	GameClient chess.GameClient
+	Prompt     chan chess.Prompt
+	Move       chan *chess.Move
+
+	Board *chess.Board
+
+	// moves submitted by the human, each with a channel receiving whether the move was accepted
+	submissions chan submission
+
+	// closed once the game is over
+	done chan struct{}
+}
+
+type submission struct {
+	move  string
+	reply chan error
+}
+
+func newHumanPlayer() *humanPlayer {
+	return &humanPlayer{
+		submissions: make(chan submission),
+		done:        make(chan struct{}),
+	}
+}
+
+func (hp *humanPlayer) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	hp.Color = c
+	hp.Prompt = prompt
+	hp.Move = move
+	hp.GameClient = gc
+
+	hp.Board = gc.GetBoard()
+}
+
+func (hp *humanPlayer) Run() {
+	defer close(hp.done)
+
+	toMove := false
+	for {
+		select {
+		case p, ok := <-hp.Prompt:
+			if !ok {
+				return
+			}
+
+			if p.OppMove != nil {
+				hp.Board.UnsafeMove(p.OppMove)
+			}
+
+			toMove = len(hp.Board.LegalMoves()) > 0
+
+		case s := <-hp.submissions:
+			if !toMove {
+				s.reply <- fmt.Errorf("not your turn")
+				continue
+			}
+
+			m, err := chess.NewMoveUCI(s.move, hp.Board.Turn)
+			if err == nil {
+				err = hp.Board.CheckMove(m)
+			}
+
+			if err != nil {
+				s.reply <- fmt.Errorf("illegal move %v: %v", s.move, err)
+				continue
+			}
+
+			hp.Board.UnsafeMove(m)
+			toMove = false
+
+			// Send move
+			hp.Move <- m
+			s.reply <- nil
+		}
+	}
+}
+
+// submit plays a move in UCI notation, returning an error if it's not the player's turn or the move is illegal
+func (hp *humanPlayer) submit(move string) error {
+	reply := make(chan error, 1)
+	select {
+	case hp.submissions <- submission{move, reply}:
+		return <-reply
+	case <-hp.done:
+		return fmt.Errorf("game is over")
+	}
+}
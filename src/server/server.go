@@ -0,0 +1,370 @@
+// Package server hosts chess games over HTTP. Games are created, joined and inspected through a REST API, and
+// followed and played through a WebSocket stream:
+//
+//	POST /games               create a game from a CreateRequest, returning its State
+//	GET  /games               list the States of all games
+//	GET  /games/{id}          get the State of a game
+//	POST /games/{id}/join     join a game from a JoinRequest, returning a JoinResponse
+//	GET  /games/{id}/ws       stream the Events of a game, and submit moves with MoveRequests
+package server
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"Chess2020/src/players/search"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// PlayerHuman is the type of players joining a game through the server
+	PlayerHuman = "human"
+
+	// PlayerRandom is the type of random.RandomPlayer
+	PlayerRandom = "random"
+
+	// PlayerSearch is the type of search.SearchPlayer
+	PlayerSearch = "search"
+)
+
+const (
+	// DefaultRetention is the default time finished games are kept around for clients to look at
+	DefaultRetention = 10 * time.Minute
+
+	// size of the transposition table of each search player, in megabytes; every game gets its own engine, so it is
+	// kept much smaller than search.DefaultHashMB
+	searchHashMB = 4
+
+	// number of events buffered for each subscriber, beyond which it is considered too slow and dropped
+	subscriberBuffer = 64
+
+	// time after which a WebSocket client that isn't reading what is sent to it is disconnected
+	writeTimeout = 10 * time.Second
+)
+
+// CreateRequest is the body of a request creating a game
+type CreateRequest struct {
+	// InitialSeconds and IncrementSeconds set the time control. With no initial time, the game is untimed.
+	InitialSeconds   float64 `json:"initial_seconds"`
+	IncrementSeconds float64 `json:"increment_seconds"`
+
+	// FEN is the position to start from, or the starting position if empty
+	FEN string `json:"fen"`
+
+	// White and Black are the types of the players: PlayerHuman if empty
+	White string `json:"white"`
+	Black string `json:"black"`
+}
+
+// JoinRequest is the body of a request joining a game
+type JoinRequest struct {
+	Color string `json:"color"`
+}
+
+// JoinResponse is the response to a JoinRequest, holding the token authorizing the moves of the joined player
+type JoinResponse struct {
+	Token string `json:"token"`
+}
+
+// MoveRequest is a message sent by clients over a game's WebSocket stream to play a move, in UCI notation
+type MoveRequest struct {
+	Token string `json:"token"`
+	Move  string `json:"move"`
+}
+
+// errorResponse is the body of the response to a failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server hosts any number of concurrent games
+type Server struct {
+	// Retention is how long finished games are kept, after which they are forgotten
+	Retention time.Duration
+
+	mu    sync.Mutex
+	games map[string]*game
+
+	upgrader websocket.Upgrader
+}
+
+// New returns a Server hosting no games
+func New() *Server {
+	return &Server{
+		Retention: DefaultRetention,
+		games:     make(map[string]*game),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// newPlayer returns a computer player of the given type, or nil for a human player
+func newPlayer(playerType string) (chess.Player, error) {
+	switch playerType {
+	case PlayerHuman:
+		return nil, nil
+	case PlayerRandom:
+		return random.Player(), nil
+	case PlayerSearch:
+		return &search.SearchPlayer{Engine: search.NewEngine(1, searchHashMB)}, nil
+	default:
+		return nil, fmt.Errorf("unknown player type: %v", playerType)
+	}
+}
+
+func parseColor(s string) (chess.Color, error) {
+	switch strings.ToLower(s) {
+	case "white":
+		return chess.White, nil
+	case "black":
+		return chess.Black, nil
+	default:
+		return chess.White, fmt.Errorf("color must be one of {white,black}, is: %v", s)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorResponse{Error: err.Error()})
+}
+
+// ServeHTTP routes requests to the handlers of the API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "games" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found: %v", r.URL.Path))
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r)
+		case http.MethodPost:
+			s.create(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %v", r.Method))
+		}
+		return
+	}
+
+	s.mu.Lock()
+	g := s.games[parts[1]]
+	s.mu.Unlock()
+
+	if g == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such game: %v", parts[1]))
+		return
+	}
+
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, g.snapshot())
+	case action == "join" && r.Method == http.MethodPost:
+		s.join(w, r, g)
+	case action == "ws" && r.Method == http.MethodGet:
+		s.stream(w, r, g)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found: %v %v", r.Method, r.URL.Path))
+	}
+}
+
+// list handles GET /games
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	games := make([]*game, 0, len(s.games))
+	for _, g := range s.games {
+		games = append(games, g)
+	}
+	s.mu.Unlock()
+
+	states := make([]*State, len(games))
+	for i, g := range games {
+		states[i] = g.snapshot()
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, states)
+}
+
+// create handles POST /games
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	var tc chess.TimeControl = chess.InfiniteTime{}
+	if req.InitialSeconds > 0 {
+		tc = chess.Fischer{
+			Initial: time.Duration(req.InitialSeconds * float64(time.Second)),
+			Inc:     time.Duration(req.IncrementSeconds * float64(time.Second)),
+		}
+	}
+
+	b := chess.NewBoard()
+	if req.FEN != "" {
+		var err error
+		if b, err = chess.NewBoardFromFEN(req.FEN); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid FEN: %v", err))
+			return
+		}
+	}
+
+	if len(b.LegalMoves()) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("game is already over"))
+		return
+	}
+
+	types := [2]string{req.White, req.Black}
+	var players [2]chess.Player
+	for c := range types {
+		if types[c] == "" {
+			types[c] = PlayerHuman
+		}
+
+		var err error
+		if players[c], err = newPlayer(types[c]); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	g := newGame(newID(), tc, b, players[chess.White], players[chess.Black], types[chess.White], types[chess.Black])
+	g.onOver = func() {
+		time.AfterFunc(s.Retention, func() {
+			s.mu.Lock()
+			delete(s.games, g.id)
+			s.mu.Unlock()
+		})
+	}
+
+	s.mu.Lock()
+	s.games[g.id] = g
+	s.mu.Unlock()
+
+	g.mu.Lock()
+	g.startIfReady()
+	state := g.state()
+	g.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, state)
+}
+
+// join handles POST /games/{id}/join
+func (s *Server) join(w http.ResponseWriter, r *http.Request, g *game) {
+	var req JoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	c, err := parseColor(req.Color)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	token, err := g.join(c)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &JoinResponse{Token: token})
+}
+
+// stream handles GET /games/{id}/ws, sending the events of the game to the client, and playing the moves it sends
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, g *game) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader already replied with an error
+	}
+	defer conn.Close()
+
+	events := g.subscribe()
+	defer g.unsubscribe(events)
+
+	// replies to rejected requests are sent by the writer, which owns the connection
+	replies := make(chan []byte, subscriberBuffer)
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		for {
+			var req MoveRequest
+			err := conn.ReadJSON(&req)
+			switch err.(type) {
+			case nil:
+				err = g.submit(req.Token, req.Move)
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				err = fmt.Errorf("invalid request: %v", err)
+			default:
+				return // the connection is closed or broken
+			}
+
+			if err != nil {
+				msg, _ := json.Marshal(&Event{Type: "error", Error: err.Error()})
+				select {
+				case replies <- msg:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		var msg []byte
+		var ok bool
+		select {
+		case msg, ok = <-events:
+			if !ok {
+				conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		case msg = <-replies:
+		case <-closed:
+			return
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"Chess2020/src/chess"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// post sends v as JSON to url, decodes the response into out, and returns the status code
+func post(t *testing.T, url string, v, out interface{}) int {
+	body, _ := json.Marshal(v)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	return resp.StatusCode
+}
+
+func TestCreate(t *testing.T) {
+	ts := httptest.NewServer(New())
+	defer ts.Close()
+
+	var state State
+	status := post(t, ts.URL+"/games", &CreateRequest{
+		FEN:   "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1",
+		White: PlayerHuman,
+		Black: PlayerRandom,
+	}, &state)
+
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status %v, but got: %v", http.StatusCreated, status)
+	}
+
+	if state.Status != StatusWaiting || state.Turn != "Black" || !state.Black.Joined || state.White.Joined {
+		t.Fatalf("Expected a game waiting for white, with black to move, but got: %+v", state)
+	}
+
+	for _, req := range []*CreateRequest{
+		{FEN: "invalid"},
+		{White: "unknown"},
+		{FEN: "7k/5QQ1/8/8/8/8/8/K7 b - - 0 1"}, // stalemate
+	} {
+		if status := post(t, ts.URL+"/games", req, nil); status != http.StatusBadRequest {
+			t.Fatalf("Expected status %v for %+v, but got: %v", http.StatusBadRequest, req, status)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/games/unknown")
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status %v, but got: %v", http.StatusNotFound, resp.StatusCode)
+	}
+
+	var join JoinResponse
+	if status := post(t, ts.URL+"/games/"+state.ID+"/join", &JoinRequest{Color: "black"}, &join); status != http.StatusConflict {
+		t.Fatalf("Expected joining a computer's seat to fail, but got: %v", status)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	s := New()
+	s.Retention = 0
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var state State
+	post(t, ts.URL+"/games", &CreateRequest{InitialSeconds: 60, Black: PlayerRandom}, &state)
+
+	var join JoinResponse
+	if status := post(t, ts.URL+"/games/"+state.ID+"/join", &JoinRequest{Color: "white"}, &join); status != http.StatusOK {
+		t.Fatalf("Expected status %v, but got: %v", http.StatusOK, status)
+	}
+
+	if status := post(t, ts.URL+"/games/"+state.ID+"/join", &JoinRequest{Color: "white"}, nil); status != http.StatusConflict {
+		t.Fatalf("Expected joining twice to fail, but got: %v", status)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/games/"+state.ID+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+	defer conn.Close()
+
+	// play the first legal move of every position, after trying an illegal one and a move with a wrong token
+	errors := 0
+	conn.WriteJSON(&MoveRequest{Token: join.Token, Move: "e2e5"})
+	conn.WriteJSON(&MoveRequest{Token: "wrong", Move: "e2e4"})
+
+	for {
+		var event Event
+		if err := conn.ReadJSON(&event); err != nil {
+			break // the game is over
+		}
+
+		switch event.Type {
+		case "error":
+			errors++
+		case "state":
+			state = *event.State
+			if state.Status != StatusPlaying || state.Turn != "White" {
+				continue
+			}
+
+			b, err := chess.NewBoardFromFEN(state.FEN)
+			if err != nil {
+				t.Fatalf("Expected no errors, but got: %v", err)
+			}
+
+			if moves := b.LegalMoves(); len(moves) > 0 {
+				conn.WriteJSON(&MoveRequest{Token: join.Token, Move: moves[0].UCI()})
+			}
+		}
+	}
+
+	if errors != 2 {
+		t.Fatalf("Expected 2 errors, but got: %v", errors)
+	}
+
+	if state.Status != StatusOver || state.Result == "" || strings.Contains(state.Reason, "White lost") {
+		t.Fatalf("Expected the game to be over without white losing on a technicality, but got: %+v", state)
+	}
+
+	// replaying the moves from the start must lead to the final position
+	b, _ := chess.NewBoardFromFEN(state.StartFEN)
+	for _, s := range state.Moves {
+		m, err := chess.NewMoveUCI(s, b.Turn)
+		if err == nil {
+			err = b.Move(m)
+		}
+
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+	}
+
+	if b.FEN() != state.FEN {
+		t.Fatalf("Expected final position %v, but got: %v", state.FEN, b.FEN())
+	}
+
+	// finished games are forgotten once the retention period is over
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := http.Get(ts.URL + "/games/" + state.ID)
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the finished game to be forgotten, but got status: %v", resp.StatusCode)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
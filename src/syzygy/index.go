@@ -0,0 +1,157 @@
+package syzygy
+
+// Squares in this package are numbered as in the tablebase files: a1 = 0, b1 = 1, ..., h8 = 63. Pieces are coded as
+// in the files too: pawn = 1, knight = 2, bishop = 3, rook = 4, queen = 5, king = 6, plus 8 for black pieces.
+const (
+	pawn   = 1
+	knight = 2
+	bishop = 3
+	rook   = 4
+	queen  = 5
+	king   = 6
+
+	black = 8
+)
+
+// maxPieces is the largest number of pieces a table can hold
+const maxPieces = 7
+
+var (
+	// mapPawns [s] encodes the squares a2-h7 to 0..47, such that the pawn with the highest value is the one nearest
+	// to the edge and, among pawns on the same file, the one with lowest rank
+	mapPawns [64]int
+
+	// mapB1H1H7 [s] encodes the squares below the a1-h8 diagonal to 0..27
+	mapB1H1H7 [64]int
+
+	// mapA1D1D4 [s] encodes the squares of the a1-d1-d4 triangle to 0..9, the diagonal squares last
+	mapA1D1D4 [64]int
+
+	// mapKK [k][s] encodes the 462 legal placements of two kings where the first king's square k is encoded by
+	// mapA1D1D4
+	mapKK [10][64]int
+
+	// binomial [k][n] is the number of ways to choose k elements from a set of n elements
+	binomial [maxPieces - 1][64]uint64
+
+	// leadPawnIdx [n][s] is the start index of placements of n leading pawns with the leading one on s
+	leadPawnIdx [maxPieces - 1][64]uint64
+
+	// leadPawnsSize [n][f] is the number of placements of n leading pawns with the leading one on file f
+	leadPawnsSize [maxPieces - 1][4]uint64
+)
+
+func fileOf(s int) int {
+	return s & 7
+}
+
+func rankOf(s int) int {
+	return s >> 3
+}
+
+// offA1H8 is positive for squares above the a1-h8 diagonal, negative below it and 0 on it
+func offA1H8(s int) int {
+	return rankOf(s) - fileOf(s)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+func init() {
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offA1H8(s) < 0 {
+			mapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for s := 0; s <= 27; s++ { // a1..d4
+		switch {
+		case offA1H8(s) < 0 && fileOf(s) <= 3:
+			mapA1D1D4[s] = code
+			code++
+		case offA1H8(s) == 0 && fileOf(s) <= 3:
+			diagonal = append(diagonal, s)
+		}
+	}
+
+	for _, s := range diagonal {
+		mapA1D1D4[s] = code
+		code++
+	}
+
+	// if the first king is on the a1-d4 diagonal, the other one can't be above the a1-h8 diagonal. Placements with
+	// both kings on the diagonal are encoded last.
+	var bothOnDiagonal [][2]int
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) { // b1 is mapped to 0
+				continue
+			}
+
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case abs(fileOf(s1)-fileOf(s2)) <= 1 && abs(rankOf(s1)-rankOf(s2)) <= 1:
+					continue // adjacent kings
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+					continue
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, [2]int{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+
+	for _, p := range bothOnDiagonal {
+		mapKK[p[0]][p[1]] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < maxPieces-1 && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	// there are 47 squares left for the other pawns when the leading pawn is on a2, and 2 less for every rank it
+	// advances, as it can't be below any other pawn, nor its mirror
+	available := 47
+	for n := 1; n < maxPieces-1; n++ {
+		for f := 0; f < 4; f++ {
+			idx := uint64(0)
+			for r := 1; r < 7; r++ {
+				s := 8*r + f
+				if n == 1 {
+					mapPawns[s] = available
+					available--
+					mapPawns[s^7] = available
+					available--
+				}
+
+				leadPawnIdx[n][s] = idx
+				idx += binomial[n-1][mapPawns[s]]
+			}
+
+			leadPawnsSize[n][f] = idx
+		}
+	}
+}
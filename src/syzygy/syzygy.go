@@ -0,0 +1,565 @@
+package syzygy
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/bits"
+	"path/filepath"
+	"strings"
+)
+
+// WDL is the game theoretical value of a position for the player to move, taking the 50-move rule into account
+type WDL int
+
+const (
+	Loss        WDL = -2 // lost
+	BlessedLoss WDL = -1 // lost, but drawn by the 50-move rule
+	Draw        WDL = 0
+	CursedWin   WDL = 1 // won, but drawn by the 50-move rule
+	Win         WDL = 2 // won
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed loss"
+	case Draw:
+		return "draw"
+	case CursedWin:
+		return "cursed win"
+	case Win:
+		return "win"
+	default:
+		panic(fmt.Sprintf("Unhandled WDL value: %d", int(w)))
+	}
+}
+
+// pieceCodes [p] is the tablebase code of piece p
+var pieceCodes = [12]int{
+	chess.WhiteKing:   king,
+	chess.WhiteQueen:  queen,
+	chess.WhiteKnight: knight,
+	chess.WhiteBishop: bishop,
+	chess.WhiteRook:   rook,
+	chess.WhitePawn:   pawn,
+	chess.BlackKing:   black | king,
+	chess.BlackQueen:  black | queen,
+	chess.BlackKnight: black | knight,
+	chess.BlackBishop: black | bishop,
+	chess.BlackRook:   black | rook,
+	chess.BlackPawn:   black | pawn,
+}
+
+// pieceLetters is the order pieces are listed in table file names
+const pieceLetters = "KQRBNP"
+
+var letterCodes = map[rune]int{'K': king, 'Q': queen, 'R': rook, 'B': bishop, 'N': knight, 'P': pawn}
+
+// materialKey returns the name of the table for the given piece counts of white and black, e.g. KRvKN
+func materialKey(white, black [king + 1]int) string {
+	var sb strings.Builder
+	for i, counts := range [2][king + 1]int{white, black} {
+		if i == 1 {
+			sb.WriteByte('v')
+		}
+
+		for _, l := range pieceLetters {
+			sb.WriteString(strings.Repeat(string(l), counts[letterCodes[l]]))
+		}
+	}
+
+	return sb.String()
+}
+
+// Tablebase probes Syzygy endgame tablebase files. Files are only read when first needed, and a Tablebase can be
+// probed by several goroutines at once.
+type Tablebase struct {
+	// tables by material key, with both colors as the stronger side
+	wdlTables map[string]*table
+	dtzTables map[string]*table
+
+	maxPieces int
+}
+
+// Open returns a Tablebase using the .rtbw (WDL) and .rtbz (DTZ) files found in the given directories. Tables with
+// a WDL file but no DTZ file can still be probed for WDL values.
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{
+		wdlTables: make(map[string]*table),
+		dtzTables: make(map[string]*table),
+	}
+
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tablebase directory: %v", err)
+		}
+
+		for _, f := range files {
+			if filepath.Ext(f.Name()) != extensions[wdlTable] {
+				continue
+			}
+
+			name := strings.TrimSuffix(f.Name(), extensions[wdlTable])
+			counts, ok := parseName(name)
+			if !ok {
+				continue
+			}
+
+			wdl := newTable(wdlTable, filepath.Join(dir, f.Name()), counts)
+			dtz := newTable(dtzTable, filepath.Join(dir, name+extensions[dtzTable]), counts)
+			if _, ok := tb.wdlTables[wdl.key]; ok {
+				continue // already found in an earlier directory
+			}
+
+			for _, key := range []string{wdl.key, wdl.key2} {
+				tb.wdlTables[key] = wdl
+				tb.dtzTables[key] = dtz
+			}
+
+			if wdl.pieceCount > tb.maxPieces {
+				tb.maxPieces = wdl.pieceCount
+			}
+		}
+	}
+
+	return tb, nil
+}
+
+// parseName returns the piece counts of the stronger and weaker side of a table file name like KRvKN
+func parseName(name string) ([2][king + 1]int, bool) {
+	var counts [2][king + 1]int
+
+	sides := strings.Split(name, "v")
+	if len(sides) != 2 || len(name)-1 > maxPieces {
+		return counts, false
+	}
+
+	for i, side := range sides {
+		for _, l := range side {
+			p, ok := letterCodes[l]
+			if !ok {
+				return counts, false
+			}
+
+			counts[i][p]++
+		}
+
+		if counts[i][king] != 1 {
+			return counts, false
+		}
+	}
+
+	return counts, true
+}
+
+// MaxPieces returns the largest number of pieces, kings included, of the available tables
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// newPosition translates b into the terms of the table files
+func newPosition(b *chess.Board) *position {
+	p := &position{}
+
+	var counts [2][king + 1]int
+	for piece, pieces := range b.Pieces {
+		pieces := uint64(pieces)
+		for pieces != 0 {
+			// bit i of a bitmap is file 7 - i%8 of rank i/8, so it is square i^7 of the table files
+			s := bits.TrailingZeros64(pieces) ^ 7
+			p.board[s] = pieceCodes[piece]
+			counts[p.board[s]>>3][p.board[s]&7]++
+			pieces &= pieces - 1
+		}
+	}
+
+	p.key = materialKey(counts[0], counts[1])
+	if b.Turn == chess.Black {
+		p.stm = 1
+	}
+
+	return p
+}
+
+// check returns an error if positions like b can't be probed
+func (tb *Tablebase) check(b *chess.Board) error {
+	if b.Variant != nil {
+		return fmt.Errorf("positions of variants are not in the tablebase")
+	}
+
+	if b.CanWhiteCastleKingside || b.CanWhiteCastleQueenside || b.CanBlackCastleKingside || b.CanBlackCastleQueenside {
+		return fmt.Errorf("positions with castling rights are not in the tablebase")
+	}
+
+	if n := b.PieceCount(); n > tb.maxPieces && n > 2 {
+		return fmt.Errorf("no tables for %v pieces", n)
+	}
+
+	return nil
+}
+
+// probeTable looks up p in its table among tables. ok is false for DTZ tables that only store the other side to
+// move.
+func (tb *Tablebase) probeTable(tables map[string]*table, p *position) (value, f int, ok bool, t *table, err error) {
+	t, found := tables[p.key]
+	if !found {
+		return 0, 0, false, nil, fmt.Errorf("no table for %v", p.key)
+	}
+
+	if err := t.load(); err != nil {
+		return 0, 0, false, nil, err
+	}
+
+	// a corrupted table can send decompression out of bounds
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupted table %v: %v", t.path, r)
+		}
+	}()
+
+	value, f, ok = t.probe(p)
+	return value, f, ok, t, nil
+}
+
+// probeWDLTable returns the WDL value of b stored in its table, which may be wrong if b has captures
+func (tb *Tablebase) probeWDLTable(b *chess.Board) (WDL, error) {
+	if b.PieceCount() == 2 {
+		return Draw, nil
+	}
+
+	value, _, _, _, err := tb.probeTable(tb.wdlTables, newPosition(b))
+	if err != nil {
+		return 0, err
+	}
+
+	return WDL(value - 2), nil
+}
+
+// isZeroing returns true iff m resets the 50-move counter
+func isZeroing(b *chess.Board, m *chess.Move) bool {
+	p := b.PieceAt(m.From)
+	return p == chess.WhitePawn || p == chess.BlackPawn || b.IsCapture(m)
+}
+
+// search returns the WDL value of b, resolving captures (and pawn moves, if zeroing is set) by searching them, as
+// tables store arbitrary values for positions where a capture is best. bestZeroing is true if a capture or pawn move
+// is the best move, in which case DTZ tables don't store a correct value either.
+func (tb *Tablebase) search(b *chess.Board, zeroing bool) (wdl WDL, bestZeroing bool, err error) {
+	moves := b.LegalMoves()
+
+	best := Loss
+	searched := 0
+	for _, m := range moves {
+		if !b.IsCapture(m) && !(zeroing && isZeroing(b, m)) {
+			continue
+		}
+		searched++
+
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		v, _, err := tb.search(child, false)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if -v > best {
+			best = -v
+			if best >= Win {
+				return best, true, nil
+			}
+		}
+	}
+
+	// with all moves searched, b itself needn't be in a table (e.g. positions with en-passent rights aren't)
+	allSearched := searched > 0 && searched == len(moves)
+
+	value := best
+	if !allSearched {
+		if value, err = tb.probeWDLTable(b); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if best >= value {
+		return best, best > Draw || allSearched, nil
+	}
+
+	return value, false, nil
+}
+
+// ProbeWDL returns the WDL value of b. Returns an error if b has castling rights, or its table is missing.
+func (tb *Tablebase) ProbeWDL(b *chess.Board) (WDL, error) {
+	if err := tb.check(b); err != nil {
+		return 0, err
+	}
+
+	wdl, _, err := tb.search(b, false)
+	return wdl, err
+}
+
+// Outcome returns the outcome of b under perfect play, implementing chess.Tablebase to adjudicate games. Positions are
+// only known right after a capture or pawn move, as the tablebase doesn't know how far the 50-move counter has
+// already advanced, and only in standard chess.
+func (tb *Tablebase) Outcome(b *chess.Board) (chess.Outcome, bool) {
+	if b.HalfMoveClock != 0 {
+		return 0, false
+	}
+
+	wdl, err := tb.ProbeWDL(b)
+	if err != nil {
+		return 0, false
+	}
+
+	winner, loser := chess.Outcome(chess.WhiteWon), chess.Outcome(chess.BlackWon)
+	if b.Turn == chess.Black {
+		winner, loser = loser, winner
+	}
+
+	switch wdl {
+	case Win:
+		return winner, true
+	case Loss:
+		return loser, true
+	default:
+		return chess.Draw, true // cursed wins and blessed losses are draws under the 50-move rule
+	}
+}
+
+// dtzBeforeZeroing returns the DTZ value of a position where the best move is a capture or pawn move with the given
+// WDL value
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// ProbeDTZ returns the distance to zeroing of b: the number of plies until a capture or pawn move that keeps the
+// WDL value of b, assuming optimal play. The result is positive if b is won for the player to move, negative if it is
+// lost, and 0 if it is drawn. Values beyond 100 in magnitude are cursed wins and blessed losses. Returns an error if b
+// has castling rights, or one of the needed tables is missing.
+//
+// The result may be 1 ply too high for wins and losses stored in moves rather than plies. This never matters for
+// the 50-move rule.
+func (tb *Tablebase) ProbeDTZ(b *chess.Board) (int, error) {
+	if err := tb.check(b); err != nil {
+		return 0, err
+	}
+
+	return tb.dtz(b)
+}
+
+func (tb *Tablebase) dtz(b *chess.Board) (int, error) {
+	wdl, bestZeroing, err := tb.search(b, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if wdl == Draw {
+		return 0, nil // DTZ tables don't store draws
+	}
+
+	if bestZeroing {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	if b.PieceCount() == 2 {
+		return 0, nil
+	}
+
+	value, f, ok, t, err := tb.probeTable(tb.dtzTables, newPosition(b))
+	if err != nil {
+		return 0, err
+	}
+
+	if ok {
+		dtz := t.mapDTZ(f, value, wdl)
+		if wdl == CursedWin || wdl == BlessedLoss {
+			dtz += 100
+		}
+
+		return dtz * sign(int(wdl)), nil
+	}
+
+	// the table only stores the other side to move: search one ply for the best DTZ
+	minDTZ := math.MaxInt32
+	for _, m := range b.LegalMoves() {
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		var dtz int
+		if isZeroing(b, m) {
+			// the DTZ of the move itself, as the child's would count towards the next zeroing move
+			v, _, err := tb.search(child, false)
+			if err != nil {
+				return 0, err
+			}
+
+			dtz = -dtzBeforeZeroing(v)
+		} else {
+			v, err := tb.dtz(child)
+			if err != nil {
+				return 0, err
+			}
+
+			dtz = -v + sign(-v)
+		}
+
+		// mating moves always have a DTZ of 1
+		if dtz == 2 && child.IsCheckmate() {
+			minDTZ = 1
+		}
+
+		if dtz < minDTZ && sign(dtz) == sign(int(wdl)) {
+			minDTZ = dtz
+		}
+	}
+
+	// there are no legal moves: the position is mate
+	if minDTZ == math.MaxInt32 {
+		return -1, nil
+	}
+
+	return minDTZ, nil
+}
+
+// RootMove is a legal move at the root of a search, ranked by the tablebase
+type RootMove struct {
+	Move *chess.Move
+
+	// Rank is higher for better moves. Moves keeping a win (or holding a draw) have the same rank, unless the win
+	// is at risk from the 50-move rule.
+	Rank int
+
+	// WDL is the value of the position after the move, for the player making it
+	WDL WDL
+}
+
+// RankRootMoves ranks all legal moves on b, using DTZ tables where available and WDL tables otherwise. b's
+// HalfMoveClock is taken into account for the 50-move rule.
+func (tb *Tablebase) RankRootMoves(b *chess.Board) ([]RootMove, error) {
+	if err := tb.check(b); err != nil {
+		return nil, err
+	}
+
+	moves, err := tb.rankByDTZ(b)
+	if err != nil {
+		moves, err = tb.rankByWDL(b)
+	}
+
+	return moves, err
+}
+
+// rankByDTZ ranks the moves on b by their DTZ values: wins that are safe from the 50-move rule rank highest, the
+// shortest first, and losses rank lowest, the longest first
+func (tb *Tablebase) rankByDTZ(b *chess.Board) ([]RootMove, error) {
+	var ranked []RootMove
+	for _, m := range b.LegalMoves() {
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		var dtz int
+		var wdl WDL
+		if child.HalfMoveClock == 0 {
+			v, err := tb.ProbeWDL(child)
+			if err != nil {
+				return nil, err
+			}
+
+			wdl = -v
+			dtz = dtzBeforeZeroing(wdl)
+			if wdl == CursedWin || wdl == BlessedLoss {
+				wdl = Draw // the move resets the count, but the position after it is drawn by the 50-move rule
+			}
+		} else {
+			v, err := tb.dtz(child)
+			if err != nil {
+				return nil, err
+			}
+
+			dtz = -v + sign(-v)
+			wdl = dtzToWDL(dtz, b.HalfMoveClock)
+		}
+
+		if dtz == 2 && child.IsCheckmate() {
+			dtz = 1
+		}
+
+		rank := 0
+		switch wdl {
+		case Win:
+			rank = 2000 - dtz
+		case CursedWin:
+			rank = 1000 - (dtz + b.HalfMoveClock)
+		case Loss:
+			rank = -2000 - dtz
+		case BlessedLoss:
+			rank = -1000 + (-dtz + b.HalfMoveClock)
+		}
+
+		ranked = append(ranked, RootMove{Move: m, Rank: rank, WDL: wdl})
+	}
+
+	return ranked, nil
+}
+
+// dtzToWDL returns the WDL value of a position with the given DTZ value, halfMoveClock plies into the 50-move count.
+// Wins and losses are only counted as such with a ply to spare, as DTZ values may be 1 ply too high.
+func dtzToWDL(dtz, halfMoveClock int) WDL {
+	switch {
+	case dtz > 0 && dtz+halfMoveClock <= 99:
+		return Win
+	case dtz > 0:
+		return CursedWin
+	case dtz < 0 && -dtz+halfMoveClock <= 99:
+		return Loss
+	case dtz < 0:
+		return BlessedLoss
+	default:
+		return Draw
+	}
+}
+
+// rankByWDL ranks the moves on b by the WDL values of the resulting positions
+func (tb *Tablebase) rankByWDL(b *chess.Board) ([]RootMove, error) {
+	var ranked []RootMove
+	for _, m := range b.LegalMoves() {
+		child := b.Copy()
+		child.UnsafeMove(m)
+
+		v, err := tb.ProbeWDL(child)
+		if err != nil {
+			return nil, err
+		}
+
+		wdl := -v
+		ranked = append(ranked, RootMove{Move: m, Rank: [5]int{-1000, -899, 0, 899, 1000}[wdl+2], WDL: wdl})
+	}
+
+	return ranked, nil
+}
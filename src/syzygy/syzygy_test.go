@@ -0,0 +1,557 @@
+package syzygy
+
+import (
+	"Chess2020/src/chess"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/bits"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// newBoard returns a board without castling rights holding the given pieces
+func newBoard(t *testing.T, turn chess.Color, pieces map[chess.Piece][]chess.Coordinate) *chess.Board {
+	b := &chess.Board{Turn: turn}
+	for p, coords := range pieces {
+		for _, c := range coords {
+			s, err := c.Square()
+			if err != nil {
+				t.Fatalf("Expected no errors, but got: %v", err)
+			}
+
+			b.Pieces[p] |= 1 << uint(bits.TrailingZeros64(uint64(s)))
+		}
+	}
+
+	return b
+}
+
+func TestIndexTables(t *testing.T) {
+	max := 0
+	for _, row := range mapKK {
+		for _, code := range row {
+			if code > max {
+				max = code
+			}
+		}
+	}
+
+	if max != 461 {
+		t.Errorf("Expected 462 king placements, but got: %v", max+1)
+	}
+
+	if binomial[2][48] != 1128 || binomial[5][63] != 7028847 {
+		t.Errorf("Expected binomial coefficients 1128 and 7028847, but got: %v and %v", binomial[2][48], binomial[5][63])
+	}
+
+	for f := 0; f < 4; f++ {
+		if leadPawnsSize[1][f] != 6 {
+			t.Errorf("Expected 6 placements of a single pawn on file %v, but got: %v", f, leadPawnsSize[1][f])
+		}
+	}
+
+	if mapPawns[8] != 47 || mapPawns[15] != 46 || mapPawns[16] != 45 {
+		t.Errorf("Expected a2, h2 and a3 to map to 47, 46 and 45, but got: %v, %v and %v", mapPawns[8], mapPawns[15], mapPawns[16])
+	}
+}
+
+// testTable returns a WDL table for the material in name, with pieces encoded in the given order
+func testTable(t *testing.T, name string, pieces []int) *table {
+	counts, ok := parseName(name)
+	if !ok {
+		t.Fatalf("Expected %v to be a valid table name", name)
+	}
+
+	tb := newTable(wdlTable, name, counts)
+
+	order := [2]int{0, 0xF}
+	if tb.hasPawns && tb.pawnCount[1] > 0 {
+		order = [2]int{0, 1}
+	}
+
+	for f := 0; f < 4; f++ {
+		for stm := 0; stm < 2; stm++ {
+			d := tb.get(stm, f)
+			copy(d.pieces[:], pieces)
+			tb.setGroups(d, order, f)
+		}
+	}
+
+	return tb
+}
+
+// randomPosition places pieces on random squares, with pawns on ranks 2-7 and kings not adjacent to each other
+func randomPosition(r *rand.Rand, key string, pieces []int) *position {
+	for {
+		p := &position{key: key}
+		var kings []int
+		for _, pc := range pieces {
+			s := r.Intn(64)
+			if pc&7 == pawn {
+				s = 8 + r.Intn(48)
+			}
+
+			if p.board[s] != 0 {
+				break
+			}
+
+			p.board[s] = pc
+			if pc&7 == king {
+				kings = append(kings, s)
+			}
+		}
+
+		placed := 0
+		for _, pc := range p.board {
+			if pc != 0 {
+				placed++
+			}
+		}
+
+		if placed == len(pieces) && (abs(fileOf(kings[0])-fileOf(kings[1])) > 1 || abs(rankOf(kings[0])-rankOf(kings[1])) > 1) {
+			return p
+		}
+	}
+}
+
+// canonical returns the smallest of the boards equivalent to board under the symmetries tables make use of
+func canonical(board [64]int, hasPawns bool) [64]int {
+	transforms := []func(int) int{
+		func(s int) int { return s },
+		func(s int) int { return s ^ 7 },
+	}
+
+	if !hasPawns {
+		for _, tr := range transforms[:2] {
+			tr := tr
+			transforms = append(transforms, func(s int) int { return tr(s) ^ 56 })
+		}
+
+		for _, tr := range transforms[:4] {
+			tr := tr
+			transforms = append(transforms, func(s int) int { s = tr(s); return (s>>3 | s<<3) & 63 })
+		}
+	}
+
+	best := board
+	for _, tr := range transforms {
+		var image [64]int
+		for s, pc := range board {
+			image[tr(s)] = pc
+		}
+
+		for s := range image {
+			if image[s] != best[s] {
+				if image[s] < best[s] {
+					best = image
+				}
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// Positions that aren't symmetric to each other must have different indices, all within the size of the table
+func TestEncode(t *testing.T) {
+	const (
+		wK, wQ, wR, wN, wP = king, queen, rook, knight, pawn
+		bK, bN, bP         = black | king, black | knight, black | pawn
+	)
+
+	tests := []struct {
+		name   string
+		pieces []int
+	}{
+		{"KQvK", []int{wK, wQ, bK}},
+		{"KRvKN", []int{wK, wR, bK, bN}},
+		{"KNNvK", []int{wK, bK, wN, wN}},
+		{"KPvK", []int{wP, wK, bK}},
+		{"KPPvK", []int{wP, wP, wK, bK}},
+		{"KPvKP", []int{wP, bP, wK, bK}},
+		{"KRPvKN", []int{wP, wK, wR, bK, bN}},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		tb := testTable(t, test.name, test.pieces)
+		classes := map[[2]uint64][64]int{}
+
+		for i := 0; i < 20000; i++ {
+			p := randomPosition(r, tb.key, test.pieces)
+
+			d, f, idx, _ := tb.encode(p)
+			n := 0
+			for d.groupLen[n] != 0 {
+				n++
+			}
+
+			if idx >= d.groupIdx[n] {
+				t.Fatalf("%v: expected index below %v, but got: %v", test.name, d.groupIdx[n], idx)
+			}
+
+			class := canonical(p.board, tb.hasPawns)
+			if other, ok := classes[[2]uint64{uint64(f), idx}]; ok && other != class {
+				t.Fatalf("%v: expected different positions to have different indices, but %v and %v both have index %v", test.name, other, class, idx)
+			}
+
+			classes[[2]uint64{uint64(f), idx}] = class
+		}
+	}
+}
+
+// huffman is a canonical Huffman code over 6 symbols: a pair of the values 2 and 4, and leaves for the values 0-4
+var huffman = struct {
+	lengths []int // code length of each symbol, non-increasing
+	left    []int
+	right   []int
+}{
+	lengths: []int{4, 4, 3, 2, 2, 2},
+	left:    []int{3, 0, 1, 2, 3, 4},
+	right:   []int{5, 0xFFF, 0xFFF, 0xFFF, 0xFFF, 0xFFF},
+}
+
+// bitWriter writes codes MSB first
+type bitWriter struct {
+	buf  []byte
+	bits int
+}
+
+func (w *bitWriter) write(code uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+
+		w.buf[len(w.buf)-1] |= byte((code>>uint(i))&1) << uint(7-w.bits%8)
+		w.bits++
+	}
+}
+
+// compressed holds a sub-table compressed with the huffman code
+type compressed struct {
+	sizes       []byte
+	sparseIndex []byte
+	blockLength []byte
+	data        []byte
+}
+
+// compress encodes values into blocks of 32 bytes with a sparse index entry every 64 values
+func compress(values []int) compressed {
+	const blockBits, spanBits = 5, 6
+	blockSize, span := 1<<blockBits, 1<<spanBits
+
+	minLen, maxLen := huffman.lengths[len(huffman.lengths)-1], huffman.lengths[0]
+
+	// count [l] is the number of symbols of length l, lowest [l] the first of them, and first [l] the first code
+	count := make([]int, maxLen+2)
+	for _, l := range huffman.lengths {
+		count[l]++
+	}
+
+	lowest := make([]int, maxLen+1)
+	first := make([]int, maxLen+1)
+	for l := maxLen - 1; l >= minLen; l-- {
+		lowest[l] = lowest[l+1] + count[l+1]
+		first[l] = (first[l+1] + count[l+1]) / 2
+	}
+
+	// greedily use the pair symbol
+	var syms []int
+	for i := 0; i < len(values); i++ {
+		if i+1 < len(values) && values[i] == 2 && values[i+1] == 4 {
+			syms = append(syms, 0)
+			i++
+		} else {
+			syms = append(syms, values[i]+1)
+		}
+	}
+
+	var blocks []bitWriter
+	var lengths []int // number of values in each block
+	var blockOf, offsetOf []int
+	for _, sym := range syms {
+		l := huffman.lengths[sym]
+		if len(blocks) == 0 || blocks[len(blocks)-1].bits+l > 8*blockSize {
+			blocks = append(blocks, bitWriter{})
+			lengths = append(lengths, 0)
+		}
+
+		expands := 1
+		if sym == 0 {
+			expands = 2
+		}
+
+		for i := 0; i < expands; i++ {
+			blockOf = append(blockOf, len(blocks)-1)
+			offsetOf = append(offsetOf, lengths[len(lengths)-1]+i)
+		}
+
+		blocks[len(blocks)-1].write(uint64(first[l]+sym-lowest[l]), l)
+		lengths[len(lengths)-1] += expands
+	}
+
+	var c compressed
+	var sizes bytes.Buffer
+	sizes.Write([]byte{0, blockBits, spanBits, 1})
+	binary.Write(&sizes, binary.LittleEndian, uint32(len(blocks)))
+	sizes.Write([]byte{byte(maxLen), byte(minLen)})
+	for l := minLen; l <= maxLen; l++ {
+		binary.Write(&sizes, binary.LittleEndian, uint16(lowest[l]))
+	}
+
+	binary.Write(&sizes, binary.LittleEndian, uint16(len(huffman.left)))
+	for sym := range huffman.left {
+		left, right := huffman.left[sym], huffman.right[sym]
+		sizes.Write([]byte{byte(left), byte(left>>8) | byte(right&0xF)<<4, byte(right >> 4)})
+	}
+
+	if len(huffman.left)&1 != 0 {
+		sizes.WriteByte(0)
+	}
+	c.sizes = sizes.Bytes()
+
+	for k := 0; k*span < len(values); k++ {
+		i := k*span + span/2
+		var entry [6]byte
+		binary.LittleEndian.PutUint32(entry[:], uint32(blockOf[i]))
+		binary.LittleEndian.PutUint16(entry[4:], uint16(offsetOf[i]))
+		c.sparseIndex = append(c.sparseIndex, entry[:]...)
+	}
+
+	// one entry of padding
+	for _, n := range append(lengths, 1) {
+		c.blockLength = append(c.blockLength, byte(n-1), byte((n-1)>>8))
+	}
+
+	for _, b := range blocks {
+		c.data = append(c.data, b.buf...)
+		c.data = append(c.data, make([]byte, blockSize-len(b.buf))...)
+	}
+
+	return c
+}
+
+func align(buf *bytes.Buffer, n int) {
+	for buf.Len()%n != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// writeKQvK writes a KQvK table with the given sub-tables for white and black to move. DTZ tables only have one.
+func writeKQvK(t *testing.T, dir string, typ tableType, sides [][]byte, sparseIndex, blockLength, data [][]byte) {
+	var buf bytes.Buffer
+	buf.Write(magics[typ][:])
+	buf.WriteByte(1) // split, no pawns
+	buf.WriteByte(0) // leading group encoded first
+
+	pieces := []byte{king, queen, black | king}
+	for _, p := range pieces {
+		if typ == wdlTable {
+			p |= p << 4
+		}
+		buf.WriteByte(p)
+	}
+	align(&buf, 2)
+
+	for _, s := range sides {
+		buf.Write(s)
+	}
+
+	if typ == dtzTable {
+		align(&buf, 2)
+	}
+
+	for _, s := range sparseIndex {
+		buf.Write(s)
+	}
+
+	for _, s := range blockLength {
+		buf.Write(s)
+	}
+
+	for _, s := range data {
+		align(&buf, 64)
+		buf.Write(s)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "KQvK"+extensions[typ]), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	var values [2][]int
+	var sides [2]compressed
+	for side := range values {
+		values[side] = make([]int, 31332)
+		for i := range values[side] {
+			values[side][i] = r.Intn(5)
+		}
+
+		sides[side] = compress(values[side])
+	}
+
+	dir := t.TempDir()
+	writeKQvK(t, dir, wdlTable,
+		[][]byte{sides[0].sizes, sides[1].sizes},
+		[][]byte{sides[0].sparseIndex, sides[1].sparseIndex},
+		[][]byte{sides[0].blockLength, sides[1].blockLength},
+		[][]byte{sides[0].data, sides[1].data})
+
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	kqk := tb.wdlTables["KQvK"]
+	if err := kqk.load(); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	for i := 0; i < 20000; i++ {
+		p := randomPosition(r, "KQvK", []int{king, queen, black | king})
+		p.stm = i % 2
+
+		d, _, idx, _ := kqk.encode(p)
+		if v := kqk.decompress(d, idx); v != values[p.stm][idx] {
+			t.Fatalf("Expected value %v at index %v, but got: %v", values[p.stm][idx], idx, v)
+		}
+	}
+}
+
+// singleValueKQvK writes KQvK tables where white to move always wins, in dtz moves
+func singleValueKQvK(t *testing.T, dtz byte) *Tablebase {
+	dir := t.TempDir()
+
+	// raw WDL values are offset by 2
+	writeKQvK(t, dir, wdlTable, [][]byte{{flagSingleValue, byte(Win + 2)}, {flagSingleValue, byte(Loss + 2)}}, nil, nil, nil)
+	writeKQvK(t, dir, dtzTable, [][]byte{{flagSingleValue, dtz}}, nil, nil, nil)
+
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if tb.MaxPieces() != 3 {
+		t.Fatalf("Expected tables for 3 pieces, but got: %v", tb.MaxPieces())
+	}
+
+	return tb
+}
+
+func TestProbeWDL(t *testing.T) {
+	tb := singleValueKQvK(t, 3)
+
+	tests := []struct {
+		turn   chess.Color
+		pieces map[chess.Piece][]chess.Coordinate
+		wdl    WDL
+	}{
+		{chess.White, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}}, Win},
+		{chess.Black, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}}, Loss},
+
+		// the queen hangs
+		{chess.Black, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"e6"}, chess.BlackKing: {"e5"}}, Draw},
+
+		// black is the stronger side
+		{chess.White, map[chess.Piece][]chess.Coordinate{chess.BlackKing: {"a1"}, chess.BlackQueen: {"h8"}, chess.WhiteKing: {"e5"}}, Loss},
+		{chess.Black, map[chess.Piece][]chess.Coordinate{chess.BlackKing: {"a1"}, chess.BlackQueen: {"h8"}, chess.WhiteKing: {"e5"}}, Win},
+
+		{chess.White, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.BlackKing: {"e5"}}, Draw},
+	}
+
+	for i, test := range tests {
+		wdl, err := tb.ProbeWDL(newBoard(t, test.turn, test.pieces))
+		if err != nil {
+			t.Fatalf("Test %v: expected no errors, but got: %v", i, err)
+		}
+
+		if wdl != test.wdl {
+			t.Errorf("Test %v: expected %v, but got: %v", i, test.wdl, wdl)
+		}
+	}
+
+	if _, err := tb.ProbeWDL(chess.NewBoard()); err == nil {
+		t.Errorf("Expected an error probing the starting position")
+	}
+
+	b := newBoard(t, chess.White, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteRook: {"h8"}, chess.BlackKing: {"e5"}})
+	if _, err := tb.ProbeWDL(b); err == nil {
+		t.Errorf("Expected an error probing a missing table")
+	}
+
+	b = newBoard(t, chess.White, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}})
+	b.Variant = chess.Atomic
+	if _, err := tb.ProbeWDL(b); err == nil {
+		t.Errorf("Expected an error probing a variant position")
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tb := singleValueKQvK(t, 3)
+	pieces := map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}}
+
+	for _, turn := range []chess.Color{chess.White, chess.Black} {
+		b := newBoard(t, turn, pieces)
+		if o, ok := tb.Outcome(b); !ok || o != chess.WhiteWon {
+			t.Errorf("Expected white to win with %v to move, but got: %v, %v", turn, o, ok)
+		}
+
+		b.HalfMoveClock = 10
+		if o, ok := tb.Outcome(b); ok {
+			t.Errorf("Expected no outcome once the 50-move counter has advanced, but got: %v", o)
+		}
+	}
+
+	if o, ok := tb.Outcome(chess.NewBoard()); ok {
+		t.Errorf("Expected no outcome for the starting position, but got: %v", o)
+	}
+}
+
+func TestProbeDTZ(t *testing.T) {
+	tb := singleValueKQvK(t, 3)
+	pieces := map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}}
+
+	// 3 moves are 7 plies; the 1 ply search for black adds one more
+	for turn, expected := range map[chess.Color]int{chess.White: 7, chess.Black: -8} {
+		dtz, err := tb.ProbeDTZ(newBoard(t, turn, pieces))
+		if err != nil {
+			t.Fatalf("Expected no errors, but got: %v", err)
+		}
+
+		if dtz != expected {
+			t.Errorf("Expected a DTZ of %v with %v to move, but got: %v", expected, turn, dtz)
+		}
+	}
+}
+
+func TestRankRootMoves(t *testing.T) {
+	tb := singleValueKQvK(t, 3)
+	b := newBoard(t, chess.White, map[chess.Piece][]chess.Coordinate{chess.WhiteKing: {"a1"}, chess.WhiteQueen: {"h8"}, chess.BlackKing: {"e5"}})
+
+	moves, err := tb.RankRootMoves(b)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	hanging, _ := chess.NewMoveCoord("h8", "e6")
+	safe, _ := chess.NewMoveCoord("h8", "h1")
+	for _, m := range moves {
+		switch *m.Move {
+		case *hanging:
+			if m.WDL != Draw || m.Rank != 0 {
+				t.Errorf("Expected hanging the queen to draw, but got: %v (rank %v)", m.WDL, m.Rank)
+			}
+		case *safe:
+			if m.WDL != Win || m.Rank != 2000-9 {
+				t.Errorf("Expected a win in 9 plies, but got: %v (rank %v)", m.WDL, m.Rank)
+			}
+		}
+	}
+}
@@ -0,0 +1,767 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+// tableType is the kind of a table file
+type tableType uint8
+
+const (
+	wdlTable = iota
+	dtzTable = iota
+)
+
+var magics = [2][4]byte{
+	wdlTable: {0x71, 0xE8, 0x23, 0x5D},
+	dtzTable: {0xD7, 0x66, 0x0C, 0xA5},
+}
+
+var extensions = [2]string{
+	wdlTable: ".rtbw",
+	dtzTable: ".rtbz",
+}
+
+// flags of a pairsData. All but singleValue only apply to DTZ tables.
+const (
+	flagSTM         = 1
+	flagMapped      = 2
+	flagWinPlies    = 4
+	flagLossPlies   = 8
+	flagWide        = 16
+	flagSingleValue = 128
+)
+
+// pairsData describes one compressed sub-table: the positions of a table for one side to move and, for tables with
+// pawns, one file of the leading pawn. Offsets point into the table's file data.
+type pairsData struct {
+	flags byte
+
+	sizeofBlock uint64 // block size in bytes
+	span        uint64 // there is a sparse index entry about every span values
+	numBlocks   int
+	maxSymLen   int // maximum length in bits of the Huffman symbols
+	minSymLen   int // minimum length in bits of the Huffman symbols, or the value of a singleValue table
+
+	lowestSym   int // lowestSym[l] is the symbol of length l with the lowest value
+	btree       int // btree[sym] holds the left and right symbols that sym expands to
+	blockLength int // number of positions (minus one) stored in each block
+	sparseIndex int // partial indices into blockLength
+	data        int // start of the Huffman compressed data
+
+	blockLengthSize int
+	sparseIndexSize uint64
+
+	// base64 [l - minSymLen] is the lowest symbol of length l, padded to 64 bits
+	base64 []uint64
+
+	// symlen [sym] is the number of values (minus one) sym expands to
+	symlen []uint8
+
+	// pieces in the order they are encoded, which defines the groups
+	pieces [maxPieces]int
+
+	groupIdx [maxPieces + 1]uint64 // start index used to encode the pieces of a group
+	groupLen [maxPieces + 1]int    // number of pieces in each group, 0 terminated
+
+	// start of the DTZ map of each of Win, Loss, CursedWin and BlessedLoss
+	mapIdx [4]int
+}
+
+// table is a WDL or DTZ table file, loaded on first use
+type table struct {
+	typ  tableType
+	path string
+
+	// material of the table with the stronger side as white, and as black
+	key, key2 string
+
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // of the leading color, and of the other color
+
+	once sync.Once
+	err  error
+
+	file []byte
+
+	// sub-tables by side to move and file of the leading pawn
+	items [2][4]pairsData
+
+	dtzMap int
+}
+
+// newTable returns a table for the given material, where counts [c][p] is the number of pieces of color c and type
+// p of the stronger side (c = 0) and the weaker side (c = 1)
+func newTable(typ tableType, path string, counts [2][king + 1]int) *table {
+	t := &table{
+		typ:  typ,
+		path: path,
+		key:  materialKey(counts[0], counts[1]),
+		key2: materialKey(counts[1], counts[0]),
+	}
+
+	for c := 0; c < 2; c++ {
+		for p := pawn; p <= king; p++ {
+			t.pieceCount += counts[c][p]
+			if p != king && counts[c][p] == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	t.hasPawns = counts[0][pawn]+counts[1][pawn] > 0
+
+	// the leading color is the one with less pawns, as it compresses better
+	lead := 0
+	if counts[1][pawn] > 0 && (counts[0][pawn] == 0 || counts[1][pawn] < counts[0][pawn]) {
+		lead = 1
+	}
+
+	t.pawnCount[0] = counts[lead][pawn]
+	t.pawnCount[1] = counts[1-lead][pawn]
+
+	return t
+}
+
+func (t *table) sides() int {
+	if t.typ == wdlTable && t.key != t.key2 {
+		return 2
+	}
+
+	return 1
+}
+
+// get returns the sub-table for the given side to move and file of the leading pawn
+func (t *table) get(stm, f int) *pairsData {
+	if t.typ == dtzTable {
+		stm = 0
+	}
+
+	if !t.hasPawns {
+		f = 0
+	}
+
+	return &t.items[stm%2][f]
+}
+
+// load reads and parses the table file, if not done already
+func (t *table) load() error {
+	t.once.Do(func() {
+		t.err = t.read()
+		if t.err != nil {
+			t.file = nil
+			t.err = fmt.Errorf("could not load %v: %v", t.path, t.err)
+		}
+	})
+
+	return t.err
+}
+
+func (t *table) u8(off int) int {
+	return int(t.file[off])
+}
+
+func (t *table) u16(off int) int {
+	return int(binary.LittleEndian.Uint16(t.file[off:]))
+}
+
+func (t *table) u32(off int) uint32 {
+	return binary.LittleEndian.Uint32(t.file[off:])
+}
+
+func (t *table) read() (err error) {
+	file, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+
+	if len(file) < 5 || [4]byte{file[0], file[1], file[2], file[3]} != magics[t.typ] {
+		return fmt.Errorf("corrupted table")
+	}
+
+	// decompression reads whole words, which may run past the end of the last block
+	t.file = append(file, make([]byte, 8)...)
+
+	// parsing reads past the end of truncated files; report them as corrupted rather than crash
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupted table: %v", r)
+		}
+	}()
+
+	return t.parse(len(file))
+}
+
+// parse sets up the sub-tables from the file header
+func (t *table) parse(size int) error {
+	const (
+		split    = 1
+		hasPawns = 2
+	)
+
+	off := 4
+	flags := t.u8(off)
+	if t.hasPawns != (flags&hasPawns != 0) || (t.key != t.key2) != (flags&split != 0) {
+		return fmt.Errorf("table does not match its file name")
+	}
+	off++
+
+	sides := t.sides()
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+
+	pp := t.hasPawns && t.pawnCount[1] > 0 // pawns on both sides
+
+	for f := 0; f <= maxFile; f++ {
+		order := [2][2]int{{t.u8(off) & 0xF, 0xF}, {t.u8(off) >> 4, 0xF}}
+		if pp {
+			order[0][1] = t.u8(off+1) & 0xF
+			order[1][1] = t.u8(off+1) >> 4
+			off++
+		}
+		off++
+
+		for k := 0; k < t.pieceCount; k++ {
+			t.get(0, f).pieces[k] = t.u8(off) & 0xF
+			if sides == 2 {
+				t.get(1, f).pieces[k] = t.u8(off) >> 4
+			}
+			off++
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+
+	off += off & 1 // word alignment
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			var err error
+			if off, err = t.setSizes(t.get(i, f), off); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.typ == dtzTable {
+		off = t.setDTZMap(off, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.sparseIndex = off
+			off += int(d.sparseIndexSize) * 6
+		}
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.blockLength = off
+			off += d.blockLengthSize * 2
+		}
+	}
+
+	if off > size {
+		return fmt.Errorf("table is truncated")
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			off = (off + 0x3F) &^ 0x3F // 64 byte alignment
+			d.data = off
+			off += d.numBlocks * int(d.sizeofBlock)
+
+			if d.numBlocks > 0 && d.data >= size {
+				return fmt.Errorf("table is truncated")
+			}
+		}
+	}
+
+	return nil
+}
+
+// setGroups splits the pieces of d into groups, and computes the index each group starts at. A group is a set of
+// identical pieces, except for the leading group: the leading pawns, or the first 3 unique pieces, or the kings.
+func (t *table) setGroups(d *pairsData, order [2]int, f int) {
+	firstLen := 2
+	switch {
+	case t.hasPawns:
+		firstLen = 0
+	case t.hasUniquePieces:
+		firstLen = 3
+	}
+
+	n := 0
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	// groups are encoded in the order given by the table: the leading group is at order[0], and the remaining pawns,
+	// if any, at order[1]
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch k {
+		case order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][f]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+
+	d.groupIdx[n] = idx
+}
+
+// setSizes reads the compression parameters of d starting at off, returning the offset past them
+func (t *table) setSizes(d *pairsData, off int) (int, error) {
+	d.flags = t.file[off]
+	off++
+
+	if d.flags&flagSingleValue != 0 {
+		d.minSymLen = t.u8(off) // the single value
+		return off + 1, nil
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.sizeofBlock = 1 << uint(t.u8(off))
+	d.span = 1 << uint(t.u8(off+1))
+	d.sparseIndexSize = (tbSize + d.span - 1) / d.span
+	padding := t.u8(off + 2)
+	d.numBlocks = int(t.u32(off + 3))
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = t.u8(off + 7)
+	d.minSymLen = t.u8(off + 8)
+	off += 9
+
+	if d.minSymLen < 1 || d.maxSymLen < d.minSymLen || d.maxSymLen > 32 {
+		return 0, fmt.Errorf("invalid symbol lengths")
+	}
+
+	d.lowestSym = off
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+
+	// longer symbols have lower values, so that base64 is decreasing and a symbol s64 of length l, padded to 64 bits,
+	// satisfies base64[l-1] > s64 >= base64[l]
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(t.u16(d.lowestSym+2*i)) - uint64(t.u16(d.lowestSym+2*i+2))) / 2
+	}
+
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+
+	off += len(d.base64) * 2
+	d.symlen = make([]uint8, t.u16(off))
+	off += 2
+	d.btree = off
+
+	// symbols are compressed by recursive pairing: each symbol stands for a pair of other symbols
+	visited := make([]bool, len(d.symlen))
+	for sym := range d.symlen {
+		if !visited[sym] {
+			d.symlen[sym] = t.setSymlen(d, sym, visited)
+		}
+	}
+
+	return off + len(d.symlen)*3 + len(d.symlen)&1, nil
+}
+
+func (t *table) setSymlen(d *pairsData, sym int, visited []bool) uint8 {
+	visited[sym] = true // the tree is acyclic
+
+	sr := t.right(d, sym)
+	if sr == 0xFFF {
+		return 0
+	}
+
+	sl := t.left(d, sym)
+	if !visited[sl] {
+		d.symlen[sl] = t.setSymlen(d, sl, visited)
+	}
+
+	if !visited[sr] {
+		d.symlen[sr] = t.setSymlen(d, sr, visited)
+	}
+
+	return d.symlen[sl] + d.symlen[sr] + 1
+}
+
+// left returns the left-hand symbol that sym expands to, or the value of sym if it is a leaf
+func (t *table) left(d *pairsData, sym int) int {
+	lr := t.file[d.btree+3*sym:]
+	return int(lr[1]&0xF)<<8 | int(lr[0])
+}
+
+// right returns the right-hand symbol that sym expands to, or 0xFFF if it is a leaf
+func (t *table) right(d *pairsData, sym int) int {
+	lr := t.file[d.btree+3*sym:]
+	return int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+// setDTZMap reads the maps of stored DTZ values starting at off, returning the offset past them
+func (t *table) setDTZMap(off, maxFile int) int {
+	t.dtzMap = off
+
+	for f := 0; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+
+		if d.flags&flagWide != 0 {
+			off += off & 1
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (off-t.dtzMap)/2 + 1
+				off += 2*t.u16(off) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = off - t.dtzMap + 1
+				off += t.u8(off) + 1
+			}
+		}
+	}
+
+	return off + off&1
+}
+
+// decompress returns the value stored at index idx of d
+func (t *table) decompress(d *pairsData, idx uint64) int {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen
+	}
+
+	// the sparse index entry k points at the block and offset within the block of the value at index
+	// k*span + span/2. Starting from there, walk the blocks until the one holding idx.
+	k := idx / d.span
+	entry := d.sparseIndex + 6*int(k)
+	block := int(t.u32(entry))
+	offset := t.u16(entry + 4)
+
+	offset += int(idx%d.span) - int(d.span/2)
+
+	for offset < 0 {
+		block--
+		offset += t.u16(d.blockLength+2*block) + 1
+	}
+
+	for offset > t.u16(d.blockLength+2*block) {
+		offset -= t.u16(d.blockLength+2*block) + 1
+		block++
+	}
+
+	// the block is a sequence of canonical Huffman codes. Skip symbols until the one expanding to the value at offset.
+	ptr := d.data + block*int(d.sizeofBlock)
+	buf := binary.BigEndian.Uint64(t.file[ptr:])
+	ptr += 8
+	bufSize := 64
+
+	var sym int
+	for {
+		l := 0
+		for buf < d.base64[l] {
+			l++
+		}
+
+		sym = int(uint16((buf - d.base64[l]) >> uint(64-l-d.minSymLen)))
+		sym = int(uint16(sym + t.u16(d.lowestSym+2*l)))
+
+		if offset < int(d.symlen[sym])+1 {
+			break
+		}
+
+		offset -= int(d.symlen[sym]) + 1
+		l += d.minSymLen
+		buf <<= uint(l)
+		bufSize -= l
+
+		if bufSize <= 32 {
+			bufSize += 32
+			buf |= uint64(binary.BigEndian.Uint32(t.file[ptr:])) << uint(64-bufSize)
+			ptr += 4
+		}
+	}
+
+	// expand the symbol's pairs until the leaf holding the value
+	for d.symlen[sym] != 0 {
+		left := t.left(d, sym)
+		if offset < int(d.symlen[left])+1 {
+			sym = left
+		} else {
+			offset -= int(d.symlen[left]) + 1
+			sym = t.right(d, sym)
+		}
+	}
+
+	return t.left(d, sym)
+}
+
+// position is a position in the terms of the tablebase files
+type position struct {
+	// board [s] is the code of the piece on s, or 0 if empty
+	board [64]int
+
+	key string
+	stm int // 0 for white, 1 for black
+}
+
+// probe returns the raw value stored in the table for p. ok is false for DTZ tables that only store the other side
+// to move.
+func (t *table) probe(p *position) (value int, f int, ok bool) {
+	d, f, idx, ok := t.encode(p)
+	if !ok {
+		return 0, f, false
+	}
+
+	return t.decompress(d, idx), f, true
+}
+
+// encode returns the sub-table holding p, the file of its leading pawn, and the index of p in the sub-table. ok is
+// false for DTZ tables that only store the other side to move.
+func (t *table) encode(p *position) (d *pairsData, f int, idx uint64, ok bool) {
+	var squares [maxPieces]int
+	var pieces [maxPieces]int
+	size, leadPawnsCnt := 0, 0
+
+	// tables are computed with the stronger side as white. Positions with black as the stronger side, or black to
+	// move in tables with the same pieces on both sides, are looked up with colors switched and the board flipped.
+	flip := t.key != p.key || (t.key == t.key2 && p.stm == 1)
+	flipColor, flipSquares, stm := 0, 0, p.stm
+	if flip {
+		flipColor, flipSquares, stm = black, 56, 1-p.stm
+	}
+
+	// tables with pawns have 4 sub-tables for the file of the leading pawn, the one with the highest mapPawns value
+	f = 0
+	var leadPawns [64]bool
+	if t.hasPawns {
+		pc := t.get(0, 0).pieces[0] ^ flipColor
+		for s := 0; s < 64; s++ {
+			if p.board[s] == pc {
+				squares[size] = s ^ flipSquares
+				size++
+				leadPawns[s] = true
+			}
+		}
+
+		leadPawnsCnt = size
+
+		best := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+
+		f = fileOf(squares[0])
+		if f > 3 {
+			f = fileOf(squares[0] ^ 7)
+		}
+	}
+
+	d = t.get(stm, f)
+	if t.typ == dtzTable && int(d.flags&flagSTM) != stm && !(t.key == t.key2 && !t.hasPawns) {
+		return d, f, 0, false
+	}
+
+	for s := 0; s < 64; s++ {
+		if p.board[s] != 0 && !leadPawns[s] {
+			squares[size] = s ^ flipSquares
+			pieces[size] = p.board[s] ^ flipColor
+			size++
+		}
+	}
+
+	// order the pieces as they are encoded in the table
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// flip the board so that the leading piece is on files a-d
+	if fileOf(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawnsCnt][squares[0]]
+
+		rest := squares[1:leadPawnsCnt]
+		sort.SliceStable(rest, func(i, j int) bool {
+			return mapPawns[rest[i]] < mapPawns[rest[j]]
+		})
+
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		idx = t.encodePieces(d, squares[:size])
+	}
+
+	idx *= d.groupIdx[0]
+
+	// encode the other groups in ascending order of squares, skipping the squares taken by earlier groups
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	start := d.groupLen[0]
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+
+		n := uint64(0)
+		for i, s := range group {
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if s > prev {
+					adjust++
+				}
+			}
+
+			if remainingPawns {
+				adjust += 8
+			}
+
+			n += binomial[i+1][s-adjust]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	return d, f, idx, true
+}
+
+// encodePieces flips squares so that the leading piece is in the a1-d1-d4 triangle, and returns the index of the
+// leading group of a table without pawns
+func (t *table) encodePieces(d *pairsData, squares []int) uint64 {
+	if rankOf(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+
+	// the first piece of the leading group off the a1-h8 diagonal must be below it
+	for i := 0; i < d.groupLen[0]; i++ {
+		if offA1H8(squares[i]) == 0 {
+			continue
+		}
+
+		if offA1H8(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = (squares[j]>>3 | squares[j]<<3) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1 := 0
+	if s1 > s0 {
+		adjust1 = 1
+	}
+
+	adjust2 := 0
+	if s2 > s0 {
+		adjust2++
+	}
+
+	if s2 > s1 {
+		adjust2++
+	}
+
+	var idx int
+	switch {
+	case offA1H8(s0) != 0:
+		idx = (mapA1D1D4[s0]*63+(s1-adjust1))*62 + s2 - adjust2
+	case offA1H8(s1) != 0:
+		idx = (6*63+rankOf(s0)*28+mapB1H1H7[s1])*62 + s2 - adjust2
+	case offA1H8(s2) != 0:
+		idx = 6*63*62 + 4*28*62 + rankOf(s0)*7*28 + (rankOf(s1)-adjust1)*28 + mapB1H1H7[s2]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + rankOf(s0)*7*6 + (rankOf(s1)-adjust1)*6 + (rankOf(s2) - adjust2)
+	}
+
+	return uint64(idx)
+}
+
+// mapDTZ translates a raw value of a DTZ table into plies, for a position with the given WDL value
+func (t *table) mapDTZ(f, value int, wdl WDL) int {
+	d := t.get(0, f)
+
+	if d.flags&flagMapped != 0 {
+		idx := d.mapIdx[[5]int{1, 3, 0, 2, 0}[wdl+2]]
+		if d.flags&flagWide != 0 {
+			value = t.u16(t.dtzMap + 2*(idx+value))
+		} else {
+			value = t.u8(t.dtzMap + idx + value)
+		}
+	}
+
+	// values are stored in moves or plies; convert to plies
+	if (wdl == Win && d.flags&flagWinPlies == 0) || (wdl == Loss && d.flags&flagLossPlies == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+
+	return value + 1
+}
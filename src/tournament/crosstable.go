@@ -0,0 +1,175 @@
+package tournament
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Standing is the record of an entrant over the games of a tournament
+type Standing struct {
+	// Entrant is the index of the entrant
+	Entrant int
+	Name    string
+
+	Wins   int
+	Draws  int
+	Losses int
+
+	// Byes is the number of rounds the entrant didn't play in a Swiss tournament
+	Byes int
+
+	// Points is the score of the entrant: 1 per win or bye, and 1/2 per draw
+	Points float64
+
+	// Buchholz is the sum of the points of the opponents of the entrant, over every game played against them
+	Buchholz float64
+
+	// SonnebornBerger is the sum of the points of the opponents of the entrant, weighted by the points scored
+	// against them in every game
+	SonnebornBerger float64
+
+	// Against holds the points scored against every entrant, by index, and Played the number of games played against
+	// them
+	Against []float64
+	Played  []int
+}
+
+// Games returns the number of games played by the entrant
+func (s *Standing) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Crosstable holds the standings of all entrants of a tournament, best first
+type Crosstable []*Standing
+
+// NewCrosstable returns the crosstable of the given games between entrants, ranked by points, then Buchholz, then
+// Sonneborn-Berger. Games not played yet, i.e. nil, are ignored.
+func NewCrosstable(entrants []Entrant, games []*Game) Crosstable {
+	ct := Crosstable(newStandings(len(entrants), games))
+	for _, s := range ct {
+		s.Name = entrants[s.Entrant].Name
+	}
+
+	sort.SliceStable(ct, func(i, j int) bool {
+		switch {
+		case ct[i].Points != ct[j].Points:
+			return ct[i].Points > ct[j].Points
+		case ct[i].Buchholz != ct[j].Buchholz:
+			return ct[i].Buchholz > ct[j].Buchholz
+		default:
+			return ct[i].SonnebornBerger > ct[j].SonnebornBerger
+		}
+	})
+
+	return ct
+}
+
+// newStandings returns the standings of n entrants after the given games, by entrant
+func newStandings(n int, games []*Game) []*Standing {
+	standings := make([]*Standing, n)
+	for i := range standings {
+		standings[i] = &Standing{
+			Entrant: i,
+			Against: make([]float64, n),
+			Played:  make([]int, n),
+		}
+	}
+
+	for _, g := range games {
+		switch {
+		case g == nil:
+		case g.Black == Bye:
+			standings[g.White].Byes++
+			standings[g.White].Points++
+		case g.Result != nil:
+			white, black := standings[g.White], standings[g.Black]
+			points := whitePoints(g.Result.Outcome)
+			white.score(g.Black, points)
+			black.score(g.White, 1-points)
+		}
+	}
+
+	// tiebreaks depend on the final points of the opponents, so they can only be computed once all games are scored
+	for _, g := range games {
+		if g == nil || g.Black == Bye || g.Result == nil {
+			continue
+		}
+
+		white, black := standings[g.White], standings[g.Black]
+		points := whitePoints(g.Result.Outcome)
+
+		white.Buchholz += black.Points
+		black.Buchholz += white.Points
+		white.SonnebornBerger += points * black.Points
+		black.SonnebornBerger += (1 - points) * white.Points
+	}
+
+	return standings
+}
+
+// whitePoints returns the points scored by white in a game of the given outcome
+func whitePoints(o chess.Outcome) float64 {
+	switch o {
+	case chess.WhiteWon:
+		return 1
+	case chess.BlackWon:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+// score records a game scoring the given points against an opponent
+func (s *Standing) score(opp int, points float64) {
+	switch points {
+	case 1:
+		s.Wins++
+	case 0:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+
+	s.Played[opp]++
+	s.Points += points
+	s.Against[opp] += points
+}
+
+// String returns the crosstable as a table, with a column of the points scored against every other entrant, in the
+// order of the rows
+func (ct Crosstable) String() string {
+	width := len("Name")
+	for _, s := range ct {
+		if len(s.Name) > width {
+			width = len(s.Name)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%3v  %-*v  %5v  %6v  %4v  %4v  %4v  %8v  %6v ", "#", width, "Name", "Games", "Points",
+		"W", "D", "L", "Buchholz", "SB")
+	for rank := range ct {
+		fmt.Fprintf(&sb, " %5v", rank+1)
+	}
+	sb.WriteString("\n")
+
+	for rank, s := range ct {
+		fmt.Fprintf(&sb, "%3v  %-*v  %5v  %6.1f  %4v  %4v  %4v  %8.1f  %6.2f ", rank+1, width, s.Name, s.Games(), s.Points,
+			s.Wins, s.Draws, s.Losses, s.Buchholz, s.SonnebornBerger)
+		for _, opp := range ct {
+			switch {
+			case opp.Entrant == s.Entrant:
+				fmt.Fprintf(&sb, " %5v", "-")
+			case s.Played[opp.Entrant] == 0:
+				fmt.Fprintf(&sb, " %5v", ".")
+			default:
+				fmt.Fprintf(&sb, " %5.1f", s.Against[opp.Entrant])
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,143 @@
+package tournament
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/pgn"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Opening is a position games start from, reached by playing moves from a starting position
+type Opening struct {
+	Name string
+
+	// Start is the position the moves are played from
+	Start *chess.Board
+	Moves []*chess.Move
+}
+
+// Board returns the position reached by the opening
+func (o *Opening) Board() *chess.Board {
+	b := o.Start.Copy()
+	for _, m := range o.Moves {
+		b.UnsafeMove(m)
+	}
+
+	return b
+}
+
+// LoadOpenings reads an opening suite from the file at path: an EPD file, with a position per line, or a PGN file,
+// with a move sequence per game, depending on its extension
+func LoadOpenings(path string) ([]*Opening, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var openings []*Opening
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".epd":
+		openings, err = ReadEPD(f)
+	case ".pgn":
+		openings, err = ReadPGN(f)
+	default:
+		return nil, fmt.Errorf("opening suite must be an .epd or .pgn file, is: %v", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read opening suite %v: %v", path, err)
+	}
+
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("opening suite %v has no openings", path)
+	}
+
+	return openings, nil
+}
+
+// ReadEPD reads openings from Extended Position Descriptions, one per line: the first 4 fields of a FEN, followed by
+// operations, of which only the id naming the position is used
+func ReadEPD(r io.Reader) ([]*Opening, error) {
+	var openings []*Opening
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %v: EPD must have at least 4 fields, has: %v", line, len(fields))
+		}
+
+		fen := strings.Join(fields[:4], " ")
+		b, err := chess.NewBoardFromFEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+
+		o := &Opening{Name: fen, Start: b}
+		for _, op := range strings.Split(strings.Join(fields[4:], " "), ";") {
+			if op = strings.TrimSpace(op); strings.HasPrefix(op, "id ") {
+				o.Name = strings.Trim(strings.TrimSpace(op[len("id "):]), `"`)
+			}
+		}
+
+		openings = append(openings, o)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return openings, checkOpenings(openings)
+}
+
+// ReadPGN reads openings from the games of a PGN database, named after their Opening or Variation tags if any
+func ReadPGN(r io.Reader) ([]*Opening, error) {
+	games, err := pgn.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	openings := make([]*Opening, len(games))
+	for i, g := range games {
+		o := &Opening{Start: g.Start, Moves: g.Moves}
+		if o.Start == nil {
+			o.Start = chess.NewBoard()
+		}
+
+		var names []string
+		for _, tag := range []string{"Opening", "Variation"} {
+			if g.Tags[tag] != "" {
+				names = append(names, g.Tags[tag])
+			}
+		}
+
+		o.Name = strings.Join(names, ", ")
+		if o.Name == "" {
+			o.Name = fmt.Sprint(i + 1)
+		}
+
+		openings[i] = o
+	}
+
+	return openings, checkOpenings(openings)
+}
+
+// checkOpenings returns an error if a game can't be played from one of the openings
+func checkOpenings(openings []*Opening) error {
+	for _, o := range openings {
+		if len(o.Board().LegalMoves()) == 0 {
+			return fmt.Errorf("opening %v: game is already over", o.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package tournament
+
+import (
+	"Chess2020/src/rating"
+	"sync"
+)
+
+// RunSPRT plays pairs of games between the first two entrants of the tournament, from the same opening with colors
+// reversed, and adds them to s until it accepts either hypothesis. Returns the games played, which include
+// those of the pairs still being played once the test was decided. Pairings are ignored, and progress, if set, is
+// called with s after every pair, one pair at a time.
+func (t *Tournament) RunSPRT(s *rating.SPRT, progress func(s *rating.SPRT)) []*Game {
+	workers := t.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var games []*Game
+	pairs := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				mu.Lock()
+				if s.Decision() != rating.Undecided {
+					mu.Unlock()
+					return
+				}
+				pairs++
+				round := pairs
+				mu.Unlock()
+
+				first := t.play(Pairing{Round: round, White: 0, Black: 1, Opening: round - 1})
+				second := t.play(Pairing{Round: round, White: 1, Black: 0, Opening: round - 1})
+
+				mu.Lock()
+				games = append(games, first, second)
+				s.AddPair(whitePoints(first.Result.Outcome), 1-whitePoints(second.Result.Outcome))
+
+				if t.OnGame != nil {
+					t.OnGame(first)
+					t.OnGame(second)
+				}
+
+				if progress != nil {
+					progress(s)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return games
+}
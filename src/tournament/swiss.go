@@ -0,0 +1,236 @@
+package tournament
+
+import (
+	"Chess2020/src/chess"
+	"fmt"
+	"sort"
+)
+
+// strengths of color preferences
+const (
+	noPreference       = iota
+	mildPreference     = iota // the player had the other color last
+	strongPreference   = iota // the player had the other color once more than this one
+	absolutePreference = iota // the player can't get the other color
+)
+
+// swissPlayer is an entrant of a Swiss tournament, as known to pair the next round
+type swissPlayer struct {
+	entrant int
+	points  float64
+
+	// colors played with, in order
+	colors []chess.Color
+
+	opponents map[int]bool
+	bye       bool
+}
+
+// balance returns the number of games played with white, minus those played with black
+func (p *swissPlayer) balance() int {
+	balance := 0
+	for _, c := range p.colors {
+		if c == chess.White {
+			balance++
+		} else {
+			balance--
+		}
+	}
+
+	return balance
+}
+
+// preference returns the color the player should get next, and the strength of this preference
+func (p *swissPlayer) preference() (chess.Color, int) {
+	n := len(p.colors)
+	balance := p.balance()
+
+	switch {
+	case balance <= -2 || n >= 2 && p.colors[n-1] == chess.Black && p.colors[n-2] == chess.Black:
+		return chess.White, absolutePreference
+	case balance >= 2 || n >= 2 && p.colors[n-1] == chess.White && p.colors[n-2] == chess.White:
+		return chess.Black, absolutePreference
+	case balance == -1:
+		return chess.White, strongPreference
+	case balance == 1:
+		return chess.Black, strongPreference
+	case n > 0:
+		return p.colors[n-1].Other(), mildPreference
+	default:
+		return chess.White, noPreference
+	}
+}
+
+// canPlay returns true iff the players haven't met yet, and, if strict, can be given different colors
+func canPlay(p, q *swissPlayer, strict bool) bool {
+	if p.opponents[q.entrant] {
+		return false
+	} else if !strict {
+		return true
+	}
+
+	pc, ps := p.preference()
+	qc, qs := q.preference()
+
+	return pc != qc || ps != absolutePreference || qs != absolutePreference
+}
+
+// SwissRound returns the pairings of the given round of a Swiss tournament between n entrants, from the games of the
+// previous rounds, following a simplified Dutch system:
+//
+//   - entrants are ranked by points, then by index, which is their seed
+//   - the top half of each score group is paired against its bottom half, in order, and entrants that can't be paired
+//     within their score group float down to the next one
+//   - no two entrants meet twice, and byes go to the lowest ranked entrant that hasn't had one yet
+//   - no entrant gets the same color three times in a row, or two more games with a color than with the other, unless
+//     the round can't be paired otherwise
+//   - within these limits, each entrant gets the color it had less, or didn't have last, the higher ranked one first
+//   - all games of a round start from the same opening
+//
+// Returns an error if the round can't be paired, e.g. if there are more rounds than there are opponents.
+func SwissRound(n, round int, games []*Game) ([]Pairing, error) {
+	players := make([]*swissPlayer, n)
+	for i := range players {
+		players[i] = &swissPlayer{entrant: i, opponents: make(map[int]bool)}
+	}
+
+	standings := newStandings(n, games)
+	for i, s := range standings {
+		players[i].points = s.Points
+	}
+
+	played := make([]*Game, 0, len(games))
+	for _, g := range games {
+		if g != nil {
+			played = append(played, g)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		return played[i].Round < played[j].Round
+	})
+
+	for _, g := range played {
+		if g.Black == Bye {
+			players[g.White].bye = true
+			continue
+		}
+
+		white, black := players[g.White], players[g.Black]
+		white.colors = append(white.colors, chess.White)
+		black.colors = append(black.colors, chess.Black)
+		white.opponents[g.Black] = true
+		black.opponents[g.White] = true
+	}
+
+	ranked := make([]*swissPlayer, n)
+	copy(ranked, players)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].points > ranked[j].points
+	})
+
+	for _, strict := range []bool{true, false} {
+		if n%2 == 0 {
+			if pairs, ok := pairSwiss(ranked, strict); ok {
+				return pairings(round, pairs), nil
+			}
+
+			continue
+		}
+
+		// try giving the bye to the lowest ranked entrants first, then to those that already had one
+		for _, byes := range []bool{false, true} {
+			for i := n - 1; i >= 0; i-- {
+				if ranked[i].bye != byes {
+					continue
+				}
+
+				rest := append(append([]*swissPlayer{}, ranked[:i]...), ranked[i+1:]...)
+				if pairs, ok := pairSwiss(rest, strict); ok {
+					return append(pairings(round, pairs), Pairing{Round: round, White: ranked[i].entrant, Black: Bye, Opening: round - 1}), nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("round %v can't be paired without two entrants meeting twice", round)
+}
+
+// pairSwiss pairs ranked players, the first player of each pair being the higher ranked, with strict color
+// constraints or not. Returns false if they can't be paired.
+func pairSwiss(ranked []*swissPlayer, strict bool) ([][2]*swissPlayer, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+
+	p, others := ranked[0], ranked[1:]
+
+	// the score group of p is p followed by others[:size-1]
+	size := 1
+	for size-1 < len(others) && others[size-1].points == p.points {
+		size++
+	}
+
+	// opponents in order of preference: the bottom half of the score group, from its top, then the top half, from its
+	// bottom, then the lower score groups
+	var order []int
+	for i := size / 2; i < size; i++ {
+		if i > 0 {
+			order = append(order, i-1)
+		}
+	}
+	for i := size/2 - 1; i >= 1; i-- {
+		order = append(order, i-1)
+	}
+	for i := size - 1; i < len(others); i++ {
+		order = append(order, i)
+	}
+
+	for _, i := range order {
+		q := others[i]
+		if !canPlay(p, q, strict) {
+			continue
+		}
+
+		rest := append(append([]*swissPlayer{}, others[:i]...), others[i+1:]...)
+		if pairs, ok := pairSwiss(rest, strict); ok {
+			return append([][2]*swissPlayer{{p, q}}, pairs...), true
+		}
+	}
+
+	return nil, false
+}
+
+// pairings returns the pairings of the given pairs, from the top board down, assigning colors
+func pairings(round int, pairs [][2]*swissPlayer) []Pairing {
+	ps := make([]Pairing, len(pairs))
+	for board, pair := range pairs {
+		high, low := pair[0], pair[1]
+		hc, hs := high.preference()
+		lc, ls := low.preference()
+
+		white, black := high, low
+		switch {
+		case hs == noPreference && ls == noPreference:
+			// e.g. in the first round: the higher ranked entrant alternates colors from one board to the next
+			if board%2 == 1 {
+				white, black = low, high
+			}
+		case hc != lc && hs != noPreference && ls != noPreference:
+			if hc == chess.Black {
+				white, black = low, high
+			}
+		case ls > hs:
+			if lc == chess.White {
+				white, black = low, high
+			}
+		default:
+			if hc == chess.Black {
+				white, black = low, high
+			}
+		}
+
+		ps[board] = Pairing{Round: round, White: white.entrant, Black: black.entrant, Opening: round - 1}
+	}
+
+	return ps
+}
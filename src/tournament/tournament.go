@@ -0,0 +1,267 @@
+// Package tournament plays tournaments between any number of players, e.g. to measure the strength of an engine change
+// against previous versions or other engines.
+package tournament
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/pgn"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entrant is a player taking part in a tournament
+type Entrant struct {
+	Name string
+
+	// New returns the Player playing the next game of the entrant. Each game gets its own Player, as games may be
+	// played concurrently.
+	New func() chess.Player
+}
+
+// Bye is the opponent of an entrant not playing in a round of a Swiss tournament, which scores a point for it
+const Bye = -1
+
+// Pairing is a game of a tournament, between the entrants of the given indices
+type Pairing struct {
+	// Round is the round the game belongs to, starting at 1
+	Round int
+
+	White int
+
+	// Black is Bye if the pairing is a bye of White
+	Black int
+
+	// Opening is the index of the opening the game starts from in the openings of the tournament, modulo their
+	// number. Games between the same entrants with colors reversed share the same opening.
+	Opening int
+}
+
+// RoundRobin returns the pairings of a round-robin between n entrants: every entrant plays every other one once per
+// cycle, with colors reversed every other cycle, so a double round-robin is 2 cycles. Within a cycle, the colors of
+// each entrant are balanced as well as possible, and each game has its own opening.
+func RoundRobin(n, cycles int) []Pairing {
+	// circle method: the last entrant stays in place while the others rotate, and an odd number of entrants gets a
+	// bye, i.e. an entrant not playing, as the last one
+	size := n + n%2
+
+	var pairings []Pairing
+	for cycle := 0; cycle < cycles; cycle++ {
+		game := 0
+		for r := 0; r < size-1; r++ {
+			round := cycle*(size-1) + r + 1
+
+			for i := 0; i < size/2; i++ {
+				white, black := (r+i)%(size-1), (r+size-1-i)%(size-1)
+				if i == 0 {
+					black = size - 1
+					if r%2 == 1 {
+						white, black = black, white
+					}
+				}
+
+				if white >= n || black >= n {
+					continue // bye
+				}
+
+				if cycle%2 == 1 {
+					white, black = black, white
+				}
+
+				// the game with colors reversed, in the next cycle, has the same opening
+				opening := cycle/2*n*(n-1)/2 + game
+				game++
+
+				pairings = append(pairings, Pairing{Round: round, White: white, Black: black, Opening: opening})
+			}
+		}
+	}
+
+	return pairings
+}
+
+// Gauntlet returns the pairings of a gauntlet between n entrants: the first entrant plays the given number of games
+// against each of the others, alternating colors with the same opening, and the others don't play each other
+func Gauntlet(n, games int) []Pairing {
+	var pairings []Pairing
+	for g := 0; g < games; g++ {
+		for opp := 1; opp < n; opp++ {
+			p := Pairing{Round: g + 1, White: 0, Black: opp, Opening: g/2*(n-1) + opp - 1}
+			if g%2 == 1 {
+				p.White, p.Black = opp, 0
+			}
+
+			pairings = append(pairings, p)
+		}
+	}
+
+	return pairings
+}
+
+// Game is a game played in a tournament
+type Game struct {
+	Pairing
+
+	// Result is nil for byes, which aren't played
+	Result *chess.GameResult
+
+	// Opening is the opening the game started from, nil if it started from the starting position
+	Opening *Opening
+
+	// Started is the time the game started at
+	Started time.Time
+}
+
+// Tournament plays a set of pairings between its entrants
+type Tournament struct {
+	// Event is the name of the tournament
+	Event string
+
+	Entrants []Entrant
+	Pairings []Pairing
+
+	TimeControl chess.TimeControl
+
+	// Adjudication, if set, replaces the default adjudication of games
+	Adjudication *chess.Adjudication
+
+	// Openings, if set, are the openings games start from, as given by their pairings
+	Openings []*Opening
+
+	// Variant, if set, is the variant games are played in, from its starting position or from the openings
+	Variant chess.Variant
+
+	// Concurrency is the maximum number of games played at the same time, at least 1
+	Concurrency int
+
+	// OnGame, if set, is called with every game once played, one game at a time
+	OnGame func(g *Game)
+}
+
+// Run plays all pairings of the tournament, and returns the games played, in the order of the pairings
+func (t *Tournament) Run() []*Game {
+	return t.Play(t.Pairings)
+}
+
+// Play plays the given pairings between the entrants of the tournament, and returns the games played, in the order of
+// the pairings. OnGame isn't called for byes.
+func (t *Tournament) Play(pairings []Pairing) []*Game {
+	games := make([]*Game, len(pairings))
+	next := make(chan int)
+
+	workers := t.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				games[i] = t.play(pairings[i])
+				if t.OnGame != nil {
+					mu.Lock()
+					t.OnGame(games[i])
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i, p := range pairings {
+		if p.Black == Bye {
+			games[i] = &Game{Pairing: p, Started: time.Now()}
+			continue
+		}
+
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return games
+}
+
+// play plays the game of a pairing
+func (t *Tournament) play(p Pairing) *Game {
+	white := t.Entrants[p.White].New()
+	black := t.Entrants[p.Black].New()
+
+	g := &Game{Pairing: p, Started: time.Now()}
+	if len(t.Openings) > 0 {
+		g.Opening = t.Openings[p.Opening%len(t.Openings)]
+	}
+
+	b := t.start(g.Opening)
+	if g.Opening != nil {
+		for _, m := range g.Opening.Moves {
+			b.UnsafeMove(m)
+		}
+	}
+
+	game := chess.NewGameFromBoard(white, black, t.TimeControl, b)
+	if t.Adjudication != nil {
+		game.Adjudication = *t.Adjudication
+	}
+
+	g.Result = game.Start()
+
+	return g
+}
+
+// start returns the position a game starting from the given opening, if any, starts from before the opening moves, in
+// the variant of the tournament
+func (t *Tournament) start(o *Opening) *chess.Board {
+	switch {
+	case o != nil:
+		b := o.Start.Copy()
+		if t.Variant != nil {
+			b.Variant = t.Variant
+		}
+		return b
+	case t.Variant != nil:
+		return t.Variant.NewBoard()
+	default:
+		return chess.NewBoard()
+	}
+}
+
+// WritePGN writes the given games of the tournament to w in PGN
+func (t *Tournament) WritePGN(w io.Writer, games []*Game) error {
+	for _, g := range games {
+		if g.Black == Bye {
+			continue
+		}
+
+		tags := map[string]string{
+			"Event": t.Event,
+			"Date":  g.Started.Format("2006.01.02"),
+			"Round": fmt.Sprint(g.Round),
+			"White": t.Entrants[g.White].Name,
+			"Black": t.Entrants[g.Black].Name,
+		}
+
+		if _, untimed := t.TimeControl.(chess.InfiniteTime); !untimed {
+			tc := t.TimeControl
+			tags["TimeControl"] = fmt.Sprintf("%v+%v", tc.InitialTime().Seconds(), tc.Increment().Seconds())
+		}
+
+		game := pgn.NewGame(tags, t.start(g.Opening), g.Result)
+		if g.Opening != nil {
+			// the opening moves are part of the game, rather than the position they lead to
+			tags["Opening"] = g.Opening.Name
+			game.Moves = append(append([]*chess.Move{}, g.Opening.Moves...), g.Result.Moves...)
+		}
+
+		if err := game.Write(w); err != nil {
+			return fmt.Errorf("round %v, %v - %v: %v", g.Round, tags["White"], tags["Black"], err)
+		}
+	}
+
+	return nil
+}
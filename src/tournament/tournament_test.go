@@ -0,0 +1,379 @@
+package tournament
+
+import (
+	"Chess2020/src/chess"
+	"Chess2020/src/players/random"
+	"Chess2020/src/rating"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundRobin(t *testing.T) {
+	for n := 2; n <= 7; n++ {
+		for cycles := 1; cycles <= 2; cycles++ {
+			pairings := RoundRobin(n, cycles)
+			if len(pairings) != cycles*n*(n-1)/2 {
+				t.Fatalf("Expected %v games for %v entrants, but got: %v", cycles*n*(n-1)/2, n, len(pairings))
+			}
+
+			whites := make([]int, n)
+			met := make(map[[2]int]int)
+			played := make(map[[2]int]bool) // by round and entrant
+			for _, p := range pairings {
+				for _, e := range []int{p.White, p.Black} {
+					if played[[2]int{p.Round, e}] {
+						t.Fatalf("Expected entrant %v to play once in round %v of %v", e, p.Round, pairings)
+					}
+					played[[2]int{p.Round, e}] = true
+				}
+
+				whites[p.White]++
+				met[[2]int{p.White, p.Black}]++
+			}
+
+			for white := 0; white < n; white++ {
+				for black := 0; black < n; black++ {
+					games := met[[2]int{white, black}] + met[[2]int{black, white}]
+					if white != black && games != cycles {
+						t.Fatalf("Expected %v and %v to meet %v times, but got: %v", white, black, cycles, games)
+					}
+
+					if white != black && cycles == 2 && met[[2]int{white, black}] != 1 {
+						t.Fatalf("Expected %v and %v to play once with each color", white, black)
+					}
+				}
+
+				if blacks := cycles*(n-1) - whites[white]; whites[white]-blacks > 1 || blacks-whites[white] > 1 {
+					t.Fatalf("Expected balanced colors, but %v has %v whites and %v blacks", white, whites[white], blacks)
+				}
+			}
+		}
+	}
+}
+
+func TestGauntlet(t *testing.T) {
+	pairings := Gauntlet(4, 3)
+	if len(pairings) != 9 {
+		t.Fatalf("Expected 9 games, but got: %v", len(pairings))
+	}
+
+	whites := 0
+	for _, p := range pairings {
+		if p.White != 0 && p.Black != 0 {
+			t.Fatalf("Expected every game to be played by the first entrant, but got: %+v", p)
+		}
+
+		if p.White == 0 {
+			whites++
+		}
+	}
+
+	if whites != 6 {
+		t.Fatalf("Expected 6 games with white, but got: %v", whites)
+	}
+}
+
+// quitter abandons every game on its first move
+type quitter struct {
+	prompt chan chess.Prompt
+	move   chan *chess.Move
+}
+
+func (q *quitter) Init(c chess.Color, gc chess.GameClient, prompt chan chess.Prompt, move chan *chess.Move) {
+	q.prompt = prompt
+	q.move = move
+}
+
+func (q *quitter) Run() {
+	for range q.prompt {
+		q.move <- nil
+	}
+}
+
+func TestRun(t *testing.T) {
+	tournament := &Tournament{
+		Event: "Test",
+		Entrants: []Entrant{
+			{Name: "quitter", New: func() chess.Player { return &quitter{} }},
+			{Name: "random", New: func() chess.Player { return random.Player() }},
+			{Name: "random2", New: func() chess.Player { return random.Player() }},
+		},
+		Pairings:    RoundRobin(3, 2),
+		TimeControl: chess.InfiniteTime{},
+		Concurrency: 2,
+	}
+
+	played := 0
+	tournament.OnGame = func(g *Game) { played++ }
+	games := tournament.Run()
+
+	if len(games) != 6 || played != 6 {
+		t.Fatalf("Expected 6 games, but got: %v", len(games))
+	}
+
+	ct := NewCrosstable(tournament.Entrants, games)
+	last := ct[2]
+	if last.Name != "quitter" || last.Points != 0 || last.Losses != 4 || last.Played[1] != 2 {
+		t.Fatalf("Expected the quitter to lose all its games, but got:\n%v", ct)
+	}
+
+	if ct[0].Points+ct[1].Points != 6 || ct[0].Against[0] != 2 {
+		t.Fatalf("Expected all points to go to the random players, but got:\n%v", ct)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(ct.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(strings.TrimSpace(lines[3]), "3  quitter") {
+		t.Fatalf("Expected a row per entrant, but got:\n%v", ct)
+	}
+
+	var buf bytes.Buffer
+	if err := tournament.WritePGN(&buf, games); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if n := strings.Count(buf.String(), "[Event \"Test\"]"); n != 6 {
+		t.Fatalf("Expected 6 games in PGN, but got: %v", n)
+	}
+}
+
+func TestSwissRound(t *testing.T) {
+	pairings, err := SwissRound(6, 1, nil)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	expected := []Pairing{{1, 0, 3, 0}, {1, 4, 1, 0}, {1, 2, 5, 0}}
+	for i, p := range pairings {
+		if p != expected[i] {
+			t.Fatalf("Expected %v, but got: %v", expected, pairings)
+		}
+	}
+
+	for n := 2; n <= 12; n++ {
+		rounds := 0
+		for 1<<uint(rounds) < n {
+			rounds++
+		}
+
+		var games []*Game
+		for round := 1; round <= rounds; round++ {
+			pairings, err := SwissRound(n, round, games)
+			if err != nil {
+				t.Fatalf("Expected no errors pairing round %v for %v entrants, but got: %v", round, n, err)
+			}
+
+			paired := make(map[int]bool)
+			for _, p := range pairings {
+				for _, e := range []int{p.White, p.Black} {
+					if e != Bye && paired[e] {
+						t.Fatalf("Expected entrant %v to be paired once in round %v, but got: %v", e, round, pairings)
+					}
+					paired[e] = true
+				}
+
+				// the lower index wins, unless the sum of both is a multiple of 3
+				g := &Game{Pairing: p, Result: &chess.GameResult{Outcome: chess.WhiteWon}}
+				switch {
+				case p.Black == Bye:
+				case (p.White+p.Black)%3 == 0:
+					g.Result.Outcome = chess.Draw
+				case p.White > p.Black:
+					g.Result.Outcome = chess.BlackWon
+				}
+				games = append(games, g)
+			}
+
+			if len(paired) != n+n%2 {
+				t.Fatalf("Expected every entrant to be paired in round %v, but got: %v", round, pairings)
+			}
+		}
+
+		met := make(map[[2]int]bool)
+		colors := make([][]chess.Color, n)
+		byes := make([]int, n)
+		for _, g := range games {
+			if g.Black == Bye {
+				byes[g.White]++
+				continue
+			}
+
+			if met[[2]int{g.White, g.Black}] || met[[2]int{g.Black, g.White}] {
+				t.Fatalf("Expected %v and %v to meet once", g.White, g.Black)
+			}
+			met[[2]int{g.White, g.Black}] = true
+
+			colors[g.White] = append(colors[g.White], chess.White)
+			colors[g.Black] = append(colors[g.Black], chess.Black)
+		}
+
+		for e := 0; e < n; e++ {
+			p := &swissPlayer{colors: colors[e]}
+			if balance := p.balance(); balance > 2 || balance < -2 {
+				t.Fatalf("Expected balanced colors, but %v played with: %v", e, colors[e])
+			}
+
+			for i := 2; i < len(colors[e]); i++ {
+				if colors[e][i] == colors[e][i-1] && colors[e][i] == colors[e][i-2] {
+					t.Fatalf("Expected no color three times in a row, but %v played with: %v", e, colors[e])
+				}
+			}
+
+			if byes[e] > 1 {
+				t.Fatalf("Expected at most one bye, but %v got: %v", e, byes[e])
+			}
+		}
+	}
+
+	// two entrants can only meet once
+	games := []*Game{{Pairing: Pairing{1, 0, 1, 0}, Result: &chess.GameResult{Outcome: chess.Draw}}}
+	if _, err := SwissRound(2, 2, games); err == nil {
+		t.Fatalf("Expected an error pairing a second round, but got none")
+	}
+}
+
+func TestTiebreaks(t *testing.T) {
+	entrants := make([]Entrant, 4)
+	for i := range entrants {
+		entrants[i].Name = fmt.Sprint(i)
+	}
+
+	game := func(round, white, black int, o chess.Outcome) *Game {
+		return &Game{Pairing: Pairing{round, white, black, round - 1}, Result: &chess.GameResult{Outcome: o}}
+	}
+
+	// 0 and 1 both score 1.5, but 0 drew with 1 and beat 2, who scored more than 3, beaten by 1
+	ct := NewCrosstable(entrants, []*Game{
+		game(1, 0, 1, chess.Draw),
+		game(1, 2, 3, chess.WhiteWon),
+		game(2, 0, 2, chess.WhiteWon),
+		game(2, 3, 1, chess.BlackWon),
+		{Pairing: Pairing{3, 3, Bye, 2}},
+	})
+
+	if ct[0].Name != "0" || ct[1].Name != "1" || ct[0].Buchholz != 2.5 || ct[1].Buchholz != 2.5 {
+		t.Fatalf("Expected 0 and 1 to have the same Buchholz, but got:\n%v", ct)
+	}
+
+	if ct[0].SonnebornBerger != 1.75 || ct[1].SonnebornBerger != 1.75 {
+		t.Fatalf("Expected Sonneborn-Berger of 1.75, but got:\n%v", ct)
+	}
+
+	if ct[2].Name != "2" || ct[3].Name != "3" || ct[3].Byes != 1 || ct[3].Points != 1 {
+		t.Fatalf("Expected the bye to count as a point, but got:\n%v", ct)
+	}
+}
+
+func TestRunSPRT(t *testing.T) {
+	tournament := &Tournament{
+		Entrants: []Entrant{
+			{Name: "random", New: func() chess.Player { return random.Player() }},
+			{Name: "quitter", New: func() chess.Player { return &quitter{} }},
+		},
+		TimeControl: chess.InfiniteTime{},
+		Concurrency: 2,
+	}
+
+	s := rating.NewSPRT(0, 5, 0.05, 0.05)
+	progress := 0
+	games := tournament.RunSPRT(s, func(s *rating.SPRT) { progress++ })
+
+	if s.Decision() != rating.AcceptH1 || len(games) != 2*progress || s.Pairs[4] != progress {
+		t.Fatalf("Expected H1 to be accepted after every pair was won, but got: %v", s)
+	}
+
+	for i := 0; i < len(games); i += 2 {
+		if games[i].Round != games[i+1].Round || games[i].White != games[i+1].Black {
+			t.Fatalf("Expected pairs of games with colors reversed, but got: %+v, %+v", games[i], games[i+1])
+		}
+	}
+}
+
+func TestOpenings(t *testing.T) {
+	dir := t.TempDir()
+	epd := filepath.Join(dir, "suite.epd")
+	ioutil.WriteFile(epd, []byte(`rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - id "Open Game";
+4k3/8/8/8/8/8/4P3/4K3 b - - bm Kd7;
+
+`), 0644)
+
+	openings, err := LoadOpenings(epd)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if len(openings) != 2 || openings[0].Name != "Open Game" || openings[1].Board().Turn != chess.Black {
+		t.Fatalf("Expected 2 openings, but got: %+v", openings)
+	}
+
+	suite := filepath.Join(dir, "suite.pgn")
+	ioutil.WriteFile(suite, []byte(`[Opening "Sicilian"]
+
+1. e4 c5 *
+
+1. d4 d5 2. c4 *
+`), 0644)
+
+	if openings, err = LoadOpenings(suite); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if len(openings) != 2 || openings[0].Name != "Sicilian" || openings[1].Name != "2" || len(openings[1].Moves) != 3 {
+		t.Fatalf("Expected 2 openings, but got: %+v", openings)
+	}
+
+	mate := filepath.Join(dir, "mate.epd")
+	ioutil.WriteFile(mate, []byte("R5k1/5ppp/8/8/8/8/8/4K3 b - -\n"), 0644)
+	for _, path := range []string{mate, filepath.Join(dir, "missing.epd"), filepath.Join(dir, "suite.txt")} {
+		if _, err := LoadOpenings(path); err == nil {
+			t.Errorf("Expected an error loading %v", path)
+		}
+	}
+
+	tournament := &Tournament{
+		Entrants: []Entrant{
+			{Name: "quitter", New: func() chess.Player { return &quitter{} }},
+			{Name: "random", New: func() chess.Player { return random.Player() }},
+		},
+		Pairings:    Gauntlet(2, 4),
+		TimeControl: chess.InfiniteTime{},
+		Openings:    openings,
+	}
+
+	games := tournament.Run()
+	for i, g := range games {
+		if g.Opening != openings[i/2] {
+			t.Fatalf("Expected game %v to start from opening %v, but got: %+v", i, i/2, g.Opening)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tournament.WritePGN(&buf, games); err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	if n := strings.Count(buf.String(), "[Opening \"Sicilian\"]\n"); n != 2 || !strings.Contains(buf.String(), "1. d4 d5 2. c4 {") {
+		t.Fatalf("Expected the games to start with the moves of their openings, but got:\n%v", buf.String())
+	}
+}
+
+func TestAdjudication(t *testing.T) {
+	tournament := &Tournament{
+		Entrants: []Entrant{
+			{Name: "random", New: func() chess.Player { return random.Player() }},
+			{Name: "random2", New: func() chess.Player { return random.Player() }},
+		},
+		Pairings:     RoundRobin(2, 2),
+		TimeControl:  chess.InfiniteTime{},
+		Adjudication: &chess.Adjudication{MaxMoves: 1},
+	}
+
+	for _, g := range tournament.Run() {
+		if g.Result.Outcome != chess.Draw || len(g.Result.Moves) != 2 {
+			t.Errorf("Expected games to be drawn after 1 move, but got: %v after %v moves", g.Result.Outcome,
+				len(g.Result.Moves))
+		}
+	}
+}